@@ -3,24 +3,26 @@ package handler
 import (
 	"Sellers/internal/domain"
 	"Sellers/internal/locality"
+	"Sellers/internal/logging"
 	"Sellers/pkg/web"
-	//"fmt"
-	//"strconv"
+	"strconv"
 	"github.com/gin-gonic/gin"
 	"net/http"
-	"context"
+	"strings"
 )
 
 
 type Locality struct {
 	service locality.Service
+	log     *logging.Logger
 }
 
 
 
-func NewLocality( p locality.Service) *Locality{
+func NewLocality( p locality.Service, log *logging.Logger) *Locality{
 	return &Locality{
 		service: p,
+		log:     log,
 	}
 }
 
@@ -32,87 +34,224 @@ func (s *Locality) Create() gin.HandlerFunc {
 		var req domain.Locality
 
 		if err := c.Bind(&req); err != nil {
-			c.JSON(400, web.NewResponse(400, nil, err.Error()))
+			web.Error(c, http.StatusBadRequest, "invalid_body", err)
 			return
 		}
 
-		if s.service.Exists(c.Request.Context(), req.ZipCode) {
-			web.Error(c, 409, "%s", "Ya existe un locality con ese ZipCode")
+		p, err := s.service.Save(c.Request.Context(), req)
+		if err != nil {
+			c.Error(err)
 			return
 		}
+		web.Success(c, http.StatusCreated, p)
+	}
+
+}
+
+
+// Get reports the number of sellers per locality. Without zip_code it covers
+// every locality; zip_code may be repeated or comma separated to ask for
+// several. A single zip code keeps answering with one object.
+func (s *Locality) Get() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var zipCodes []string
+		for _, v := range c.QueryArray("zip_code") {
+			for _, z := range strings.Split(v, ",") {
+				if z = strings.TrimSpace(z); z != "" {
+					zipCodes = append(zipCodes, z)
+				}
+			}
+		}
 
-		if req.ZipCode == "" {
-			c.JSON(422, web.NewResponse(422, nil, "El zip code es requerido"))
+		if _, ok := c.GetQuery("zip_code"); ok && len(zipCodes) == 0 {
+			web.Error(c, http.StatusBadRequest, "zip_code_empty")
 			return
 		}
 
-		if req.LocalityName == "" {
-			c.JSON(422, web.NewResponse(422, nil, "El nombre de la  localidad es requerido"))
+		report, err := s.service.ReportSellers(c.Request.Context(), zipCodes)
+		if err != nil {
+			c.Error(err)
 			return
 		}
 
-		if req.ProvinceName == "" {
-			c.JSON(422, web.NewResponse(422, nil, "El nombre de la provincia es requerido"))
+		found := make(map[string]bool, len(report))
+		for _, lr := range report {
+			found[lr.ZipCode] = true
+		}
+		var missing []string
+		for _, z := range zipCodes {
+			if !found[z] {
+				missing = append(missing, z)
+			}
+		}
+		if len(missing) > 0 {
+			web.Error(c, http.StatusNotFound, "localities_zip_not_found", strings.Join(missing, ", "))
 			return
 		}
 
-		if req.CountryName == "" {
-			c.JSON(422, web.NewResponse(422, nil, "El nombre del pais es requerido"))
+		if len(zipCodes) == 1 {
+			web.Success(c, http.StatusOK, report[0])
 			return
 		}
+		web.Success(c, http.StatusOK, report)
+	}
+}
 
-		p, err := s.service.Save(c.Request.Context(), req)
+
+func (s *Locality) GetAll() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		limit, offset, cursor, err := web.ParsePagination(c)
 		if err != nil {
-			c.JSON(422, web.NewResponse(422, nil, err.Error()))
+			web.ErrorFrom(c, http.StatusBadRequest, err)
+			return
+		}
+		if cursor != "" {
+			web.Error(c, http.StatusBadRequest, "locality_cursor_unsupported")
+			return
+		}
+
+		page, err := s.service.List(c.Request.Context(), locality.Query{Limit: limit, Offset: offset})
+		if err != nil {
+			c.Error(err)
 			return
 		}
-		c.JSON(201, web.NewResponse(201, p, ""))
-	}
 
+		meta := web.Pagination{Total: page.Total, Limit: page.Limit, Offset: offset}
+		web.SetPaginationHeaders(c, meta)
+		web.SuccessWithMeta(c, http.StatusOK, page.Localities, meta)
+	}
 }
 
 
-func (s *Locality) Get() gin.HandlerFunc {
+func (s *Locality) GetByID() gin.HandlerFunc {
 	return func(c *gin.Context) {
-	
-	
-		zipCode := c.Request.URL.Query().Get("zip_code")
-		
-		if zipCode == "" {
-			c.AbortWithStatusJSON(http.StatusBadRequest, web.NewResponse(http.StatusBadRequest, nil, "Parametro 'zip_code' vacío"))
+
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			web.Error(c, http.StatusBadRequest, "invalid_id")
 			return
 		}
 
-		l, err := s.service.GetByZipCode(context.Background(), zipCode)
+		l, err := s.service.Get(c.Request.Context(), id)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		web.Success(c, http.StatusOK, l)
+	}
+}
+
+
+func (s *Locality) Update() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		id, err := strconv.Atoi(c.Param("id"))
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, web.NewResponse(http.StatusInternalServerError, nil, ""))
+			web.Error(c, http.StatusBadRequest, "invalid_id")
 			return
 		}
 
-		if l.ID == 0 {
-			c.AbortWithStatusJSON(http.StatusNotFound, web.NewResponse(http.StatusNotFound, nil, ""))
+		req := domain.Locality{}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			web.Error(c, http.StatusBadRequest, "invalid_body", err)
 			return
 		}
 
-		type LocalityData struct {
-			ZipCode      string `json:"zip_code"`
-			LocalityName string `json:"locality_name"`
-			SellerCounts int    `json:"sellers_count"`
+		last, err := s.service.Get(c.Request.Context(), id)
+		if err != nil {
+			c.Error(err)
+			return
 		}
 
-		var data LocalityData
-		sellers, err := s.service.GetSellers(context.Background(), l)
+		newL := updateLocalityFields(last, req, id)
+		err = s.service.Update(c.Request.Context(), newL)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, web.NewResponse(http.StatusInternalServerError, nil, ""))
+			c.Error(err)
 			return
 		}
 
-		data.LocalityName = l.LocalityName
-		data.ZipCode = l.ZipCode
-		data.SellerCounts = len(sellers)
+		web.Success(c, http.StatusOK, newL)
+	}
+}
 
-		c.JSON(http.StatusOK, web.NewResponse(http.StatusOK, data, ""))
+
+func updateLocalityFields(last domain.Locality, req domain.Locality, id int) domain.Locality {
+	if req.ZipCode != "" {
+		last.ZipCode = req.ZipCode
+	}
+	if req.LocalityName != "" {
+		last.LocalityName = req.LocalityName
 	}
+	if req.ProvinceName != "" {
+		last.ProvinceName = req.ProvinceName
+	}
+	if req.CountryName != "" {
+		last.CountryName = req.CountryName
+	}
+
+	last.ID = id
+	return last
 }
 
 
+// Delete removes a locality. When it still has sellers the configured policy
+// applies; ?reassign_to=ID picks the locality they are moved to.
+func (s *Locality) Delete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			web.Error(c, http.StatusBadRequest, "invalid_id")
+			return
+		}
+
+		reassignTo := 0
+		if v := c.Query("reassign_to"); v != "" {
+			reassignTo, err = strconv.Atoi(v)
+			if err != nil {
+				web.Error(c, http.StatusBadRequest, "invalid_reassign_to")
+				return
+			}
+		}
+
+		err = s.service.Delete(c.Request.Context(), id, reassignTo)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		web.Success(c, http.StatusOK, web.T(c, "locality_deleted", id))
+	}
+}
+
+var localityExportHeader = []string{"id", "zip_code", "locality_name", "province_name", "country_name"}
+
+// Export streams every locality as a download in ?format= csv, ndjson or json.
+// Like the seller export, it must finish within server.write_timeout.
+func (s *Locality) Export() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		format, err := web.ExportFormat(c)
+		if err != nil {
+			web.ErrorFrom(c, http.StatusBadRequest, err)
+			return
+		}
+
+		exp := web.NewExporter(c, format, "localities", localityExportHeader)
+		err = s.service.Export(c.Request.Context(), func(l domain.Locality) error {
+			return exp.Write(l, []string{strconv.Itoa(l.ID), l.ZipCode, l.LocalityName, l.ProvinceName, l.CountryName})
+		})
+		if err == nil {
+			err = exp.Close()
+		}
+		if err != nil {
+			if c.Writer.Written() {
+				s.log.Error(c.Request.Context(), "locality export interrupted", "err", err)
+			}
+			exp.Abort()
+			c.Error(err)
+		}
+	}
+}
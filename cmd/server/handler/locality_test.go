@@ -9,6 +9,8 @@ import (
 	"testing"
 	"Sellers/internal/domain"
 	"Sellers/internal/locality"
+	"Sellers/internal/logging"
+	"Sellers/pkg/web"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -27,9 +29,9 @@ func (m *ServiceMock) Save(ctx context.Context, loc domain.Locality) (int, error
 	return args.Int(0), args.Error(1)
 }
 
-func (m *ServiceMock) Exists(ctx context.Context, zipC string) bool {
+func (m *ServiceMock) Exists(ctx context.Context, zipC string) (bool, error) {
 	args := m.Called(ctx, zipC)
-	return args.Bool(0)
+	return args.Bool(0), args.Error(1)
 }
 
 func (r *ServiceMock) GetByZipCode(ctx context.Context, zipCode string) (domain.Locality, error) {
@@ -46,13 +48,64 @@ func (r *ServiceMock) GetAll(ctx context.Context) ([]domain.Locality, error) {
 	return args.Get(0).([]domain.Locality), args.Error(1)
 }
 
+func (r *ServiceMock) List(ctx context.Context, q locality.Query) (locality.Page, error) {
+	args := r.Called(ctx, q)
+	return args.Get(0).(locality.Page), args.Error(1)
+}
+
+func (r *ServiceMock) Stream(ctx context.Context, fn func(domain.Locality) error) error {
+	args := r.Called(ctx)
+	for _, l := range args.Get(0).([]domain.Locality) {
+		if err := fn(l); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (r *ServiceMock) Get(ctx context.Context, id int) (domain.Locality, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).(domain.Locality), args.Error(1)
+}
+
+func (r *ServiceMock) CountSellers(ctx context.Context, id int) (int, error) {
+	args := r.Called(ctx, id)
+	return args.Int(0), args.Error(1)
+}
+
+func (r *ServiceMock) ReportSellers(ctx context.Context, zipCodes []string) ([]domain.LocalityReport, error) {
+	args := r.Called(ctx, zipCodes)
+	return args.Get(0).([]domain.LocalityReport), args.Error(1)
+}
+
+func (r *ServiceMock) Update(ctx context.Context, l domain.Locality) error {
+	args := r.Called(ctx, l)
+	return args.Error(0)
+}
+
+func (r *ServiceMock) Delete(ctx context.Context, id int) error {
+	args := r.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (r *ServiceMock) ReassignAndDelete(ctx context.Context, id int, to int) error {
+	args := r.Called(ctx, id, to)
+	return args.Error(0)
+}
+
 func createServerLocalities(s *Locality) *gin.Engine {
 	r := gin.Default()
+	r.Use(web.ErrorHandler())
 	locality := r.Group("/api/v1/localities")
 	{
-		locality.GET("/reportSellers", s.Get())
+		locality.GET("/reportSellers", web.Cacheable("private, no-cache"), s.Get())
+		locality.GET("/", s.GetAll())
+		locality.GET("/export", s.Export())
+		locality.GET("/:id", s.GetByID())
 		locality.POST("/", s.Create())
-	
+		locality.PATCH("/:id", s.Update())
+		locality.DELETE("/:id", s.Delete())
+
 	}
 	return r
 }
@@ -79,10 +132,10 @@ func TestCreateLocalityOK(t *testing.T) {
 
 
 	s := new(ServiceMock)
-	s.On("Exists", mock.Anything, mock.Anything).Return(false)
+	s.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
 	s.On("Save", mock.Anything, mock.Anything).Return(1, nil)
-	service := locality.NewService(s)
-	w := NewLocality(service)
+	service := locality.NewService(s, logging.Discard)
+	w := NewLocality(service, logging.Discard)
 	r := createServerLocalities(w)
 	req, res := createRequestTestLocalities(http.MethodPost, `/api/v1/localities/`, expectedResponse)
 	r.ServeHTTP(res, req)
@@ -92,10 +145,10 @@ func TestCreateLocalityOK(t *testing.T) {
 
 func TestCreateLocalityFail(t *testing.T) {
 	s := new(ServiceMock)
-	s.On("Exists", mock.Anything, mock.Anything).Return(false)
+	s.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
 	s.On("Save", mock.Anything, mock.Anything).Return(1, nil)
-	service := locality.NewService(s)
-	w := NewLocality(service)
+	service := locality.NewService(s, logging.Discard)
+	w := NewLocality(service, logging.Discard)
 	r := createServerLocalities(w)
 	body := `
 		{
@@ -113,10 +166,10 @@ func TestCreateLocalityFail(t *testing.T) {
 
 func TestCreateLocalityFailLocalityName(t *testing.T) {
 	s := new(ServiceMock)
-	s.On("Exists", mock.Anything, mock.Anything).Return(false)
+	s.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
 	s.On("Save", mock.Anything, mock.Anything).Return(1, nil)
-	service := locality.NewService(s)
-	w := NewLocality(service)
+	service := locality.NewService(s, logging.Discard)
+	w := NewLocality(service, logging.Discard)
 	r := createServerLocalities(w)
 	body := `
 		{
@@ -133,10 +186,10 @@ func TestCreateLocalityFailLocalityName(t *testing.T) {
 
 func TestCreateLocalityFailProvinceName(t *testing.T) {
 	s := new(ServiceMock)
-	s.On("Exists", mock.Anything, mock.Anything).Return(false)
+	s.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
 	s.On("Save", mock.Anything, mock.Anything).Return(1, nil)
-	service := locality.NewService(s)
-	w := NewLocality(service)
+	service := locality.NewService(s, logging.Discard)
+	w := NewLocality(service, logging.Discard)
 	r := createServerLocalities(w)
 	body := `
 		{
@@ -152,10 +205,10 @@ func TestCreateLocalityFailProvinceName(t *testing.T) {
 
 func TestCreateLocalityFailCoutryName(t *testing.T) {
 	s := new(ServiceMock)
-	s.On("Exists", mock.Anything, mock.Anything).Return(false)
+	s.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
 	s.On("Save", mock.Anything, mock.Anything).Return(1, nil)
-	service := locality.NewService(s)
-	w := NewLocality(service)
+	service := locality.NewService(s, logging.Discard)
+	w := NewLocality(service, logging.Discard)
 	r := createServerLocalities(w)
 	body := `
 		{
@@ -171,10 +224,10 @@ func TestCreateLocalityFailCoutryName(t *testing.T) {
 
 func TestCreateLocalityConflict(t *testing.T) {
 	s := new(ServiceMock)
-	s.On("Exists", mock.Anything, mock.Anything).Return(true)
+	s.On("Exists", mock.Anything, mock.Anything).Return(true, nil)
 	s.On("Save", mock.Anything, mock.AnythingOfType("domain.Locality")).Return(1,ErrNotFound)
-	service := locality.NewService(s)
-	w := NewLocality(service)
+	service := locality.NewService(s, logging.Discard)
+	w := NewLocality(service, logging.Discard)
 	r := createServerLocalities(w)
 	body := `
 		{
@@ -189,4 +242,197 @@ func TestCreateLocalityConflict(t *testing.T) {
 }
 
 
+func TestGetAllLocalitiesOK(t *testing.T) {
+	s := new(ServiceMock)
+	s.On("List", mock.Anything, locality.Query{Limit: 1, Offset: 0}).Return(locality.Page{
+		Localities: []domain.Locality{{ID: 1, ZipCode: "6700"}},
+		Total:      2,
+		Limit:      1,
+	}, nil)
+	service := locality.NewService(s, logging.Discard)
+	w := NewLocality(service, logging.Discard)
+	r := createServerLocalities(w)
+	req, res := createRequestTestLocalities(http.MethodGet, "/api/v1/localities/?limit=1", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "2", res.Header().Get("X-Total-Count"))
+	assert.Contains(t, res.Header().Get("Link"), `rel="next"`)
+}
+
+func TestGetLocalityByIDNotFound(t *testing.T) {
+	s := new(ServiceMock)
+	s.On("Get", mock.Anything, 9).Return(domain.Locality{}, locality.ErrNotFound)
+	service := locality.NewService(s, logging.Discard)
+	w := NewLocality(service, logging.Discard)
+	r := createServerLocalities(w)
+	req, res := createRequestTestLocalities(http.MethodGet, "/api/v1/localities/9", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
+
+func TestUpdateLocalityOK(t *testing.T) {
+	s := new(ServiceMock)
+	s.On("Get", mock.Anything, 1).Return(domain.Locality{ID: 1, ZipCode: "6700", LocalityName: "Lujan", ProvinceName: "Buenos Aires", CountryName: "Argentina"}, nil)
+	s.On("Update", mock.Anything, domain.Locality{ID: 1, ZipCode: "6700", LocalityName: "Lujan Centro", ProvinceName: "Buenos Aires", CountryName: "Argentina"}).Return(nil)
+	service := locality.NewService(s, logging.Discard)
+	w := NewLocality(service, logging.Discard)
+	r := createServerLocalities(w)
+	req, res := createRequestTestLocalities(http.MethodPatch, "/api/v1/localities/1", `{"locality_name": "Lujan Centro"}`)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	s.AssertExpectations(t)
+}
+
+func TestDeleteLocalityWithSellersConflict(t *testing.T) {
+	s := new(ServiceMock)
+	s.On("Get", mock.Anything, 1).Return(domain.Locality{ID: 1}, nil)
+	s.On("CountSellers", mock.Anything, 1).Return(3, nil)
+	service := locality.NewService(s, logging.Discard)
+	w := NewLocality(service, logging.Discard)
+	r := createServerLocalities(w)
+	req, res := createRequestTestLocalities(http.MethodDelete, "/api/v1/localities/1", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusConflict, res.Code)
+}
+
+func TestDeleteLocalityReassign(t *testing.T) {
+	s := new(ServiceMock)
+	s.On("Get", mock.Anything, 1).Return(domain.Locality{ID: 1}, nil)
+	s.On("Get", mock.Anything, 2).Return(domain.Locality{ID: 2}, nil)
+	s.On("CountSellers", mock.Anything, 1).Return(3, nil)
+	s.On("ReassignAndDelete", mock.Anything, 1, 2).Return(nil)
+	service := locality.NewServiceWithPolicy(s, locality.DeletePolicy{Mode: locality.DeleteReassign}, logging.Discard)
+	w := NewLocality(service, logging.Discard)
+	r := createServerLocalities(w)
+	req, res := createRequestTestLocalities(http.MethodDelete, "/api/v1/localities/1?reassign_to=2", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	s.AssertExpectations(t)
+}
+
+func TestReportSellersAllLocalities(t *testing.T) {
+	s := new(ServiceMock)
+	s.On("ReportSellers", mock.Anything, []string(nil)).Return([]domain.LocalityReport{
+		{ZipCode: "6700", LocalityName: "Lujan", SellersCount: 2},
+		{ZipCode: "4000", LocalityName: "San Miguel de Tucuman", SellersCount: 0},
+	}, nil)
+	service := locality.NewService(s, logging.Discard)
+	w := NewLocality(service, logging.Discard)
+	r := createServerLocalities(w)
+	req, res := createRequestTestLocalities(http.MethodGet, "/api/v1/localities/reportSellers", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), `{"zip_code":"4000","locality_name":"San Miguel de Tucuman","sellers_count":0}`)
+}
+
+func TestReportSellersSingleZipCode(t *testing.T) {
+	s := new(ServiceMock)
+	s.On("ReportSellers", mock.Anything, []string{"6700"}).Return([]domain.LocalityReport{
+		{ZipCode: "6700", LocalityName: "Lujan", SellersCount: 2},
+	}, nil)
+	service := locality.NewService(s, logging.Discard)
+	w := NewLocality(service, logging.Discard)
+	r := createServerLocalities(w)
+	req, res := createRequestTestLocalities(http.MethodGet, "/api/v1/localities/reportSellers?zip_code=6700", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, `{"data":{"zip_code":"6700","locality_name":"Lujan","sellers_count":2}}`, res.Body.String())
+}
+
+func TestReportSellersSeveralZipCodes(t *testing.T) {
+	s := new(ServiceMock)
+	s.On("ReportSellers", mock.Anything, []string{"6700", "4000", "5000"}).Return([]domain.LocalityReport{
+		{ZipCode: "6700", LocalityName: "Lujan", SellersCount: 2},
+		{ZipCode: "4000", LocalityName: "San Miguel de Tucuman", SellersCount: 1},
+	}, nil)
+	service := locality.NewService(s, logging.Discard)
+	w := NewLocality(service, logging.Discard)
+	r := createServerLocalities(w)
+	req, res := createRequestTestLocalities(http.MethodGet, "/api/v1/localities/reportSellers?zip_code=6700,4000&zip_code=5000", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusNotFound, res.Code)
+	assert.Contains(t, res.Body.String(), "5000")
+}
+
+func TestReportSellersEmptyZipCode(t *testing.T) {
+	s := new(ServiceMock)
+	service := locality.NewService(s, logging.Discard)
+	w := NewLocality(service, logging.Discard)
+	r := createServerLocalities(w)
+	req, res := createRequestTestLocalities(http.MethodGet, "/api/v1/localities/reportSellers?zip_code=", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+}
 
+func TestExportLocalitiesCSV(t *testing.T) {
+	s := new(ServiceMock)
+	s.On("Stream", mock.Anything).Return([]domain.Locality{
+		{ID: 1, ZipCode: "6700", LocalityName: "Lujan", ProvinceName: "Buenos Aires", CountryName: "Argentina"},
+		{ID: 2, ZipCode: "5000", LocalityName: "Cordoba, Capital", ProvinceName: "Cordoba", CountryName: "Argentina"},
+	}, nil)
+	service := locality.NewService(s, logging.Discard)
+	w := NewLocality(service, logging.Discard)
+	r := createServerLocalities(w)
+	req, res := createRequestTest(http.MethodGet, "/api/v1/localities/export?format=csv", "")
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", res.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="localities.csv"`, res.Header().Get("Content-Disposition"))
+	assert.Equal(t, "id,zip_code,locality_name,province_name,country_name\n"+
+		"1,6700,Lujan,Buenos Aires,Argentina\n"+
+		"2,5000,\"Cordoba, Capital\",Cordoba,Argentina\n", res.Body.String())
+}
+
+func TestExportLocalitiesEmptyJSON(t *testing.T) {
+	s := new(ServiceMock)
+	s.On("Stream", mock.Anything).Return([]domain.Locality{}, nil)
+	service := locality.NewService(s, logging.Discard)
+	w := NewLocality(service, logging.Discard)
+	r := createServerLocalities(w)
+	req, res := createRequestTest(http.MethodGet, "/api/v1/localities/export", "")
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "[]", res.Body.String())
+}
+
+func TestReportSellersNotModified(t *testing.T) {
+	s := new(ServiceMock)
+	s.On("ReportSellers", mock.Anything, []string{"6700"}).Return([]domain.LocalityReport{
+		{ZipCode: "6700", LocalityName: "Lujan", SellersCount: 2},
+	}, nil)
+	service := locality.NewService(s, logging.Discard)
+	w := NewLocality(service, logging.Discard)
+	r := createServerLocalities(w)
+	req, res := createRequestTestLocalities(http.MethodGet, "/api/v1/localities/reportSellers?zip_code=6700", "")
+	r.ServeHTTP(res, req)
+	etag := res.Header().Get("ETag")
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, "private, no-cache", res.Header().Get("Cache-Control"))
+
+	req, res = createRequestTestLocalities(http.MethodGet, "/api/v1/localities/reportSellers?zip_code=6700", "")
+	req.Header.Set("If-None-Match", etag)
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusNotModified, res.Code)
+	assert.Empty(t, res.Body.String())
+	assert.Equal(t, etag, res.Header().Get("ETag"))
+}
+
+func TestReportSellersErrorIsNotCached(t *testing.T) {
+	s := new(ServiceMock)
+	s.On("ReportSellers", mock.Anything, []string{"5000"}).Return([]domain.LocalityReport{}, nil)
+	service := locality.NewService(s, logging.Discard)
+	w := NewLocality(service, logging.Discard)
+	r := createServerLocalities(w)
+	req, res := createRequestTestLocalities(http.MethodGet, "/api/v1/localities/reportSellers?zip_code=5000", "")
+	req.Header.Set("If-None-Match", "*")
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusNotFound, res.Code)
+	assert.Empty(t, res.Header().Get("Cache-Control"))
+	assert.Empty(t, res.Header().Get("ETag"))
+}
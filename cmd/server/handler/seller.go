@@ -1,10 +1,12 @@
 package handler
 
 import (
+	"Sellers/internal/auth"
 	"Sellers/internal/domain"
+	"Sellers/internal/logging"
 	"Sellers/internal/seller"
 	"Sellers/pkg/web"
-	"fmt"
+	"errors"
 	"strconv"
 	"github.com/gin-gonic/gin"
 	"net/http"
@@ -16,15 +18,17 @@ type Seller struct {
 	// sellerService seller.Service
 
 	service seller.Service
+	log     *logging.Logger
 }
 
 
 
 
-func NewSeller(p seller.Service) *Seller {
+func NewSeller(p seller.Service, log *logging.Logger) *Seller {
 	return &Seller{
 		// sellerService: s,
 		service: p,
+		log:     log,
 	}
 }
 
@@ -37,18 +41,20 @@ func (s *Seller) Get() gin.HandlerFunc {
 
 		id, err := strconv.ParseInt(c.Param("id"),10, 64)
 		if err != nil  {
-			c.JSON(400, gin.H{ "error":  "invalid ID"})
+			web.Error(c, http.StatusBadRequest, "invalid_id")
 			return
 		}
 
 		p,err := s.service.Get(c.Request.Context(), int(id))
 
 		if err != nil{
-			c.JSON(404, web.NewResponse(404, nil, "No se encuentra el seller con el id ingresado"))
+			c.Error(err)
 			return
 		}
 
-		c.JSON(200, p)
+		web.SetETag(c, p.Version)
+		web.SetLastModified(c, p.UpdatedAt)
+		web.Success(c, http.StatusOK, p)
 
 
 	}
@@ -59,100 +65,151 @@ func (s *Seller) GetAll() gin.HandlerFunc {
 	return func(c *gin.Context) {
 	
 
-		p, err := s.service.GetAll(c.Request.Context())
+		q, err := sellerQuery(c)
 		if err != nil {
-			c.JSON(400, web.NewResponse(400, nil, err.Error()))
+			web.ErrorFrom(c, http.StatusBadRequest, err)
+			return
+		}
+		if err := allowIncludeDeleted(c, q); err != nil {
+			c.Error(err)
 			return
 		}
 
-		if len(p) == 0 {
-			c.JSON(404, web.NewResponse(404, nil, "No hay sellers"))
+		page, err := s.service.List(c.Request.Context(), q)
+		if errors.Is(err, seller.ErrInvalidQuery) {
+			web.ErrorFrom(c, http.StatusBadRequest, err)
+			return
+		}
+		if err != nil {
+			c.Error(err)
 			return
 		}
-		c.JSON(200, web.NewResponse(200, p, ""))
+
+		if page.Total == 0 {
+			web.Error(c, http.StatusNotFound, "sellers_empty")
+			return
+		}
+
+		meta := web.Pagination{Total: page.Total, Limit: page.Limit, Offset: q.Offset, NextCursor: page.NextCursor}
+		web.SetPaginationHeaders(c, meta)
+		web.SuccessWithMeta(c, http.StatusOK, page.Sellers, meta)
 
 	}
 }
 
 
 
-func (s *Seller) Create() gin.HandlerFunc {
-	return func(c *gin.Context) {
+var (
+	errInvalidCIDFilter          = domain.NewError(domain.ErrValidation, "invalid_cid_filter", "cid must be numeric")
+	errInvalidLocalitiesIDFilter = domain.NewError(domain.ErrValidation, "invalid_localities_id_filter", "localities_id must be numeric")
+	errInvalidIncludeDeleted     = domain.NewError(domain.ErrValidation, "invalid_include_deleted", "include_deleted must be a boolean")
+)
 
-		var req domain.Seller
+// sellerQuery reads the pagination, sort and filter query parameters of GET /sellers.
+// include_deleted=true also lists soft deleted sellers, see allowIncludeDeleted.
+func sellerQuery(c *gin.Context) (seller.Query, error) {
+	limit, offset, cursor, err := web.ParsePagination(c)
+	if err != nil {
+		return seller.Query{}, err
+	}
 
-		if err := c.Bind(&req); err != nil {
-			c.JSON(400, web.NewResponse(400, nil, err.Error()))
-			return
-		}
+	q := seller.Query{
+		Limit:  limit,
+		Offset: offset,
+		Cursor: cursor,
+		Sort:   c.Query("sort"),
+		Filter: seller.Filter{
+			CompanyName: c.Query("company_name"),
+			Province:    c.Query("province"),
+			Country:     c.Query("country"),
+		},
+	}
 
-		if s.service.Exists(c.Request.Context(), req.CID) {
-			web.Error(c, 409, "%s", "Ya existe un seller con ese CID")
-			return
+	if v := c.Query("cid"); v != "" {
+		if q.Filter.CID, err = strconv.Atoi(v); err != nil {
+			return seller.Query{}, errInvalidCIDFilter
 		}
-
-		if req.CID == 0 {
-			c.JSON(422, web.NewResponse(422, nil, "El cid es requerido"))
-			return
+	}
+	if v := c.Query("localities_id"); v != "" {
+		if q.Filter.LocalitiesID, err = strconv.Atoi(v); err != nil {
+			return seller.Query{}, errInvalidLocalitiesIDFilter
 		}
-
-		if req.Address == "" {
-			c.JSON(422, web.NewResponse(422, nil, "El domicilio es requerido"))
-			return
+	}
+	if v := c.Query("include_deleted"); v != "" {
+		if q.Filter.IncludeDeleted, err = strconv.ParseBool(v); err != nil {
+			return seller.Query{}, errInvalidIncludeDeleted
 		}
+	}
+	return q, nil
+}
 
-		if req.CompanyName == "" {
-			c.JSON(422, web.NewResponse(422, nil, "El nombre de la compañia es requerido"))
-			return
-		}
+// allowIncludeDeleted rejects include_deleted=true unless the identity of the
+// request is an admin. Without authentication every request may use it.
+func allowIncludeDeleted(c *gin.Context, q seller.Query) error {
+	if !q.Filter.IncludeDeleted {
+		return nil
+	}
+	if id, ok := web.Identity(c); ok && !id.Role.Allows(auth.Admin) {
+		return auth.ErrForbidden
+	}
+	return nil
+}
 
-		if req.Telephone == "" {
-			c.JSON(422, web.NewResponse(422, nil, "El telefono es requerido"))
-			return
-		}
-		if req.LocalitiesId == 0 {
-			c.JSON(422, web.NewResponse(422, nil, "El localitiesId es requerido"))
+func (s *Seller) Create() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var req domain.Seller
+
+		if err := c.Bind(&req); err != nil {
+			web.Error(c, http.StatusBadRequest, "invalid_body", err)
 			return
 		}
 
 		p, err := s.service.Save(c.Request.Context(), req)
 		if err != nil {
-			c.JSON(422, web.NewResponse(422, nil, err.Error()))
+			c.Error(err)
 			return
 		}
-		c.JSON(201, web.NewResponse(201, p, ""))
+		web.Success(c, http.StatusCreated, p)
 	}
 
 }
 
+// Update merges the body into the seller. With If-Match, the seller must
+// still have the ETag the client read or the update fails with 412.
 func (s *Seller) Update() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		
 		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 		if err != nil {
-			web.Error(c, http.StatusBadRequest, "%s", "El id no es valido ")
+			web.Error(c, http.StatusBadRequest, "invalid_id")
 			return
 		}
 
 		req := domain.Seller{}
 
 		if err := c.ShouldBindJSON(&req); err != nil{
-			web.Error(c, http.StatusBadRequest, "Error en la peticion: %s", err)
+			web.Error(c, http.StatusBadRequest, "invalid_body", err)
 			return
 		}
 		lastS, err := s.service.Get(c.Request.Context(), int(id))
 		if err != nil{
-			web.Error(c, http.StatusNotFound, "No se encuentra el seller con el id ingresado")
+			c.Error(err)
+			return
+		}
+		if !web.IfMatch(c, web.ETag(lastS.Version)) {
+			c.Error(seller.ErrVersionMismatch)
 			return
 		}
 
 		newS := updateSellerFields(lastS, req, int(id))
-		err = s.service.Update(c.Request.Context(), newS)
+		newS, err = s.service.Update(c.Request.Context(), newS)
 		if err != nil{
-			web.Error(c, http.StatusInternalServerError, "Ocurrio un error al actualizar el seller")
+			c.Error(err)
 			return
 		}
 
+		web.SetETag(c, newS.Version)
 		web.Success(c, http.StatusOK, newS)
 	
 	}
@@ -173,34 +230,251 @@ func updateSellerFields(lastSeller domain.Seller, newSeller domain.Seller, id in
 	if newSeller.CompanyName != lastSeller.CompanyName && newSeller.CompanyName != "" {
 		lastSeller.CompanyName = newSeller.CompanyName
 	}
+	if newSeller.LocalitiesId != lastSeller.LocalitiesId && newSeller.LocalitiesId != 0 {
+		lastSeller.LocalitiesId = newSeller.LocalitiesId
+	}
 	
 	lastSeller.ID = id
 	return lastSeller
 }
 
 
+// Restore undoes the soft delete of a seller and answers with it.
+func (s *Seller) Restore() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			web.Error(c, http.StatusBadRequest, "invalid_id")
+			return
+		}
+
+		restored, err := s.service.Restore(c.Request.Context(), id)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		web.SetETag(c, restored.Version)
+		web.Success(c, http.StatusOK, restored)
+	}
+}
+
+// History lists the changes of a seller, newest first, paginated by limit
+// and offset.
+func (s *Seller) History() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			web.Error(c, http.StatusBadRequest, "invalid_id")
+			return
+		}
+
+		limit, offset, cursor, err := web.ParsePagination(c)
+		if err != nil {
+			web.ErrorFrom(c, http.StatusBadRequest, err)
+			return
+		}
+		if cursor != "" {
+			web.Error(c, http.StatusBadRequest, "seller_history_cursor_unsupported")
+			return
+		}
+
+		page, err := s.service.History(c.Request.Context(), id, limit, offset)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		meta := web.Pagination{Total: page.Total, Limit: page.Limit, Offset: offset}
+		web.SetPaginationHeaders(c, meta)
+		web.SuccessWithMeta(c, http.StatusOK, page.Changes, meta)
+	}
+}
+
+// Delete soft deletes a seller; it can be restored until it is purged. With
+// If-Match, the seller must still have the ETag the client read or the
+// delete fails with 412.
 func (s *Seller) Delete() gin.HandlerFunc {
 	return func(c *gin.Context) {
 	
 		id, err := strconv.ParseInt(c.Param("id"),10, 64)
 		if err != nil {
-			web.Error(c, http.StatusBadRequest, "%s", "El id ingresado no es valido")
+			web.Error(c, http.StatusBadRequest, "invalid_id")
+			return
+		}
+		version := 0
+		if web.HasIfMatch(c) {
+			current, err := s.service.Get(c.Request.Context(), int(id))
+			if err != nil {
+				c.Error(err)
+				return
+			}
+			if !web.IfMatch(c, web.ETag(current.Version)) {
+				c.Error(seller.ErrVersionMismatch)
+				return
+			}
+			version = current.Version
+		}
+		err = s.service.Delete(c.Request.Context(), int(id), version)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		web.Success(c, http.StatusOK, web.T(c, "seller_deleted", id))
+
+
+	}
+}
+
+// maxImportBytes bounds the body of POST /sellers/import.
+const maxImportBytes = 10 << 20
+
+type importRow struct {
+	Line   int              `json:"line"`
+	CID    int              `json:"cid,omitempty"`
+	Status string           `json:"status"`
+	ID     int              `json:"id,omitempty"`
+	Errors []web.FieldError `json:"errors,omitempty"`
+}
+
+type importReport struct {
+	Mode      string      `json:"mode"`
+	Committed bool        `json:"committed"`
+	Created   int         `json:"created"`
+	Skipped   int         `json:"skipped"`
+	Failed    int         `json:"failed"`
+	Rows      []importRow `json:"rows"`
+}
+
+// Import creates sellers in bulk from a text/csv or application/x-ndjson body.
+// ?mode= is all-or-nothing (default) or best-effort, and ?map[column]=field
+// maps file columns to seller fields. Every row is reported: 201 when some
+// were created, 200 when none were.
+func (s *Seller) Import() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		mode, err := seller.ParseImportMode(c.Query("mode"))
+		if err != nil {
+			web.ErrorFrom(c, http.StatusBadRequest, err)
+			return
+		}
+
+		body := web.LimitBody(c, maxImportBytes)
+		mapping := c.QueryMap("map")
+
+		var records []seller.Record
+		switch c.ContentType() {
+		case "text/csv":
+			records, err = seller.ReadCSV(body, mapping)
+		case "application/x-ndjson", "application/ndjson":
+			records, err = seller.ReadNDJSON(body, mapping)
+		default:
+			web.Error(c, http.StatusUnsupportedMediaType, "import_format")
+			return
+		}
+		if errors.Is(err, web.ErrBodyTooLarge) {
+			web.Error(c, http.StatusRequestEntityTooLarge, "import_body_too_large", maxImportBytes>>20)
+			return
+		}
+		if err != nil {
+			web.ErrorFrom(c, http.StatusBadRequest, err)
 			return
 		}
-		seller, err := s.service.Get(c.Request.Context(), int(id))
-		if seller.ID == 0 {
-			web.Error(c, http.StatusNotFound, "No se encuentra el seller con id: %d", id)
+
+		report, err := s.service.Import(c.Request.Context(), records, mode)
+		if err != nil {
+			c.Error(err)
 			return
 		}
 
+		res := importReport{
+			Mode:      string(report.Mode),
+			Committed: report.Committed,
+			Created:   report.Created,
+			Skipped:   report.Skipped,
+			Failed:    report.Failed,
+			Rows:      make([]importRow, len(report.Rows)),
+		}
+		for i, row := range report.Rows {
+			res.Rows[i] = importRow{Line: row.Line, CID: row.CID, Status: string(row.Status), ID: row.ID}
+			if len(row.Errors) > 0 {
+				res.Rows[i].Errors = web.FieldErrors(c, row.Errors)
+			}
+		}
 
-		err = s.service.Delete(c.Request.Context(),int(id))
+		status := http.StatusOK
+		if report.Committed && report.Created > 0 {
+			status = http.StatusCreated
+		}
+		web.Success(c, status, res)
+	}
+}
+
+var sellerExportHeader = []string{"id", "cid", "company_name", "address", "telephone", "localities_id"}
+
+// Export streams every seller matching the GET /sellers filters and sort as
+// a download in ?format= csv, ndjson or json. Pagination parameters are
+// ignored. Exports longer than server.write_timeout are cut off, so the
+// timeout must allow for the largest export expected.
+func (s *Seller) Export() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		q, err := sellerQuery(c)
 		if err != nil {
-			web.Error(c, http.StatusInternalServerError, "Ocurrio un error al eliminar el warehouse")
+			web.ErrorFrom(c, http.StatusBadRequest, err)
 			return
 		}
-		c.JSON(200, gin.H{ "data": fmt.Sprintf("El seller %d ha sido eliminado", id) })
+		if err := allowIncludeDeleted(c, q); err != nil {
+			c.Error(err)
+			return
+		}
+		format, err := web.ExportFormat(c)
+		if err != nil {
+			web.ErrorFrom(c, http.StatusBadRequest, err)
+			return
+		}
+
+		exp := web.NewExporter(c, format, "sellers", sellerExportHeader)
+		err = s.service.Export(c.Request.Context(), q, func(se domain.Seller) error {
+			return exp.Write(se, []string{
+				strconv.Itoa(se.ID), strconv.Itoa(se.CID), se.CompanyName,
+				se.Address, se.Telephone, strconv.Itoa(se.LocalitiesId),
+			})
+		})
+		if errors.Is(err, seller.ErrInvalidQuery) {
+			web.ErrorFrom(c, http.StatusBadRequest, err)
+			return
+		}
+		if err == nil {
+			err = exp.Close()
+		}
+		if err != nil {
+			if c.Writer.Written() {
+				s.log.Error(c.Request.Context(), "seller export interrupted", "err", err)
+			}
+			exp.Abort()
+			c.Error(err)
+		}
+	}
+}
+
+// CreateBatch creates a JSON array of sellers in a single transaction and
+// answers with them, IDs included, in the same order.
+func (s *Seller) CreateBatch() gin.HandlerFunc {
+	return func(c *gin.Context) {
 
+		var req []domain.Seller
+		if err := c.ShouldBindJSON(&req); err != nil {
+			web.Error(c, http.StatusBadRequest, "invalid_body", err)
+			return
+		}
 
+		created, err := s.service.SaveBatch(c.Request.Context(), req)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		web.Success(c, http.StatusCreated, created)
 	}
 }
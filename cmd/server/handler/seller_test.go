@@ -4,11 +4,18 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+	"Sellers/internal/auth"
 	"Sellers/internal/domain"
+	"Sellers/internal/locality"
+	"Sellers/internal/logging"
 	"Sellers/internal/seller"
+	"Sellers/pkg/web"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -16,6 +23,7 @@ import (
 
 type ServiceM struct {
 	mock.Mock
+	changes []seller.Change
 }
 
 
@@ -35,32 +43,85 @@ func (m *ServiceM) GetAll(ctx context.Context) ([]domain.Seller, error) {
 	return args.Get(0).([]domain.Seller), args.Error(1)
 }
 
+func (m *ServiceM) List(ctx context.Context, q seller.Query) (seller.Page, error) {
+	args := m.Called(ctx, q)
+	return args.Get(0).(seller.Page), args.Error(1)
+}
+
+func (m *ServiceM) Stream(ctx context.Context, q seller.Query, fn func(domain.Seller) error) error {
+	args := m.Called(ctx, q)
+	for _, s := range args.Get(0).([]domain.Seller) {
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
 func (m *ServiceM) Update(ctx context.Context, s domain.Seller) error {
 	args := m.Called(ctx, s)
 	return args.Error(0)
 }
 
-func (m *ServiceM) Delete(ctx context.Context, id int) error {
+func (m *ServiceM) Delete(ctx context.Context, id int, version int) error {
+	args := m.Called(ctx, id, version)
+	return args.Error(0)
+}
+
+func (m *ServiceM) Restore(ctx context.Context, id int) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
-func (m *ServiceM) Exists(ctx context.Context, cid int) bool {
+func (m *ServiceM) Purge(ctx context.Context, olderThan time.Duration) (int64, error) {
+	args := m.Called(ctx, olderThan)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *ServiceM) Exists(ctx context.Context, cid int) (bool, error) {
 	args := m.Called(ctx, cid)
-	return args.Bool(0)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *ServiceM) WithTx(ctx context.Context, fn func(seller.Repository) error) error {
+	return fn(m)
+}
+
+// Record keeps the change so tests can inspect the audit trail.
+func (m *ServiceM) Record(ctx context.Context, c seller.Change) error {
+	m.changes = append(m.changes, c)
+	return nil
+}
+
+func (m *ServiceM) History(ctx context.Context, q seller.HistoryQuery) (seller.HistoryPage, error) {
+	args := m.Called(ctx, q)
+	return args.Get(0).(seller.HistoryPage), args.Error(1)
 }
 
 
 
+// localityFound returns a locality repository mock in which every locality exists.
+func localityFound() *ServiceMock {
+	l := new(ServiceMock)
+	l.On("Get", mock.Anything, mock.Anything).Return(domain.Locality{ID: 1}, nil)
+	return l
+}
+
 func createServer(s *Seller) *gin.Engine {
 	r := gin.Default()
+	r.Use(web.RequestID(), web.ErrorHandler())
 	seller := r.Group("/api/v1/sellers")
 	{
-		seller.GET("/", s.GetAll())
-		seller.GET("/:id", s.Get())
+		seller.GET("/", web.Cacheable("private, no-cache"), s.GetAll())
+		seller.GET("/export", s.Export())
+		seller.GET("/:id", web.Cacheable("private, no-cache"), s.Get())
 		seller.POST("/", s.Create())
+		seller.POST("/batch", s.CreateBatch())
+		seller.POST("/import", s.Import())
 		seller.PATCH("/:id", s.Update())
 		seller.DELETE("/:id", s.Delete())
+		seller.POST("/:id/restore", s.Restore())
+		seller.GET("/:id/history", s.History())
 	}
 	return r
 }
@@ -88,10 +149,10 @@ func TestCreateSellerOK(t *testing.T) {
 
 
 	s := new(ServiceM)
-	s.On("Exists", mock.Anything, mock.Anything).Return(false)
+	s.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
 	s.On("Save", mock.Anything, mock.Anything).Return(1, nil)
-	service := seller.NewService(s)
-	w := NewSeller(service)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
 	r := createServer(w)
 	req, res := createRequestTest(http.MethodPost, `/api/v1/sellers/`, expectedResponse)
 	r.ServeHTTP(res, req)
@@ -103,10 +164,10 @@ func TestCreateSellerOK(t *testing.T) {
 
 func TestCreateSellerFail(t *testing.T) {
 	s := new(ServiceM)
-	s.On("Exists", mock.Anything, mock.Anything).Return(false)
+	s.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
 	s.On("Save", mock.Anything, mock.Anything).Return(1, nil)
-	service := seller.NewService(s)
-	w := NewSeller(service)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
 	r := createServer(w)
 	body := `
 		{
@@ -124,10 +185,10 @@ func TestCreateSellerFail(t *testing.T) {
 }
 func TestCreateSellerFailCompanyName(t *testing.T) {
 	s := new(ServiceM)
-	s.On("Exists", mock.Anything, mock.Anything).Return(false)
+	s.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
 	s.On("Save", mock.Anything, mock.Anything).Return(1, nil)
-	service := seller.NewService(s)
-	w := NewSeller(service)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
 	r := createServer(w)
 	body := `
 		{
@@ -145,10 +206,10 @@ func TestCreateSellerFailCompanyName(t *testing.T) {
 
 func TestCreateSellerFailAddress(t *testing.T) {
 	s := new(ServiceM)
-	s.On("Exists", mock.Anything, mock.Anything).Return(false)
+	s.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
 	s.On("Save", mock.Anything, mock.Anything).Return(1, nil)
-	service := seller.NewService(s)
-	w := NewSeller(service)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
 	r := createServer(w)
 	body := `
 		{
@@ -167,10 +228,10 @@ func TestCreateSellerFailAddress(t *testing.T) {
 
 func TestCreateSellerFailTelephone(t *testing.T) {
 	s := new(ServiceM)
-	s.On("Exists", mock.Anything, mock.Anything).Return(false)
+	s.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
 	s.On("Save", mock.Anything, mock.Anything).Return(1, nil)
-	service := seller.NewService(s)
-	w := NewSeller(service)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
 	r := createServer(w)
 	body := `
 		{
@@ -187,10 +248,10 @@ func TestCreateSellerFailTelephone(t *testing.T) {
 
 func TestCreateSellerFailLocalities(t *testing.T) {
 	s := new(ServiceM)
-	s.On("Exists", mock.Anything, mock.Anything).Return(false)
+	s.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
 	s.On("Save", mock.Anything, mock.Anything).Return(1, nil)
-	service := seller.NewService(s)
-	w := NewSeller(service)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
 	r := createServer(w)
 	body := `
 		{
@@ -210,10 +271,10 @@ func TestCreateSellerFailLocalities(t *testing.T) {
 
 func TestCreateSellerConflict(t *testing.T) {
 	s := new(ServiceM)
-	s.On("Exists", mock.Anything, mock.Anything).Return(true)
+	s.On("Exists", mock.Anything, mock.Anything).Return(true, nil)
 	s.On("Save", mock.Anything, mock.AnythingOfType("domain.Seller")).Return(1, errors.New("Ya existe un seller con ese numero de cid"))
-	service := seller.NewService(s)
-	w := NewSeller(service)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
 	r := createServer(w)
 	body := `
 		{
@@ -252,9 +313,9 @@ func TestFindAllSellers(t *testing.T) {
 			LocalitiesId:       2,
 	})
 	s := new(ServiceM)
-	s.On("GetAll", mock.Anything, mock.Anything).Return(mockResponse, nil)
-	service := seller.NewService(s)
-	w := NewSeller(service)
+	s.On("List", mock.Anything, mock.Anything).Return(seller.Page{Sellers: mockResponse, Total: 2, Limit: seller.DefaultLimit}, nil)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
 	r := createServer(w)
 	req, res := createRequestTest(http.MethodGet, "/api/v1/sellers/", "")
 	r.ServeHTTP(res, req)
@@ -266,23 +327,115 @@ func TestFindAllSellersFailService(t *testing.T) {
 
 	
 	s := new(ServiceM)
-	s.On("GetAll", mock.Anything, mock.Anything).Return([]domain.Seller{}, ErrNotFound)
-	service := seller.NewService(s)
-	w := NewSeller(service)
+	s.On("List", mock.Anything, mock.Anything).Return(seller.Page{}, ErrNotFound)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
 	r := createServer(w)
 	req, res := createRequestTest(http.MethodGet, "/api/v1/sellers/", "")
 	r.ServeHTTP(res, req)
 
+	assert.Equal(t, 500, res.Code)
+	assert.NotContains(t, res.Body.String(), ErrNotFound.Error())
+
+}
+
+func TestFindAllSellersQueryParams(t *testing.T) {
+
+	expectedQuery := seller.Query{
+		Limit:  2,
+		Offset: 2,
+		Sort:   "-company_name",
+		Filter: seller.Filter{CompanyName: "Me", CID: 5, LocalitiesID: 3, Province: "Tucuman", Country: "Argentina"},
+	}
+	s := new(ServiceM)
+	s.On("List", mock.Anything, expectedQuery).Return(seller.Page{Sellers: []domain.Seller{{ID: 3}, {ID: 4}}, Total: 7, Limit: 2}, nil)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodGet, "/api/v1/sellers/?limit=2&offset=2&sort=-company_name&company_name=Me&cid=5&localities_id=3&province=Tucuman&country=Argentina", "")
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "7", res.Header().Get("X-Total-Count"))
+	assert.Contains(t, res.Header().Get("Link"), `offset=4`)
+	assert.Contains(t, res.Header().Get("Link"), `rel="next"`)
+	assert.Contains(t, res.Header().Get("Link"), `rel="prev"`)
+	assert.Contains(t, res.Body.String(), `"meta":{"total":7,"limit":2,"offset":2}`)
+	s.AssertExpectations(t)
+}
+
+func TestFindAllSellersInvalidParams(t *testing.T) {
+
+	s := new(ServiceM)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodGet, "/api/v1/sellers/?limit=abc", "")
+	r.ServeHTTP(res, req)
+
 	assert.Equal(t, 400, res.Code)
+	s.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+}
+
+func TestFindSellerByIdUnavailable(t *testing.T) {
+	s := new(ServiceM)
+	s.On("Get", mock.Anything, mock.Anything).Return(domain.Seller{}, domain.WithKind(domain.ErrUnavailable, errors.New("invalid connection")))
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodGet, "/api/v1/sellers/1", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+}
 
+func TestCreateSellerDuplicateCIDFromService(t *testing.T) {
+	s := new(ServiceM)
+	s.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
+	s.On("Save", mock.Anything, mock.Anything).Return(0, domain.WithKind(domain.ErrConflict, errors.New("Duplicate entry '1' for key 'uq_sellers_cid'")))
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	body := `{"cid": 1, "company_name": "Meli", "address": "Bulnes 10", "telephone": "123456", "localities_id": 1}`
+	req, res := createRequestTest(http.MethodPost, `/api/v1/sellers/`, body)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusConflict, res.Code)
+}
+
+func TestFindSellerByIdProblemResponse(t *testing.T) {
+	s := new(ServiceM)
+	s.On("Get", mock.Anything, mock.Anything).Return(domain.Seller{}, seller.ErrNotFound)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodGet, "/api/v1/sellers/100", "")
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusNotFound, res.Code)
+	assert.Equal(t, web.ProblemContentType, res.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"type":"about:blank","title":"Not Found","status":404,"code":"seller_not_found","detail":"El seller no existe","instance":"/api/v1/sellers/100"}`, res.Body.String())
+}
+
+func TestCreateSellerValidationProblem(t *testing.T) {
+	s := new(ServiceM)
+	s.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	body := `{"cid": 1, "company_name": "Meli", "telephone": "123456", "localities_id": 1}`
+	req, res := createRequestTest(http.MethodPost, `/api/v1/sellers/`, body)
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	assert.Equal(t, web.ProblemContentType, res.Header().Get("Content-Type"))
+	assert.Contains(t, res.Body.String(), `"errors":[{"field":"address","code":"required","message":"address es requerido"}]`)
 }
 
 func TestFindAllSellersFailService2(t *testing.T) {
 
 	s := new(ServiceM)
-	s.On("GetAll", mock.Anything, mock.Anything).Return([]domain.Seller{}, nil)
-	service := seller.NewService(s)
-	w := NewSeller(service)
+	s.On("List", mock.Anything, mock.Anything).Return(seller.Page{Sellers: []domain.Seller{}}, nil)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
 	r := createServer(w)
 	req, res := createRequestTest(http.MethodGet, "/api/v1/sellers/", "")
 	r.ServeHTTP(res, req)
@@ -297,11 +450,10 @@ func TestFindAllSellersFailService2(t *testing.T) {
 
 func TestFindSellerByIdNonExistent(t *testing.T) {
 	expectedResult := domain.Seller{}
-	expectedError := "sql: no rows in result set"
 	s := new(ServiceM)
-	s.On("Get", mock.Anything, mock.Anything).Return(expectedResult, errors.New(expectedError))
-	service := seller.NewService(s)
-	w := NewSeller(service)
+	s.On("Get", mock.Anything, mock.Anything).Return(expectedResult, seller.ErrNotFound)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
 	r := createServer(w)
 	req, err := createRequestTest(http.MethodGet, "/api/v1/sellers/100", "")
 	r.ServeHTTP(err, req)
@@ -322,11 +474,11 @@ func TestFindSellerByIdExistent(t *testing.T) {
 		LocalitiesId: 		1,
 	}
 
-	expectedResponse := `{"id":1,"cid":1,"company_name":"Meli","address":"Bulnes 10","telephone":"123456","localities_id":1}`
+	expectedResponse := `{"data":{"id":1,"cid":1,"company_name":"Meli","address":"Bulnes 10","telephone":"123456","localities_id":1}}`
 	s := new(ServiceM)
 	s.On("Get", mock.Anything, mock.Anything).Return(mockResponse, nil)
-	service := seller.NewService(s)
-	w := NewSeller(service)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
 	r := createServer(w)
 	req, res := createRequestTest(http.MethodGet, "/api/v1/sellers/1", "")
 	r.ServeHTTP(res, req)
@@ -344,13 +496,14 @@ func TestUpdateSellerOK(t *testing.T) {
 		CompanyName: 		"Meli",
 		Address:            "Bulnes 10",
 		Telephone:          "123456",
+		LocalitiesId:       1,
 	}
 	expectedResponse := `{"id":1,"cid":1,"company_name":"Meli","address":"Av Belgrano 3200","telephone":"654321"}`
 	s := new(ServiceM)
 	s.On("Get", mock.Anything, mock.Anything).Return(mockResponse, nil)
 	s.On("Update", mock.Anything, mock.Anything).Return(nil)
-	service := seller.NewService(s)
-	w := NewSeller(service)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
 	r := createServer(w)
 	objAc := `{"id":1,"cid":1,"company_name":"Meli","address":"Av Belgrano 3200","telephone":"654321"}`
 	req, res := createRequestTest(http.MethodPatch, `/api/v1/sellers/1`, objAc)
@@ -364,11 +517,10 @@ func TestUpdateSellerOK(t *testing.T) {
 func TestUpdateNonSeller(t *testing.T) {
 
 	expectedResult := domain.Seller{}
-	expectedError := "sql: no rows in result set"
 	s := new(ServiceM)
-	s.On("Get", mock.Anything, mock.Anything).Return(expectedResult, errors.New(expectedError))
-	service := seller.NewService(s)
-	w := NewSeller(service)
+	s.On("Get", mock.Anything, mock.Anything).Return(expectedResult, seller.ErrNotFound)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
 	r := createServer(w)
 
 	body := `
@@ -396,8 +548,8 @@ func TestUpdateSellerFail(t *testing.T) {
 	s := new(ServiceM)
 	s.On("Get", mock.Anything, mock.Anything).Return(mockResponse, nil)
 	s.On("Update", mock.Anything, mock.Anything).Return(nil)
-	service := seller.NewService(s)
-	w := NewSeller(service)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
 	r := createServer(w)
 	req, res := createRequestTest(http.MethodPatch, `/api/v1/sellers/2`,
 		`{
@@ -413,14 +565,67 @@ func TestUpdateSellerFail(t *testing.T) {
 }
 
 
+func TestUpdateSellerLocality(t *testing.T) {
+	mockResponse := domain.Seller{
+		ID:           1,
+		CID:          1,
+		CompanyName:  "Meli",
+		Address:      "Bulnes 10",
+		Telephone:    "123456",
+		LocalitiesId: 1,
+	}
+	moved := mockResponse
+	moved.LocalitiesId = 2
+	s := new(ServiceM)
+	s.On("Get", mock.Anything, mock.Anything).Return(mockResponse, nil)
+	s.On("Update", mock.Anything, moved).Return(nil)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodPatch, `/api/v1/sellers/1`, `{"localities_id": 2}`)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	s.AssertExpectations(t)
+}
+
+func TestUpdateSellerInvalidLocality(t *testing.T) {
+	s := new(ServiceM)
+	s.On("Get", mock.Anything, mock.Anything).Return(domain.Seller{ID: 1, CID: 1, LocalitiesId: 1}, nil)
+	l := new(ServiceMock)
+	l.On("Get", mock.Anything, 9).Return(domain.Locality{}, locality.ErrNotFound)
+	service := seller.NewService(s, l, logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodPatch, `/api/v1/sellers/1`, `{"localities_id": 9}`)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	s.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestCreateSellerInvalidLocality(t *testing.T) {
+	s := new(ServiceM)
+	s.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
+	l := new(ServiceMock)
+	l.On("Get", mock.Anything, 9).Return(domain.Locality{}, locality.ErrNotFound)
+	service := seller.NewService(s, l, logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	body := `{"cid": 1, "company_name": "Meli", "address": "Bulnes 10", "telephone": "123456", "localities_id": 9}`
+	req, res := createRequestTest(http.MethodPost, `/api/v1/sellers/`, body)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	assert.Contains(t, res.Body.String(), `"code":"seller_invalid_locality","detail":"La locality 9 no existe"`)
+	s.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+
 // TESTS DELETE
 
 func TestDeleteNonExistSeller(t *testing.T) {
-	expectedError := "sql: no rows in result set"
 	s := new(ServiceM)
-	s.On("Get", mock.Anything, mock.Anything).Return(domain.Seller{}, errors.New(expectedError))
-	service := seller.NewService(s)
-	w := NewSeller(service)
+	s.On("Get", mock.Anything, 2).Return(domain.Seller{}, seller.ErrNotFound)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
 	r := createServer(w)
 	req, res := createRequestTest(http.MethodDelete, `/api/v1/sellers/2`, "{}")
 	r.ServeHTTP(res, req)
@@ -439,9 +644,9 @@ func TestDeleteSellerOK(t *testing.T) {
 	}
 	s := new(ServiceM)
 	s.On("Get", mock.Anything, mock.Anything).Return(mockResponse, nil)
-	s.On("Delete", mock.Anything, mock.Anything).Return(nil)
-	service := seller.NewService(s)
-	w := NewSeller(service)
+	s.On("Delete", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
 	r := createServer(w)
 	req, res := createRequestTest(http.MethodDelete, `/api/v1/sellers/1`, "")
 	r.ServeHTTP(res, req)
@@ -500,7 +705,7 @@ func TestDeleteSellerOK(t *testing.T) {
 
 // func createSellerServer() *gin.Engine {
 // 	sellerService := NewSellerServiceMock()
-// 	seller := NewSeller(sellerService)
+// 	seller := NewSeller(sellerService, logging.Discard)
 // 	r := gin.Default()
 
 // 	sellersGroup := r.Group("/sellers")
@@ -771,3 +976,581 @@ func TestDeleteSellerOK(t *testing.T) {
 
 
 
+
+func TestCreateSellerReportsAllFieldErrors(t *testing.T) {
+	s := new(ServiceM)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	body := `{"company_name": "Meli", "telephone": "x"}`
+	req, res := createRequestTest(http.MethodPost, `/api/v1/sellers/`, body)
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	assert.Contains(t, res.Body.String(), `"errors":[{"field":"cid","code":"required","message":"cid es requerido"},{"field":"address","code":"required","message":"address es requerido"},{"field":"telephone","code":"telephone","message":"telephone no es un telefono valido"},{"field":"localities_id","code":"required","message":"localities_id es requerido"}]`)
+	s.AssertNotCalled(t, "Exists", mock.Anything, mock.Anything)
+}
+
+func TestCreateSellerProblemInEnglish(t *testing.T) {
+	s := new(ServiceM)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	body := `{"cid": 1, "company_name": "Meli", "telephone": "123456", "localities_id": 1}`
+	req, res := createRequestTest(http.MethodPost, `/api/v1/sellers/`, body)
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9,es;q=0.5")
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	assert.Contains(t, res.Body.String(), `"code":"invalid_fields","detail":"The request has invalid fields"`)
+	assert.Contains(t, res.Body.String(), `"errors":[{"field":"address","code":"required","message":"address is required"}]`)
+}
+
+func TestFindSellerByIdNotFoundInEnglish(t *testing.T) {
+	s := new(ServiceM)
+	s.On("Get", mock.Anything, mock.Anything).Return(domain.Seller{}, seller.ErrNotFound)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodGet, "/api/v1/sellers/100", "")
+	req.Header.Set("Accept-Language", "en")
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusNotFound, res.Code)
+	assert.Contains(t, res.Body.String(), `"code":"seller_not_found","detail":"The seller does not exist"`)
+}
+
+func TestImportSellersCSV(t *testing.T) {
+	s := new(ServiceM)
+	s.On("Exists", mock.Anything, 1).Return(false, nil)
+	s.On("Exists", mock.Anything, 2).Return(true, nil)
+	s.On("Save", mock.Anything, mock.Anything).Return(5, nil)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	body := "cid,company_name,address,telephone,localities_id\n" +
+		"1,Meli,Bulnes 10,123456,1\n" +
+		"2,Meli,Bulnes 10,123456,1\n" +
+		"3,Meli,,123456,1\n"
+	req, res := createRequestTest(http.MethodPost, "/api/v1/sellers/import?mode=best-effort", body)
+	req.Header.Set("Content-Type", "text/csv")
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusCreated, res.Code)
+	assert.JSONEq(t, `{"data":{"mode":"best-effort","committed":true,"created":1,"skipped":1,"failed":1,"rows":[
+		{"line":2,"cid":1,"status":"created","id":5},
+		{"line":3,"cid":2,"status":"skipped_duplicate"},
+		{"line":4,"cid":3,"status":"invalid","errors":[{"field":"address","code":"required","message":"address es requerido"}]}]}}`, res.Body.String())
+}
+
+func TestImportSellersBodyTooLarge(t *testing.T) {
+	s := new(ServiceM)
+	r := createServer(NewSeller(seller.NewService(s, localityFound(), logging.Discard), logging.Discard))
+	row := "1," + strings.Repeat("x", 10<<10) + ",Bulnes 10,123456,1\n"
+	body := "cid,company_name,address,telephone,localities_id\n" + strings.Repeat(row, maxImportBytes/len(row)+1)
+	req, res := createRequestTest(http.MethodPost, "/api/v1/sellers/import", body)
+	req.Header.Set("Content-Type", "text/csv")
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, res.Code)
+	assert.Contains(t, res.Body.String(), `"code":"import_body_too_large"`)
+	s.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestImportSellersUnsupportedFormat(t *testing.T) {
+	s := new(ServiceM)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodPost, "/api/v1/sellers/import", `[]`)
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, res.Code)
+	assert.Contains(t, res.Body.String(), `"code":"import_format"`)
+}
+
+func TestImportSellersInvalidMode(t *testing.T) {
+	s := new(ServiceM)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodPost, "/api/v1/sellers/import?mode=sometimes", "cid\n1\n")
+	req.Header.Set("Content-Type", "text/csv")
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	assert.Contains(t, res.Body.String(), `"code":"import_mode"`)
+}
+
+func TestExportSellersNDJSONWithFilters(t *testing.T) {
+	s := new(ServiceM)
+	q := seller.Query{Sort: "-company_name", Filter: seller.Filter{Country: "Argentina"}}
+	s.On("Stream", mock.Anything, q).Return([]domain.Seller{
+		{ID: 2, CID: 2, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "123456", LocalitiesId: 1},
+		{ID: 1, CID: 1, CompanyName: "Globant", Address: "Libertador 1", Telephone: "654321", LocalitiesId: 1},
+	}, nil)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodGet, "/api/v1/sellers/export?format=ndjson&country=Argentina&sort=-company_name", "")
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "application/x-ndjson", res.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="sellers.ndjson"`, res.Header().Get("Content-Disposition"))
+	assert.Equal(t, `{"id":2,"cid":2,"company_name":"Meli","address":"Bulnes 10","telephone":"123456","localities_id":1}`+"\n"+
+		`{"id":1,"cid":1,"company_name":"Globant","address":"Libertador 1","telephone":"654321","localities_id":1}`+"\n", res.Body.String())
+}
+
+func TestExportSellersFailsBeforeStreaming(t *testing.T) {
+	s := new(ServiceM)
+	s.On("Stream", mock.Anything, mock.Anything).Return([]domain.Seller{}, domain.WithKind(domain.ErrUnavailable, errors.New("bad connection")))
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodGet, "/api/v1/sellers/export?format=csv", "")
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+	assert.Equal(t, web.ProblemContentType, res.Header().Get("Content-Type"))
+	assert.Empty(t, res.Header().Get("Content-Disposition"))
+}
+
+func TestExportSellersFailsBeforeFlushing(t *testing.T) {
+	s := new(ServiceM)
+	s.On("Stream", mock.Anything, mock.Anything).Return([]domain.Seller{{ID: 1, CID: 1}}, domain.WithKind(domain.ErrUnavailable, errors.New("bad connection")))
+	r := createServer(NewSeller(seller.NewService(s, localityFound(), logging.Discard), logging.Discard))
+	req, res := createRequestTest(http.MethodGet, "/api/v1/sellers/export?format=csv", "")
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+	assert.Equal(t, web.ProblemContentType, res.Header().Get("Content-Type"))
+	assert.Empty(t, res.Header().Get("Content-Disposition"))
+	assert.NotContains(t, res.Body.String(), "company_name")
+}
+
+func TestExportSellersAbortsConnectionWhenStreamFails(t *testing.T) {
+	s := new(ServiceM)
+	s.On("Stream", mock.Anything, mock.Anything).Return([]domain.Seller{{ID: 1, CID: 1}, {ID: 2, CID: 2}}, errors.New("bad connection"))
+	var buf bytes.Buffer
+	log := logging.New(&buf, logging.LevelInfo)
+	// done is closed once the handler returned, even by panicking, so buf
+	// is no longer written when it is read.
+	done := make(chan struct{})
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		defer close(done)
+		c.Next()
+	}, web.Recovery(log), web.ErrorHandler())
+	r.GET("/api/v1/sellers/export", NewSeller(seller.NewService(s, localityFound(), logging.Discard), log).Export())
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/api/v1/sellers/export?format=json")
+	if err == nil {
+		_, err = io.ReadAll(res.Body)
+		res.Body.Close()
+	}
+	<-done
+
+	assert.Error(t, err)
+	assert.Contains(t, buf.String(), `"msg":"seller export interrupted"`)
+	assert.NotContains(t, buf.String(), `"msg":"panic"`)
+}
+
+func TestExportSellersInvalidFormat(t *testing.T) {
+	s := new(ServiceM)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodGet, "/api/v1/sellers/export?format=xml", "")
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	assert.Contains(t, res.Body.String(), `"code":"export_format"`)
+}
+
+func TestCreateSellersBatch(t *testing.T) {
+	s := new(ServiceM)
+	s.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
+	s.On("Save", mock.Anything, mock.MatchedBy(func(se domain.Seller) bool { return se.CID == 1 })).Return(8, nil)
+	s.On("Save", mock.Anything, mock.MatchedBy(func(se domain.Seller) bool { return se.CID == 2 })).Return(9, nil)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	body := `[{"cid": 1, "company_name": "Meli", "address": "Bulnes 10", "telephone": "123456", "localities_id": 1},
+		{"cid": 2, "company_name": "Globant", "address": "Libertador 1", "telephone": "654321", "localities_id": 1}]`
+	req, res := createRequestTest(http.MethodPost, "/api/v1/sellers/batch", body)
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusCreated, res.Code)
+	assert.JSONEq(t, `{"data":[
+		{"id":8,"cid":1,"company_name":"Meli","address":"Bulnes 10","telephone":"123456","localities_id":1},
+		{"id":9,"cid":2,"company_name":"Globant","address":"Libertador 1","telephone":"654321","localities_id":1}]}`, res.Body.String())
+}
+
+func TestCreateSellersBatchConflict(t *testing.T) {
+	s := new(ServiceM)
+	s.On("Exists", mock.Anything, 1).Return(true, nil)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	body := `[{"cid": 1, "company_name": "Meli", "address": "Bulnes 10", "telephone": "123456", "localities_id": 1}]`
+	req, res := createRequestTest(http.MethodPost, "/api/v1/sellers/batch", body)
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusConflict, res.Code)
+	assert.Contains(t, res.Body.String(), `"code":"seller_batch_cid_exists","detail":"El seller 0 del lote tiene un CID que ya existe (1)"`)
+	s.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestRestoreSeller(t *testing.T) {
+	s := new(ServiceM)
+	s.On("Restore", mock.Anything, 1).Return(nil)
+	s.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, CID: 1, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "123456", LocalitiesId: 1}, nil)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodPost, "/api/v1/sellers/1/restore", "")
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, `{"data":{"id":1,"cid":1,"company_name":"Meli","address":"Bulnes 10","telephone":"123456","localities_id":1}}`, res.Body.String())
+}
+
+func TestRestoreSellerNotDeleted(t *testing.T) {
+	s := new(ServiceM)
+	s.On("Restore", mock.Anything, 1).Return(seller.ErrNotFound)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodPost, "/api/v1/sellers/1/restore", "")
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
+
+func TestFindAllSellersIncludeDeleted(t *testing.T) {
+	s := new(ServiceM)
+	q := seller.Query{Limit: seller.DefaultLimit, Filter: seller.Filter{IncludeDeleted: true}}
+	s.On("List", mock.Anything, q).Return(seller.Page{Sellers: []domain.Seller{{ID: 1}}, Total: 1, Limit: seller.DefaultLimit}, nil)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodGet, "/api/v1/sellers/?include_deleted=true", "")
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	s.AssertExpectations(t)
+}
+
+func TestSellerHistory(t *testing.T) {
+	s := new(ServiceM)
+	changes := []seller.Change{
+		{ID: 2, SellerID: 1, Action: seller.ActionDelete, Before: &domain.Seller{ID: 1, CID: 123}, Actor: "ana", CreatedAt: time.Date(2022, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{ID: 1, SellerID: 1, Action: seller.ActionCreate, After: &domain.Seller{ID: 1, CID: 123}, Actor: "ana", CreatedAt: time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	s.On("History", mock.Anything, seller.HistoryQuery{SellerID: 1, Limit: 2}).Return(seller.HistoryPage{Changes: changes, Total: 3, Limit: 2}, nil)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodGet, "/api/v1/sellers/1/history?limit=2", "")
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "3", res.Header().Get("X-Total-Count"))
+	assert.Contains(t, res.Body.String(), `{"id":2,"seller_id":1,"action":"delete","before":{"id":1,"cid":123,"company_name":"","address":"","telephone":"","localities_id":0},"after":null,"actor":"ana","created_at":"2022-03-02T00:00:00Z"}`)
+}
+
+func TestSellerHistoryUnknownSeller(t *testing.T) {
+	s := new(ServiceM)
+	s.On("History", mock.Anything, mock.Anything).Return(seller.HistoryPage{}, seller.ErrNotFound)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodGet, "/api/v1/sellers/9/history", "")
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
+
+func TestSellerHistoryRejectsCursor(t *testing.T) {
+	s := new(ServiceM)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodGet, "/api/v1/sellers/1/history?cursor=abc", "")
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	assert.Contains(t, res.Body.String(), `"code":"seller_history_cursor_unsupported"`)
+	s.AssertNotCalled(t, "History", mock.Anything, mock.Anything)
+}
+
+func TestDeleteSellerRecordsRequestID(t *testing.T) {
+	s := new(ServiceM)
+	s.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, CID: 1}, nil)
+	s.On("Delete", mock.Anything, 1, 0).Return(nil)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodDelete, "/api/v1/sellers/1", "")
+	req.Header.Set(web.RequestIDHeader, "req-42")
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Len(t, s.changes, 1)
+	assert.Equal(t, "req-42", s.changes[0].RequestID)
+	assert.Equal(t, seller.ActionDelete, s.changes[0].Action)
+}
+
+func TestGetSellerSetsETag(t *testing.T) {
+	s := new(ServiceM)
+	s.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, CID: 1, Version: 3}, nil)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodGet, "/api/v1/sellers/1", "")
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, `"3"`, res.Header().Get("ETag"))
+	assert.NotContains(t, res.Body.String(), "version")
+}
+
+func TestUpdateSellerIfMatch(t *testing.T) {
+	current := domain.Seller{ID: 1, CID: 1, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "123456", LocalitiesId: 1, Version: 3}
+	s := new(ServiceM)
+	s.On("Get", mock.Anything, 1).Return(current, nil)
+	s.On("Update", mock.Anything, mock.Anything).Return(nil)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodPatch, "/api/v1/sellers/1", `{"address":"Av Belgrano 3200"}`)
+	req.Header.Set("If-Match", `"3"`)
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, `"4"`, res.Header().Get("ETag"))
+}
+
+func TestUpdateSellerStaleIfMatch(t *testing.T) {
+	s := new(ServiceM)
+	s.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, CID: 1, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "123456", LocalitiesId: 1, Version: 4}, nil)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodPatch, "/api/v1/sellers/1", `{"address":"Av Belgrano 3200"}`)
+	req.Header.Set("If-Match", `"3"`)
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusPreconditionFailed, res.Code)
+	assert.Contains(t, res.Body.String(), `"code":"seller_version_mismatch"`)
+	s.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestUpdateSellerLostRace(t *testing.T) {
+	s := new(ServiceM)
+	s.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, CID: 1, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "123456", LocalitiesId: 1, Version: 3}, nil)
+	s.On("Update", mock.Anything, mock.Anything).Return(seller.ErrVersionMismatch)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodPatch, "/api/v1/sellers/1", `{"address":"Av Belgrano 3200"}`)
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusPreconditionFailed, res.Code)
+}
+
+func TestDeleteSellerStaleIfMatch(t *testing.T) {
+	s := new(ServiceM)
+	s.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, CID: 1, Version: 4}, nil)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodDelete, "/api/v1/sellers/1", "")
+	req.Header.Set("If-Match", `"3", "2"`)
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusPreconditionFailed, res.Code)
+	s.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDeleteSellerIfMatch(t *testing.T) {
+	s := new(ServiceM)
+	s.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, CID: 1, Version: 4}, nil)
+	s.On("Delete", mock.Anything, 1, 4).Return(nil)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+	req, res := createRequestTest(http.MethodDelete, "/api/v1/sellers/1", "")
+	req.Header.Set("If-Match", `"4"`)
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	s.AssertExpectations(t)
+}
+
+func TestGetSellerNotModified(t *testing.T) {
+	updated := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := new(ServiceM)
+	s.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, CID: 1, Version: 3, UpdatedAt: updated}, nil)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+
+	req, res := createRequestTest(http.MethodGet, "/api/v1/sellers/1", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "Tue, 01 Mar 2022 12:00:00 GMT", res.Header().Get("Last-Modified"))
+	assert.Equal(t, "private, no-cache", res.Header().Get("Cache-Control"))
+
+	req, res = createRequestTest(http.MethodGet, "/api/v1/sellers/1", "")
+	req.Header.Set("If-None-Match", `W/"3"`)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusNotModified, res.Code)
+	assert.Empty(t, res.Body.String())
+
+	req, res = createRequestTest(http.MethodGet, "/api/v1/sellers/1", "")
+	req.Header.Set("If-Modified-Since", "Tue, 01 Mar 2022 12:00:00 GMT")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusNotModified, res.Code)
+
+	req, res = createRequestTest(http.MethodGet, "/api/v1/sellers/1", "")
+	req.Header.Set("If-None-Match", `"2"`)
+	req.Header.Set("If-Modified-Since", "Tue, 01 Mar 2022 12:00:00 GMT")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code, "If-None-Match takes precedence over If-Modified-Since")
+}
+
+func TestFindAllSellersNotModified(t *testing.T) {
+	s := new(ServiceM)
+	s.On("List", mock.Anything, mock.Anything).Return(seller.Page{Sellers: []domain.Seller{{ID: 1, CID: 1}}, Total: 1, Limit: seller.DefaultLimit}, nil)
+	service := seller.NewService(s, localityFound(), logging.Discard)
+	w := NewSeller(service, logging.Discard)
+	r := createServer(w)
+
+	req, res := createRequestTest(http.MethodGet, "/api/v1/sellers/", "")
+	r.ServeHTTP(res, req)
+	etag := res.Header().Get("ETag")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.NotEmpty(t, etag)
+
+	req, res = createRequestTest(http.MethodGet, "/api/v1/sellers/", "")
+	req.Header.Set("If-None-Match", `"stale", `+etag)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusNotModified, res.Code)
+	assert.Equal(t, "1", res.Header().Get("X-Total-Count"))
+}
+
+// testTokens authenticate an identity of each role.
+var testTokens = map[string]auth.Identity{
+	"0123456789abcdef": {Name: "ana", Role: auth.Admin},
+	"editor-token-0001": {Name: "eli", Role: auth.Editor},
+	"reader-token-0001": {Name: "rui", Role: auth.Reader},
+}
+
+// createAuthServer mounts seller routes behind token authentication. The
+// roles each route requires are tested on routes.MapRoutes.
+func createAuthServer(s *Seller) *gin.Engine {
+	r := gin.Default()
+	r.Use(web.RequestID(), web.ErrorHandler(), web.Authenticate(auth.NewStatic(testTokens)))
+	seller := r.Group("/api/v1/sellers")
+	{
+		seller.GET("", web.Require(auth.Reader), s.GetAll())
+		seller.GET("/export", web.Require(auth.Reader), s.Export())
+		seller.GET("/:id", web.Require(auth.Reader), s.Get())
+		seller.PATCH("/:id", web.Require(auth.Editor), s.Update())
+		seller.POST("/batch", web.Require(auth.Admin), s.CreateBatch())
+		seller.DELETE("/:id", web.Require(auth.Admin), s.Delete())
+	}
+	return r
+}
+
+func TestDeleteSellerWithoutToken(t *testing.T) {
+	s := new(ServiceM)
+	r := createAuthServer(NewSeller(seller.NewService(s, localityFound(), logging.Discard), logging.Discard))
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/sellers/1", nil)
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	assert.Equal(t, `Bearer realm="sellers"`, res.Header().Get("WWW-Authenticate"))
+	assert.Contains(t, res.Body.String(), `"code":"unauthenticated"`)
+	s.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDeleteSellerWithInvalidToken(t *testing.T) {
+	s := new(ServiceM)
+	r := createAuthServer(NewSeller(seller.NewService(s, localityFound(), logging.Discard), logging.Discard))
+	req, res := createRequestTest(http.MethodDelete, "/api/v1/sellers/1", "")
+	req.Header.Set("token", "1234")
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	assert.Contains(t, res.Header().Get("WWW-Authenticate"), `error="invalid_token"`)
+	assert.Contains(t, res.Body.String(), `"code":"invalid_token"`)
+}
+
+func TestDeleteSellerRecordsAuthenticatedActor(t *testing.T) {
+	for _, set := range []func(*http.Request){
+		func(req *http.Request) { req.Header.Set("Authorization", "Bearer 0123456789abcdef") },
+		func(req *http.Request) { req.Header.Set("token", "0123456789abcdef") },
+	} {
+		s := new(ServiceM)
+		s.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, CID: 1}, nil)
+		s.On("Delete", mock.Anything, 1, 0).Return(nil)
+		r := createAuthServer(NewSeller(seller.NewService(s, localityFound(), logging.Discard), logging.Discard))
+		req, res := createRequestTest(http.MethodDelete, "/api/v1/sellers/1", "")
+		set(req)
+		r.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "ana", s.changes[0].Actor)
+	}
+}
+
+func TestUpdateSellerRecordsEditor(t *testing.T) {
+	s := new(ServiceM)
+	s.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, CID: 1, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "123456", LocalitiesId: 1, Version: 1}, nil)
+	s.On("Update", mock.Anything, mock.Anything).Return(nil)
+	r := createAuthServer(NewSeller(seller.NewService(s, localityFound(), logging.Discard), logging.Discard))
+
+	req, res := createRequestTest(http.MethodGet, "/api/v1/sellers/1", "")
+	req.Header.Set("token", "reader-token-0001")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	req, res = createRequestTest(http.MethodPatch, "/api/v1/sellers/1", `{"address":"Av Belgrano 3200"}`)
+	req.Header.Set("token", "editor-token-0001")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "eli", s.changes[0].Actor)
+}
+
+func TestIncludeDeletedOnlyForAdmins(t *testing.T) {
+	s := new(ServiceM)
+	s.On("List", mock.Anything, mock.Anything).Return(seller.Page{Sellers: []domain.Seller{{ID: 1}}, Total: 1, Limit: seller.DefaultLimit}, nil)
+	r := createAuthServer(NewSeller(seller.NewService(s, localityFound(), logging.Discard), logging.Discard))
+
+	for _, url := range []string{"/api/v1/sellers?include_deleted=true", "/api/v1/sellers/export?include_deleted=true"} {
+		for _, token := range []string{"reader-token-0001", "editor-token-0001"} {
+			req, res := createRequestTest(http.MethodGet, url, "")
+			req.Header.Set("token", token)
+			r.ServeHTTP(res, req)
+			assert.Equal(t, http.StatusForbidden, res.Code, url)
+			assert.Contains(t, res.Body.String(), `"code":"forbidden"`)
+		}
+	}
+	s.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+
+	req, res := createRequestTest(http.MethodGet, "/api/v1/sellers?include_deleted=false", "")
+	req.Header.Set("token", "reader-token-0001")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	req, res = createRequestTest(http.MethodGet, "/api/v1/sellers?include_deleted=true", "")
+	req.Header.Set("token", "0123456789abcdef")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+}
@@ -1,23 +1,229 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/go-sql-driver/mysql"
 	"Sellers/cmd/server/routes"
+	"Sellers/internal/auth"
+	"Sellers/internal/config"
+	"Sellers/internal/lifecycle"
+	"Sellers/internal/locality"
+	"Sellers/internal/logging"
+	"Sellers/internal/migration"
+	"Sellers/internal/seller"
 )
 
+const usage = `usage:
+  server [flags]                       start the HTTP server
+  server migrate up [flags]            apply pending migrations
+  server migrate down [steps] [flags]  roll back the last migration(s), 1 by default
+  server migrate status [flags]        list migrations and whether they are applied
+  server purge [flags]                 hard delete sellers soft deleted longer than -sellers.retention ago
+  server token create <name> <role> [flags]
+                                       issue an API token for name with role
+                                       (reader, editor or admin) and print it
+  server token revoke <name> [flags]   revoke every API token of name`
+
 func main() {
 
-	//db, _ := sql.Open("mysql", "meli_sprint_user:Meli_Sprint#123@/melisprint") // me falta el host: puerto, nombre bd
-	db, _ := sql.Open("mysql", "root:peti96_cnc@tcp(127.0.0.1:3306)/meli")
-	r := gin.Default()
+	args := os.Args[1:]
+	var command []string
+	if len(args) > 0 && (args[0] == "migrate" || args[0] == "purge" || args[0] == "token") {
+		command, args = splitCommand(args)
+	}
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	level, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logger := logging.New(os.Stderr, level)
+
+	db, err := sql.Open("mysql", cfg.Database.DSN())
+	if err != nil {
+		log.Fatalf("opening database: %v", err)
+	}
+	db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime.Duration)
 
-	router := routes.NewRouter(r, db)
+	if command != nil {
+		run := migrate
+		switch command[0] {
+		case "purge":
+			run = func(db *sql.DB, args []string) error { return purge(db, cfg, logger, args) }
+		case "token":
+			run = func(db *sql.DB, args []string) error { return token(db, logger, args) }
+		}
+		if err := run(db, command[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if cfg.LogLevel != "debug" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	r := gin.New()
+
+	router := routes.NewRouter(r, db, cfg, logger)
 	router.MapRoutes()
 
-	if err := r.Run(":3001"); err != nil {
-		panic(err)
+	srv := &http.Server{
+		Addr:         cfg.Server.Address,
+		Handler:      r,
+		ReadTimeout:  cfg.Server.ReadTimeout.Duration,
+		WriteTimeout: cfg.Server.WriteTimeout.Duration,
+		IdleTimeout:  cfg.Server.IdleTimeout.Duration,
+	}
+
+	// Components stop in reverse: the server drains and finishes its
+	// requests before the database pool is closed.
+	lc := lifecycle.New(logger)
+	lc.Append("database", lifecycle.Hook{OnStop: func(context.Context) error { return db.Close() }})
+	lc.Append("http", lifecycle.NewServer(srv, router.Drain, cfg.Server.DrainPeriod.Duration, lc.Fail))
+
+	// The first signal restores the default handling before the service
+	// stops, so a second one kills the process instead of waiting for the
+	// drain.
+	signaled, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-signaled.Done()
+		stop()
+		cancel()
+	}()
+	logger.Info(ctx, "listening", "address", cfg.Server.Address)
+	if err := lc.Run(ctx, cfg.Server.DrainPeriod.Duration+cfg.Server.ShutdownTimeout.Duration); err != nil {
+		logger.Error(context.Background(), "server stopped", "err", err)
+		os.Exit(1)
+	}
+	logger.Info(context.Background(), "server stopped")
+}
+
+// splitCommand separates the leading positional words (e.g. "migrate down 2")
+// from the flags that follow them.
+func splitCommand(args []string) ([]string, []string) {
+	i := 0
+	for i < len(args) && !strings.HasPrefix(args[i], "-") {
+		i++
+	}
+	return args[:i], args[i:]
+}
+
+func migrate(db *sql.DB, args []string) error {
+	m, err := migration.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate action\n%s", usage)
+	}
+
+	switch args[0] {
+	case "up":
+		ran, err := m.Up(ctx)
+		for _, mg := range ran {
+			fmt.Println("applied", mg)
+		}
+		if err == nil && len(ran) == 0 {
+			fmt.Println("schema is up to date")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		rolled, err := m.Down(ctx, steps)
+		for _, mg := range rolled {
+			fmt.Println("rolled back", mg)
+		}
+		return err
+	case "status":
+		st, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range st {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate action %q\n%s", args[0], usage)
+	}
+}
+
+func purge(db *sql.DB, cfg config.Config, logger *logging.Logger, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("purge takes no arguments\n%s", usage)
+	}
+	service := seller.NewService(seller.NewRepository(db, logger), locality.NewRepository(db, logger), logger)
+	n, err := service.Purge(context.Background(), cfg.Sellers.Retention.Duration)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("purged %d sellers deleted more than %s ago\n", n, cfg.Sellers.Retention)
+	return nil
+}
+
+func token(db *sql.DB, logger *logging.Logger, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("token takes an action and a name\n%s", usage)
+	}
+	repo := auth.NewRepository(db, logger)
+	ctx := context.Background()
+
+	switch args[0] {
+	case "create":
+		if len(args) != 3 {
+			return fmt.Errorf("token create takes a name and a role\n%s", usage)
+		}
+		role, err := auth.ParseRole(args[2])
+		if err != nil {
+			return err
+		}
+		t, err := repo.Create(ctx, args[1], role)
+		if err != nil {
+			return err
+		}
+		fmt.Println(t)
+		return nil
+	case "revoke":
+		if len(args) != 2 {
+			return fmt.Errorf("token revoke takes a name\n%s", usage)
+		}
+		if err := repo.Revoke(ctx, args[1]); err != nil {
+			return err
+		}
+		fmt.Println("revoked the tokens of", args[1])
+		return nil
+	default:
+		return fmt.Errorf("unknown token action %q\n%s", args[0], usage)
 	}
-}
\ No newline at end of file
+}
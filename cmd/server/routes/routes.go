@@ -1,31 +1,150 @@
 package routes
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/gin-gonic/gin"
 	"Sellers/internal/seller"
 	"Sellers/cmd/server/handler"
+	"Sellers/internal/auth"
+	"Sellers/internal/cache"
+	"Sellers/internal/config"
+	"Sellers/internal/health"
 	"Sellers/internal/locality"
+	"Sellers/internal/logging"
+	"Sellers/internal/metrics"
+	"Sellers/internal/migration"
+	"Sellers/internal/ratelimit"
+	"Sellers/pkg/web"
 )
 
 type Router interface {
 	MapRoutes()
+	// Drain makes GET /readyz fail so the service stops receiving traffic
+	// before it shuts down.
+	Drain()
 }
 
 type router struct {
-	r  *gin.Engine
-	rg *gin.RouterGroup
-	db *sql.DB
+	r   *gin.Engine
+	rg  *gin.RouterGroup
+	db  *sql.DB
+	cfg config.Config
+	log *logging.Logger
+	// cache is shared by the seller and locality repositories, nil when
+	// the repository cache is disabled.
+	cache *cache.Cache
+	// limits holds the rate limit buckets, nil when rate limiting is
+	// disabled.
+	limits ratelimit.Store
+	// metrics is what GET /metrics exposes: HTTP requests, repository
+	// operations, the database pool and business gauges.
+	metrics     *metrics.Registry
+	httpMetrics *metrics.HTTP
+	ops         *metrics.Operations
+	// health backs GET /readyz.
+	health *health.Checker
 }
 
-func NewRouter(r *gin.Engine, db *sql.DB) Router {
-	return &router{r: r, db: db}
+func NewRouter(r *gin.Engine, db *sql.DB, cfg config.Config, log *logging.Logger) Router {
+	rt := &router{r: r, db: db, cfg: cfg, log: log, metrics: metrics.NewRegistry()}
+	rt.httpMetrics = metrics.NewHTTP(rt.metrics)
+	rt.ops = metrics.NewOperations(rt.metrics)
+	rt.metrics.Register(metrics.NewDBStats(db))
+	if cfg.RepoCache.Enabled {
+		rt.cache = cache.New(cache.NewLRU(cfg.RepoCache.Size, cfg.RepoCache.TTL.Duration))
+		rt.metrics.Register(metrics.NewCacheStats(rt.cache))
+	}
+	if cfg.RateLimit.Enabled {
+		rt.limits = ratelimit.NewMemory()
+	}
+	rt.health = health.New(cfg.Server.HealthTimeout.Duration)
+	rt.health.Add("database", health.Database(db))
+	if m, err := migration.NewMigrator(db); err != nil {
+		rt.health.Add("migrations", func(context.Context) error { return err })
+	} else {
+		rt.health.Add("migrations", health.Migrations(m))
+	}
+	return rt
+}
+
+func (r *router) Drain() {
+	r.health.Drain()
+}
+
+// authenticator accepts the static tokens of the config and then those stored
+// in the database.
+func (r *router) authenticator() auth.Authenticator {
+	static := make(map[string]auth.Identity, len(r.cfg.Auth.Tokens))
+	for _, t := range r.cfg.Auth.Tokens {
+		static[t.Token] = auth.Identity{Name: t.Name, Role: auth.Role(t.Role)}
+	}
+	return auth.Chain{auth.NewStatic(static), auth.NewRepository(r.db, r.log)}
+}
+
+// allow restricts a route to identities whose role allows role. Without
+// authentication there are no identities, so every request is let through.
+func (r *router) allow(role auth.Role) gin.HandlerFunc {
+	if !r.cfg.Auth.Enabled {
+		return func(*gin.Context) {}
+	}
+	return web.Require(role)
+}
+
+// limiters returns the rate limits of the read, write and bulk route groups,
+// which let every request through when rate limiting is disabled.
+func (r *router) limiters() (read, write, bulk gin.HandlerFunc) {
+	return r.limit("read", r.cfg.RateLimit.Read), r.limit("write", r.cfg.RateLimit.Write), r.limit("bulk", r.cfg.RateLimit.Bulk)
+}
+
+func (r *router) limit(group string, rate config.Rate) gin.HandlerFunc {
+	if r.limits == nil {
+		return func(*gin.Context) {}
+	}
+	return web.RateLimit(r.limits, group, ratelimit.Limit{Requests: rate.Requests, Per: rate.Per.Duration})
+}
+
+// sellerRepository returns the seller repository, instrumented and cached if
+// configured. Cache hits are not repository operations.
+func (r *router) sellerRepository() seller.Repository {
+	repo := seller.NewInstrumentedRepository(seller.NewRepository(r.db, r.log), r.ops)
+	if r.cache != nil {
+		repo = seller.NewCachedRepository(repo, r.cache)
+	}
+	return repo
+}
+
+// localityRepository returns the locality repository, instrumented and cached
+// if configured.
+func (r *router) localityRepository() locality.Repository {
+	repo := locality.NewInstrumentedRepository(locality.NewRepository(r.db, r.log), r.ops)
+	if r.cache != nil {
+		repo = locality.NewCachedRepository(repo, r.cache)
+	}
+	return repo
 }
 
 func (r *router) MapRoutes() {
+	// Validate checked the proxies, so this cannot fail.
+	if err := r.r.SetTrustedProxies(r.cfg.Server.TrustedProxies); err != nil {
+		panic(err)
+	}
+	r.r.Use(web.RequestID(), web.Logger(r.log), web.Metrics(r.httpMetrics), web.Recovery(r.log), web.Localize(), web.ErrorHandler())
+	// The orchestrator probes without a token, so these go before
+	// authentication.
+	r.r.GET("/healthz", web.Liveness())
+	r.r.GET("/readyz", web.Readiness(r.health))
+	// Limited by IP before authentication, so requests with bad tokens
+	// cannot hammer the api_tokens table; the route groups limit by token.
+	r.r.Use(r.limit("client", r.cfg.RateLimit.Client))
+	if r.cfg.Auth.Enabled {
+		r.r.Use(web.Authenticate(r.authenticator()))
+	}
 	r.setGroup()
 
+	r.r.GET("/metrics", r.allow(auth.Reader), web.MetricsHandler(r.metrics))
+
 	r.buildSellerRoutes()
 	r.buildLocalityRoutes()
 
@@ -37,23 +156,48 @@ func (r *router) setGroup() {
 
 func (r *router) buildSellerRoutes() {
 	// Example
-	repo := seller.NewRepository(r.db)
-	service := seller.NewService(repo)
-	handler := handler.NewSeller(service)
-	r.r.GET("/sellers", handler.GetAll())
-	r.r.GET("/sellers/:id", handler.Get())
-	r.r.POST("/sellers", handler.Create())
-	r.r.DELETE("/sellers/:id", handler.Delete())
-	r.r.PATCH("/sellers/:id", handler.Update())
+	service := seller.NewService(r.sellerRepository(), r.localityRepository(), r.log)
+	handler := handler.NewSeller(service, r.log)
+	read, write, bulk := r.limiters()
+	// Scrapes count straight from the database, so they do not show up as
+	// repository operations or fill the cache.
+	sellers := seller.NewRepository(r.db, r.log)
+	r.metrics.Register(metrics.NewGaugeFunc("sellers", "Sellers not deleted.", func(ctx context.Context) (float64, error) {
+		p, err := sellers.List(ctx, seller.Query{Limit: 1})
+		return float64(p.Total), err
+	}))
+	r.r.GET("/sellers", read, r.allow(auth.Reader), web.Cacheable(r.cfg.Cache.Sellers), handler.GetAll())
+	r.r.GET("/sellers/export", read, r.allow(auth.Reader), handler.Export())
+	r.r.GET("/sellers/:id", read, r.allow(auth.Reader), web.Cacheable(r.cfg.Cache.Seller), handler.Get())
+	r.r.POST("/sellers", r.limit("create", r.cfg.RateLimit.Create), r.allow(auth.Editor), handler.Create())
+	r.r.POST("/sellers/batch", bulk, r.allow(auth.Admin), handler.CreateBatch())
+	r.r.POST("/sellers/import", bulk, r.allow(auth.Admin), handler.Import())
+	r.r.DELETE("/sellers/:id", write, r.allow(auth.Admin), handler.Delete())
+	r.r.POST("/sellers/:id/restore", write, r.allow(auth.Admin), handler.Restore())
+	r.r.GET("/sellers/:id/history", read, r.allow(auth.Reader), handler.History())
+	r.r.PATCH("/sellers/:id", write, r.allow(auth.Editor), handler.Update())
 }
 
 func (r *router) buildLocalityRoutes() {
 	// Example
-	repo := locality.NewRepository(r.db)
-	service := locality.NewService(repo)
-	handler := handler.NewLocality(service)
-	r.r.POST("/localities", handler.Create())
-	r.r.GET("/localities/reportSellers", handler.Get())
-	
+	service := locality.NewServiceWithPolicy(r.localityRepository(), locality.DeletePolicy{
+		Mode:       r.cfg.Localities.DeletePolicy,
+		ReassignTo: r.cfg.Localities.ReassignTo,
+	}, r.log)
+	handler := handler.NewLocality(service, r.log)
+	read, write, _ := r.limiters()
+	localities := locality.NewRepository(r.db, r.log)
+	r.metrics.Register(metrics.NewGaugeFunc("localities", "Localities.", func(ctx context.Context) (float64, error) {
+		p, err := localities.List(ctx, locality.Query{Limit: 1})
+		return float64(p.Total), err
+	}))
+	r.r.GET("/localities", read, r.allow(auth.Reader), handler.GetAll())
+	r.r.GET("/localities/export", read, r.allow(auth.Reader), handler.Export())
+	r.r.GET("/localities/:id", read, r.allow(auth.Reader), handler.GetByID())
+	r.r.POST("/localities", write, r.allow(auth.Editor), handler.Create())
+	r.r.PATCH("/localities/:id", write, r.allow(auth.Editor), handler.Update())
+	r.r.DELETE("/localities/:id", write, r.allow(auth.Admin), handler.Delete())
+	r.r.GET("/localities/reportSellers", read, r.allow(auth.Reader), web.Cacheable(r.cfg.Cache.Report), handler.Get())
+
 }
 
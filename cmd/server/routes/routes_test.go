@@ -0,0 +1,209 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"Sellers/internal/config"
+	"Sellers/internal/logging"
+	"Sellers/pkg/web"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+const (
+	adminToken  = "0123456789abcdef"
+	editorToken = "editor-token-0001"
+	readerToken = "reader-token-0001"
+)
+
+// testConfig is the default configuration with a static token per role.
+func testConfig() config.Config {
+	cfg := config.Default()
+	cfg.Auth.Tokens = []config.Token{
+		{Name: "ana", Token: adminToken, Role: "admin"},
+		{Name: "eli", Token: editorToken, Role: "editor"},
+		{Name: "rui", Token: readerToken, Role: "reader"},
+	}
+	return cfg
+}
+
+// createServer mounts the routes of cfg on a database mock.
+func createServer(t *testing.T, cfg config.Config) (*gin.Engine, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	r := gin.New()
+	NewRouter(r, db, cfg, logging.Discard).MapRoutes()
+	return r, mock
+}
+
+func request(r *gin.Engine, method, url, token string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, strings.NewReader(""))
+	if token != "" {
+		req.Header.Set(web.TokenHeader, token)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	return res
+}
+
+func TestBadTokensAreRateLimitedByIP(t *testing.T) {
+	cfg := testConfig()
+	cfg.RateLimit.Client = config.Rate{Requests: 2, Per: config.Duration{Duration: time.Minute}}
+	r, mock := createServer(t, cfg)
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery("SELECT name, role FROM api_tokens").WillReturnRows(sqlmock.NewRows([]string{"name", "role"}))
+	}
+
+	for i := 0; i < 2; i++ {
+		res := request(r, http.MethodGet, "/sellers", "not-a-valid-token")
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+		assert.Equal(t, "2", res.Header().Get(web.RateLimitLimitHeader))
+	}
+	res := request(r, http.MethodGet, "/sellers", "not-a-valid-token")
+
+	assert.Equal(t, http.StatusTooManyRequests, res.Code)
+	assert.NotEmpty(t, res.Header().Get("Retry-After"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestForwardedForOnlyFromTrustedProxies(t *testing.T) {
+	cfg := testConfig()
+	cfg.RateLimit.Client = config.Rate{Requests: 1, Per: config.Duration{Duration: time.Minute}}
+	r, _ := createServer(t, cfg)
+
+	assert.Equal(t, http.StatusUnauthorized, request(r, http.MethodGet, "/sellers", "", "X-Forwarded-For", "203.0.113.1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, request(r, http.MethodGet, "/sellers", "", "X-Forwarded-For", "203.0.113.2").Code)
+
+	// httptest requests come from 192.0.2.1.
+	cfg.Server.TrustedProxies = []string{"192.0.2.1"}
+	r, _ = createServer(t, cfg)
+
+	assert.Equal(t, http.StatusUnauthorized, request(r, http.MethodGet, "/sellers", "", "X-Forwarded-For", "203.0.113.1").Code)
+	assert.Equal(t, http.StatusUnauthorized, request(r, http.MethodGet, "/sellers", "", "X-Forwarded-For", "203.0.113.2").Code)
+	assert.Equal(t, http.StatusTooManyRequests, request(r, http.MethodGet, "/sellers", "", "X-Forwarded-For", "203.0.113.2").Code)
+}
+
+func TestCreateSellerHasItsOwnBucket(t *testing.T) {
+	cfg := testConfig()
+	cfg.RateLimit.Create = config.Rate{Requests: 1, Per: config.Duration{Duration: time.Minute}}
+	r, _ := createServer(t, cfg)
+
+	res := request(r, http.MethodPost, "/sellers", editorToken)
+	assert.NotEqual(t, http.StatusTooManyRequests, res.Code)
+	assert.Equal(t, "1", res.Header().Get(web.RateLimitLimitHeader))
+	res = request(r, http.MethodPost, "/sellers", editorToken)
+	assert.Equal(t, http.StatusTooManyRequests, res.Code)
+	assert.Equal(t, "1", res.Header().Get(web.RateLimitLimitHeader))
+
+	res = request(r, http.MethodPatch, "/sellers/abc", editorToken)
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	assert.Equal(t, "60", res.Header().Get(web.RateLimitLimitHeader))
+}
+
+// permissions is the role each route must require.
+var permissions = []struct {
+	method, url string
+	role        string
+}{
+	{http.MethodGet, "/metrics", "reader"},
+	{http.MethodGet, "/sellers", "reader"},
+	{http.MethodGet, "/sellers/export", "reader"},
+	{http.MethodGet, "/sellers/1", "reader"},
+	{http.MethodGet, "/sellers/1/history", "reader"},
+	{http.MethodPost, "/sellers", "editor"},
+	{http.MethodPatch, "/sellers/1", "editor"},
+	{http.MethodPost, "/sellers/batch", "admin"},
+	{http.MethodPost, "/sellers/import", "admin"},
+	{http.MethodDelete, "/sellers/1", "admin"},
+	{http.MethodPost, "/sellers/1/restore", "admin"},
+	{http.MethodGet, "/localities", "reader"},
+	{http.MethodGet, "/localities/export", "reader"},
+	{http.MethodGet, "/localities/1", "reader"},
+	{http.MethodGet, "/localities/reportSellers", "reader"},
+	{http.MethodPost, "/localities", "editor"},
+	{http.MethodPatch, "/localities/1", "editor"},
+	{http.MethodDelete, "/localities/1", "admin"},
+}
+
+func TestRoutesRequireRole(t *testing.T) {
+	cfg := testConfig()
+	cfg.RateLimit.Enabled = false
+	r, _ := createServer(t, cfg)
+	tokens := []struct{ role, token string }{
+		{"reader", readerToken},
+		{"editor", editorToken},
+		{"admin", adminToken},
+	}
+
+	for _, p := range permissions {
+		allowed := false
+		for _, tk := range tokens {
+			allowed = allowed || tk.role == p.role
+			res := request(r, p.method, p.url, tk.token)
+			if allowed {
+				assert.NotContains(t, []int{http.StatusUnauthorized, http.StatusForbidden}, res.Code, "%s %s as %s", p.method, p.url, tk.role)
+			} else {
+				assert.Equal(t, http.StatusForbidden, res.Code, "%s %s as %s", p.method, p.url, tk.role)
+			}
+		}
+		assert.Equal(t, http.StatusUnauthorized, request(r, p.method, p.url, "").Code, "%s %s without token", p.method, p.url)
+	}
+}
+
+func TestRoutesWithoutAuthentication(t *testing.T) {
+	cfg := testConfig()
+	cfg.Auth.Enabled = false
+	cfg.RateLimit.Enabled = false
+	r, _ := createServer(t, cfg)
+
+	for _, p := range permissions {
+		res := request(r, p.method, p.url, "")
+		assert.NotContains(t, []int{http.StatusUnauthorized, http.StatusForbidden}, res.Code, "%s %s", p.method, p.url)
+	}
+}
+
+func TestHealthWithoutToken(t *testing.T) {
+	r, _ := createServer(t, testConfig())
+
+	assert.Equal(t, http.StatusOK, request(r, http.MethodGet, "/healthz", "").Code)
+	assert.NotEqual(t, http.StatusUnauthorized, request(r, http.MethodGet, "/readyz", "").Code)
+}
+
+func TestMetricsExposeRepositoryCache(t *testing.T) {
+	cfg := testConfig()
+	cfg.RepoCache.Enabled = true
+	r, _ := createServer(t, cfg)
+
+	res := request(r, http.MethodGet, "/metrics", readerToken)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), "repository_cache_hits_total 0\n")
+	assert.Contains(t, res.Body.String(), "repository_cache_misses_total 0\n")
+}
+
+func TestMetricsGaugesAreNotRepositoryOperations(t *testing.T) {
+	r, _ := createServer(t, testConfig())
+
+	// The database mock expects nothing, so the gauges fail; an instrumented
+	// repository would still have recorded the list.
+	request(r, http.MethodGet, "/metrics", readerToken)
+	res := request(r, http.MethodGet, "/metrics", readerToken)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.NotContains(t, res.Body.String(), `operation="list"`)
+}
@@ -0,0 +1,110 @@
+// Package auth authenticates API tokens, either configured statically or
+// stored hashed in the database.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"Sellers/internal/domain"
+)
+
+// ErrInvalidToken is returned when a token is unknown, revoked or empty.
+var ErrInvalidToken = domain.NewError(domain.ErrUnauthenticated, "invalid_token", "invalid API token")
+
+// ErrForbidden is returned when an identity's role does not allow an operation.
+var ErrForbidden = domain.NewError(domain.ErrForbidden, "forbidden", "role not allowed")
+
+// Role is what an identity may do. Each role allows everything the previous
+// ones do: readers use GET endpoints, editors also create and update, and
+// admins also delete and run bulk operations.
+type Role string
+
+const (
+	Reader Role = "reader"
+	Editor Role = "editor"
+	Admin  Role = "admin"
+)
+
+var roleRanks = map[Role]int{Reader: 1, Editor: 2, Admin: 3}
+
+// ParseRole validates s as a Role.
+func ParseRole(s string) (Role, error) {
+	r := Role(s)
+	if _, ok := roleRanks[r]; !ok {
+		return "", fmt.Errorf("role %q must be reader, editor or admin", s)
+	}
+	return r, nil
+}
+
+// Allows reports whether r includes required. Unknown roles allow nothing.
+func (r Role) Allows(required Role) bool {
+	rank, ok := roleRanks[r]
+	return ok && rank >= roleRanks[required]
+}
+
+// Identity is who a token authenticates as.
+type Identity struct {
+	Name string `json:"name"`
+	Role Role   `json:"role"`
+}
+
+// Authenticator resolves a token into the identity it belongs to.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (Identity, error)
+}
+
+// HashToken returns the hex SHA-256 of token, the form tokens are stored and
+// compared in so they never need to be kept in the clear.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Static authenticates a fixed set of tokens, such as those in the config.
+type Static struct {
+	byHash map[string]Identity
+}
+
+// NewStatic returns a Static accepting every token of tokens, which maps each
+// token to its identity.
+func NewStatic(tokens map[string]Identity) *Static {
+	s := &Static{byHash: make(map[string]Identity, len(tokens))}
+	for token, id := range tokens {
+		s.byHash[HashToken(token)] = id
+	}
+	return s
+}
+
+// Authenticate looks the token up by its hash, so the lookup time does not
+// depend on how much of a token matches a valid one.
+func (s *Static) Authenticate(ctx context.Context, token string) (Identity, error) {
+	if token == "" {
+		return Identity{}, ErrInvalidToken
+	}
+	id, ok := s.byHash[HashToken(token)]
+	if !ok {
+		return Identity{}, ErrInvalidToken
+	}
+	return id, nil
+}
+
+// Chain tries each Authenticator in order until one accepts the token. Errors
+// other than ErrInvalidToken, such as the database being down, stop the chain.
+type Chain []Authenticator
+
+func (ch Chain) Authenticate(ctx context.Context, token string) (Identity, error) {
+	for _, a := range ch {
+		id, err := a.Authenticate(ctx, token)
+		if err == nil {
+			return id, nil
+		}
+		if !errors.Is(err, ErrInvalidToken) {
+			return Identity{}, err
+		}
+	}
+	return Identity{}, ErrInvalidToken
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"Sellers/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type authenticatorFunc func(ctx context.Context, token string) (Identity, error)
+
+func (f authenticatorFunc) Authenticate(ctx context.Context, token string) (Identity, error) {
+	return f(ctx, token)
+}
+
+func TestStatic(t *testing.T) {
+	s := NewStatic(map[string]Identity{"0123456789abcdef": {Name: "frontend", Role: Reader}})
+
+	id, err := s.Authenticate(context.Background(), "0123456789abcdef")
+	assert.NoError(t, err)
+	assert.Equal(t, Identity{Name: "frontend", Role: Reader}, id)
+
+	_, err = s.Authenticate(context.Background(), "0123456789abcdeX")
+	assert.Equal(t, ErrInvalidToken, err)
+	_, err = s.Authenticate(context.Background(), "")
+	assert.Equal(t, ErrInvalidToken, err)
+}
+
+func TestChainFallsThrough(t *testing.T) {
+	db := authenticatorFunc(func(ctx context.Context, token string) (Identity, error) {
+		if token == "stored" {
+			return Identity{Name: "batch"}, nil
+		}
+		return Identity{}, ErrInvalidToken
+	})
+	ch := Chain{NewStatic(map[string]Identity{"static": {Name: "frontend"}}), db}
+
+	id, err := ch.Authenticate(context.Background(), "stored")
+	assert.NoError(t, err)
+	assert.Equal(t, "batch", id.Name)
+
+	_, err = ch.Authenticate(context.Background(), "unknown")
+	assert.Equal(t, ErrInvalidToken, err)
+}
+
+func TestChainStopsOnFailure(t *testing.T) {
+	down := domain.WithKind(domain.ErrUnavailable, errors.New("connection refused"))
+	ch := Chain{
+		authenticatorFunc(func(ctx context.Context, token string) (Identity, error) { return Identity{}, down }),
+		NewStatic(map[string]Identity{"static": {Name: "frontend"}}),
+	}
+
+	_, err := ch.Authenticate(context.Background(), "static")
+
+	assert.ErrorIs(t, err, domain.ErrUnavailable)
+}
+
+func TestRoleAllows(t *testing.T) {
+	assert.True(t, Admin.Allows(Editor))
+	assert.True(t, Editor.Allows(Editor))
+	assert.True(t, Editor.Allows(Reader))
+	assert.False(t, Editor.Allows(Admin))
+	assert.False(t, Reader.Allows(Editor))
+	assert.False(t, Role("").Allows(Reader))
+}
+
+func TestParseRole(t *testing.T) {
+	r, err := ParseRole("editor")
+	assert.NoError(t, err)
+	assert.Equal(t, Editor, r)
+
+	_, err = ParseRole("root")
+	assert.Error(t, err)
+}
+
+func TestHashToken(t *testing.T) {
+	assert.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", HashToken(""))
+	assert.Len(t, HashToken("0123456789abcdef"), 64)
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+
+	"Sellers/internal/domain"
+	"Sellers/internal/logging"
+	"Sellers/internal/storage"
+)
+
+// ErrTokenNotFound is returned when revoking a name without active tokens.
+var ErrTokenNotFound = domain.NewError(domain.ErrNotFound, "token_not_found", "no active token with that name")
+
+// Repository stores API tokens hashed in the api_tokens table.
+type Repository interface {
+	Authenticator
+	// Create issues a new token for name with role and returns it; only its
+	// hash is stored, so it cannot be read back later.
+	Create(ctx context.Context, name string, role Role) (string, error)
+	// Revoke disables every active token of name.
+	Revoke(ctx context.Context, name string) error
+}
+
+type repository struct {
+	db storage.Querier
+}
+
+// NewRepository returns a Repository on db logging its statements to log.
+func NewRepository(db *sql.DB, log *logging.Logger) Repository {
+	return &repository{
+		db: storage.Logged(db, log),
+	}
+}
+
+func (r *repository) Authenticate(ctx context.Context, token string) (Identity, error) {
+	if token == "" {
+		return Identity{}, ErrInvalidToken
+	}
+
+	query := "SELECT name, role FROM api_tokens WHERE token_hash=? AND revoked_at IS NULL"
+	var id Identity
+	err := r.db.QueryRowContext(ctx, query, HashToken(token)).Scan(&id.Name, &id.Role)
+	if err != nil {
+		return Identity{}, storage.Translate(err, ErrInvalidToken)
+	}
+	return id, nil
+}
+
+func (r *repository) Create(ctx context.Context, name string, role Role) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	query := "INSERT INTO api_tokens (name, role, token_hash, created_at) VALUES (?, ?, ?, UTC_TIMESTAMP())"
+	if _, err := r.db.ExecContext(ctx, query, name, role, HashToken(token)); err != nil {
+		return "", storage.Translate(err, ErrTokenNotFound)
+	}
+	return token, nil
+}
+
+func (r *repository) Revoke(ctx context.Context, name string) error {
+	query := "UPDATE api_tokens SET revoked_at=UTC_TIMESTAMP() WHERE name=? AND revoked_at IS NULL"
+	res, err := r.db.ExecContext(ctx, query, name)
+	if err != nil {
+		return storage.Translate(err, ErrTokenNotFound)
+	}
+
+	affect, err := res.RowsAffected()
+	if err != nil {
+		return storage.Translate(err, ErrTokenNotFound)
+	}
+	if affect < 1 {
+		return ErrTokenNotFound
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"Sellers/internal/logging"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticateStoredToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT name, role FROM api_tokens WHERE token_hash=\\? AND revoked_at IS NULL").
+		WithArgs(HashToken("secret-token")).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "role"}).AddRow("batch", "editor"))
+
+	id, err := NewRepository(db, logging.Discard).Authenticate(context.Background(), "secret-token")
+
+	assert.NoError(t, err)
+	assert.Equal(t, Identity{Name: "batch", Role: Editor}, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthenticateUnknownToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT name, role FROM api_tokens").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "role"}))
+
+	_, err = NewRepository(db, logging.Discard).Authenticate(context.Background(), "nope")
+
+	assert.Equal(t, ErrInvalidToken, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateStoresOnlyTheHash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec("INSERT INTO api_tokens \\(name, role, token_hash, created_at\\)").
+		WithArgs("batch", "admin", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	token, err := NewRepository(db, logging.Discard).Create(context.Background(), "batch", Admin)
+
+	assert.NoError(t, err)
+	assert.Len(t, token, 43)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevokeWithoutTokens(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE api_tokens SET revoked_at=UTC_TIMESTAMP\\(\\) WHERE name=\\? AND revoked_at IS NULL").
+		WithArgs("batch").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = NewRepository(db, logging.Discard).Revoke(context.Background(), "batch")
+
+	assert.Equal(t, ErrTokenNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
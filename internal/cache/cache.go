@@ -0,0 +1,75 @@
+// Package cache holds the lookup cache the seller and locality repository
+// decorators share.
+package cache
+
+import "sync/atomic"
+
+// Backend stores cached values by key. Implementations must be safe for
+// concurrent use; values must be treated as read-only by callers.
+type Backend interface {
+	Get(key string) (interface{}, bool)
+	Set(key string, value interface{})
+	Delete(keys ...string)
+	Clear()
+}
+
+// Stats is a snapshot of the lookups made through a Cache.
+type Stats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+// HitRatio returns the fraction of lookups that were hits, 0 when there were
+// none.
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// Cache is a Backend that counts its hits and misses.
+type Cache struct {
+	backend Backend
+	hits    uint64
+	misses  uint64
+}
+
+// New returns a Cache storing its values in b.
+func New(b Backend) *Cache {
+	return &Cache{backend: b}
+}
+
+// Get looks key up, counting a hit or a miss.
+func (c *Cache) Get(key string) (interface{}, bool) {
+	v, ok := c.backend.Get(key)
+	if ok {
+		atomic.AddUint64(&c.hits, 1)
+	} else {
+		atomic.AddUint64(&c.misses, 1)
+	}
+	return v, ok
+}
+
+func (c *Cache) Set(key string, value interface{}) {
+	c.backend.Set(key, value)
+}
+
+func (c *Cache) Delete(keys ...string) {
+	if len(keys) > 0 {
+		c.backend.Delete(keys...)
+	}
+}
+
+func (c *Cache) Clear() {
+	c.backend.Clear()
+}
+
+// Stats returns the lookups counted so far.
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Hits:   atomic.LoadUint64(&c.hits),
+		Misses: atomic.LoadUint64(&c.misses),
+	}
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU(2, 0)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Set("c", 3)
+
+	_, ok := c.Get("b")
+	assert.False(t, ok)
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestLRUExpiresEntries(t *testing.T) {
+	now := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := NewLRU(10, time.Minute)
+	c.now = func() time.Time { return now }
+	c.Set("a", 1)
+
+	now = now.Add(59 * time.Second)
+	_, ok := c.Get("a")
+	assert.True(t, ok)
+
+	now = now.Add(time.Second)
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestLRUSetRefreshesEntry(t *testing.T) {
+	c := NewLRU(2, 0)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("a", 10)
+	c.Set("c", 3)
+
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 10, v)
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+}
+
+func TestLRUDeleteAndClear(t *testing.T) {
+	c := NewLRU(10, 0)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+
+	c.Delete("a", "missing")
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 2, c.Len())
+
+	c.Clear()
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestCacheCountsHitsAndMisses(t *testing.T) {
+	c := New(NewLRU(10, 0))
+	c.Set("a", 1)
+
+	c.Get("a")
+	c.Get("a")
+	c.Get("b")
+
+	st := c.Stats()
+	assert.Equal(t, Stats{Hits: 2, Misses: 1}, st)
+	assert.InDelta(t, 2.0/3, st.HitRatio(), 1e-9)
+	assert.Equal(t, 0.0, Stats{}.HitRatio())
+}
@@ -0,0 +1,609 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gopkg.in/yaml.v2"
+)
+
+// EnvPrefix is prepended to every environment variable read by Load.
+const EnvPrefix = "SELLERS_"
+
+// Config holds every setting the server needs at startup.
+type Config struct {
+	Server     Server     `json:"server" yaml:"server"`
+	Database   Database   `json:"database" yaml:"database"`
+	Sellers    Sellers    `json:"sellers" yaml:"sellers"`
+	Localities Localities `json:"localities" yaml:"localities"`
+	Cache      Cache      `json:"cache" yaml:"cache"`
+	RepoCache  RepoCache  `json:"repository_cache" yaml:"repository_cache"`
+	Auth       Auth       `json:"auth" yaml:"auth"`
+	RateLimit  RateLimit  `json:"rate_limit" yaml:"rate_limit"`
+	LogLevel   string     `json:"log_level" yaml:"log_level"`
+}
+
+// Server groups the HTTP listener settings.
+type Server struct {
+	Address     string   `json:"address" yaml:"address"`
+	ReadTimeout Duration `json:"read_timeout" yaml:"read_timeout"`
+	// WriteTimeout also bounds the streaming exports, which are cut off
+	// when they take longer.
+	WriteTimeout Duration `json:"write_timeout" yaml:"write_timeout"`
+	IdleTimeout  Duration `json:"idle_timeout" yaml:"idle_timeout"`
+	// HealthTimeout bounds each dependency check of GET /readyz.
+	HealthTimeout Duration `json:"health_timeout" yaml:"health_timeout"`
+	// DrainPeriod is how long GET /readyz fails before shutting down, so
+	// load balancers stop sending requests first.
+	DrainPeriod Duration `json:"drain_period" yaml:"drain_period"`
+	// ShutdownTimeout bounds the wait for requests in flight and the
+	// shutdown of everything else, after the drain period.
+	ShutdownTimeout Duration `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+	// TrustedProxies are the IPs or CIDRs whose X-Forwarded-For and
+	// X-Real-IP headers are believed. Without them the client IP is the
+	// address of the connection, which cannot be spoofed.
+	TrustedProxies []string `json:"trusted_proxies" yaml:"trusted_proxies"`
+}
+
+// Database groups the MySQL connection and pool settings.
+type Database struct {
+	User            string   `json:"user" yaml:"user"`
+	Password        string   `json:"password" yaml:"password"`
+	Host            string   `json:"host" yaml:"host"`
+	Port            int      `json:"port" yaml:"port"`
+	Name            string   `json:"name" yaml:"name"`
+	MaxOpenConns    int      `json:"max_open_conns" yaml:"max_open_conns"`
+	MaxIdleConns    int      `json:"max_idle_conns" yaml:"max_idle_conns"`
+	ConnMaxLifetime Duration `json:"conn_max_lifetime" yaml:"conn_max_lifetime"`
+	DialTimeout     Duration `json:"dial_timeout" yaml:"dial_timeout"`
+	QueryTimeout    Duration `json:"query_timeout" yaml:"query_timeout"`
+}
+
+// Sellers groups the seller business rules.
+type Sellers struct {
+	// Retention is how long soft deleted sellers are kept before the purge
+	// command removes them.
+	Retention Duration `json:"retention" yaml:"retention"`
+}
+
+// Localities groups the locality business rules.
+type Localities struct {
+	// DeletePolicy is "block" (409 while sellers reference the locality) or
+	// "reassign" (move them to ReassignTo or the locality given in the request).
+	DeletePolicy string `json:"delete_policy" yaml:"delete_policy"`
+	ReassignTo   int    `json:"reassign_to" yaml:"reassign_to"`
+}
+
+// Cache groups the Cache-Control headers of the conditional read routes. An
+// empty value sends no Cache-Control header.
+type Cache struct {
+	Seller  string `json:"seller" yaml:"seller"`   // GET /sellers/:id
+	Sellers string `json:"sellers" yaml:"sellers"` // GET /sellers
+	Report  string `json:"report" yaml:"report"`   // GET /localities/reportSellers
+}
+
+// RepoCache configures the in-memory cache of seller and locality lookups. It
+// is per process, so with several instances an entry may be stale for up to
+// TTL after another instance changes it; likewise, CIDs freed by the purge
+// command stay taken for up to TTL. Hits and misses are exposed on /metrics.
+type RepoCache struct {
+	Enabled bool     `json:"enabled" yaml:"enabled"`
+	Size    int      `json:"size" yaml:"size"`
+	TTL     Duration `json:"ttl" yaml:"ttl"`
+}
+
+// Auth configures API token authentication. Besides Tokens, the hashed tokens
+// of the api_tokens table are accepted.
+type Auth struct {
+	Enabled bool    `json:"enabled" yaml:"enabled"`
+	Tokens  []Token `json:"tokens" yaml:"tokens"`
+}
+
+// Token is a static API token and the name and role of the identity it
+// authenticates.
+type Token struct {
+	Name  string `json:"name" yaml:"name"`
+	Token string `json:"token" yaml:"token"`
+	Role  string `json:"role" yaml:"role"`
+}
+
+// RateLimit configures the requests each client may make, identified by its
+// API token or, without authentication, its IP. Every route group has its own
+// bucket per client. Buckets are per process.
+type RateLimit struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// Client limits every request by IP before authentication, so requests
+	// with bad tokens are limited too.
+	Client Rate `json:"client" yaml:"client"`
+	Read   Rate `json:"read" yaml:"read"`     // GET routes
+	Write  Rate `json:"write" yaml:"write"`   // PATCH, DELETE and the other POST routes
+	Create Rate `json:"create" yaml:"create"` // POST /sellers
+	Bulk   Rate `json:"bulk" yaml:"bulk"`     // POST /sellers/batch and /sellers/import
+}
+
+// Rate allows Requests requests every Per, all of them at once after being
+// idle for Per.
+type Rate struct {
+	Requests int      `json:"requests" yaml:"requests"`
+	Per      Duration `json:"per" yaml:"per"`
+}
+
+// MinTokenLength is the shortest static token Validate accepts.
+const MinTokenLength = 16
+
+// DSN builds the go-sql-driver/mysql data source name.
+func (d Database) DSN() string {
+	c := mysql.NewConfig()
+	c.User = d.User
+	c.Passwd = d.Password
+	c.Net = "tcp"
+	c.Addr = net.JoinHostPort(d.Host, strconv.Itoa(d.Port))
+	c.DBName = d.Name
+	c.ParseTime = true
+	c.Timeout = d.DialTimeout.Duration
+	c.ReadTimeout = d.QueryTimeout.Duration
+	c.WriteTimeout = d.QueryTimeout.Duration
+	return c.FormatDSN()
+}
+
+// Duration wraps time.Duration so it can be written as "5s" in config files.
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"5s\": %w", err)
+	}
+	return d.set(s)
+}
+
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return d.set(s)
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// Default returns the configuration used when nothing else is provided.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Address:         ":3001",
+			ReadTimeout:     Duration{10 * time.Second},
+			WriteTimeout:    Duration{10 * time.Second},
+			IdleTimeout:     Duration{60 * time.Second},
+			HealthTimeout:   Duration{2 * time.Second},
+			DrainPeriod:     Duration{5 * time.Second},
+			ShutdownTimeout: Duration{15 * time.Second},
+		},
+		Database: Database{
+			User:            "root",
+			Host:            "127.0.0.1",
+			Port:            3306,
+			Name:            "meli",
+			MaxOpenConns:    10,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: Duration{5 * time.Minute},
+			DialTimeout:     Duration{5 * time.Second},
+			QueryTimeout:    Duration{30 * time.Second},
+		},
+		Sellers: Sellers{
+			Retention: Duration{30 * 24 * time.Hour},
+		},
+		Localities: Localities{
+			DeletePolicy: "block",
+		},
+		Cache: Cache{
+			Seller:  "private, no-cache",
+			Sellers: "private, no-cache",
+			Report:  "private, no-cache",
+		},
+		RepoCache: RepoCache{
+			Size: 10000,
+			TTL:  Duration{time.Minute},
+		},
+		Auth: Auth{
+			Enabled: true,
+		},
+		RateLimit: RateLimit{
+			Enabled: true,
+			Client:  Rate{Requests: 600, Per: Duration{time.Minute}},
+			Read:    Rate{Requests: 300, Per: Duration{time.Minute}},
+			Write:   Rate{Requests: 60, Per: Duration{time.Minute}},
+			Create:  Rate{Requests: 20, Per: Duration{time.Minute}},
+			Bulk:    Rate{Requests: 5, Per: Duration{time.Minute}},
+		},
+		LogLevel: "info",
+	}
+}
+
+// setting binds one configuration value to its flag and environment variable.
+type setting struct {
+	name  string
+	usage string
+	set   func(c *Config, v string) error
+}
+
+func (s setting) env() string {
+	return EnvPrefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(s.name))
+}
+
+func str(p func(c *Config) *string) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		*p(c) = v
+		return nil
+	}
+}
+
+func integer(p func(c *Config) *int) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", v)
+		}
+		*p(c) = n
+		return nil
+	}
+}
+
+func boolean(p func(c *Config) *bool) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", v)
+		}
+		*p(c) = b
+		return nil
+	}
+}
+
+// list parses comma separated values.
+func list(p func(c *Config) *[]string) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		var vs []string
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				vs = append(vs, s)
+			}
+		}
+		*p(c) = vs
+		return nil
+	}
+}
+
+// tokens parses comma separated name:token:role triples.
+func tokens(p func(c *Config) *[]Token) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		var ts []Token
+		for _, triple := range strings.Split(v, ",") {
+			if triple = strings.TrimSpace(triple); triple == "" {
+				continue
+			}
+			i, j := strings.Index(triple, ":"), strings.LastIndex(triple, ":")
+			if i == j {
+				return fmt.Errorf("%q is not a name:token:role triple", triple)
+			}
+			ts = append(ts, Token{Name: triple[:i], Token: triple[i+1 : j], Role: triple[j+1:]})
+		}
+		*p(c) = ts
+		return nil
+	}
+}
+
+func duration(p func(c *Config) *Duration) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		if err := p(c).set(v); err != nil {
+			return fmt.Errorf("%q is not a duration", v)
+		}
+		return nil
+	}
+}
+
+var settings = []setting{
+	{"server.address", "HTTP listen address", str(func(c *Config) *string { return &c.Server.Address })},
+	{"server.read-timeout", "HTTP read timeout", duration(func(c *Config) *Duration { return &c.Server.ReadTimeout })},
+	{"server.write-timeout", "HTTP write timeout, which also bounds exports", duration(func(c *Config) *Duration { return &c.Server.WriteTimeout })},
+	{"server.idle-timeout", "HTTP keep-alive idle timeout", duration(func(c *Config) *Duration { return &c.Server.IdleTimeout })},
+	{"server.health-timeout", "timeout of each readiness check", duration(func(c *Config) *Duration { return &c.Server.HealthTimeout })},
+	{"server.drain-period", "how long to fail readiness before shutting down", duration(func(c *Config) *Duration { return &c.Server.DrainPeriod })},
+	{"server.shutdown-timeout", "how long to wait for requests in flight when shutting down", duration(func(c *Config) *Duration { return &c.Server.ShutdownTimeout })},
+	{"server.trusted-proxies", "comma separated IPs or CIDRs of the proxies whose X-Forwarded-For is believed", list(func(c *Config) *[]string { return &c.Server.TrustedProxies })},
+	{"db.user", "MySQL user", str(func(c *Config) *string { return &c.Database.User })},
+	{"db.password", "MySQL password", str(func(c *Config) *string { return &c.Database.Password })},
+	{"db.host", "MySQL host", str(func(c *Config) *string { return &c.Database.Host })},
+	{"db.port", "MySQL port", integer(func(c *Config) *int { return &c.Database.Port })},
+	{"db.name", "MySQL database name", str(func(c *Config) *string { return &c.Database.Name })},
+	{"db.max-open-conns", "maximum open connections in the pool", integer(func(c *Config) *int { return &c.Database.MaxOpenConns })},
+	{"db.max-idle-conns", "maximum idle connections in the pool", integer(func(c *Config) *int { return &c.Database.MaxIdleConns })},
+	{"db.conn-max-lifetime", "maximum lifetime of a pooled connection", duration(func(c *Config) *Duration { return &c.Database.ConnMaxLifetime })},
+	{"db.dial-timeout", "MySQL dial timeout", duration(func(c *Config) *Duration { return &c.Database.DialTimeout })},
+	{"db.query-timeout", "MySQL read/write timeout", duration(func(c *Config) *Duration { return &c.Database.QueryTimeout })},
+	{"sellers.retention", "how long soft deleted sellers are kept before purge", duration(func(c *Config) *Duration { return &c.Sellers.Retention })},
+	{"localities.delete-policy", "deleting a locality with sellers: block or reassign", str(func(c *Config) *string { return &c.Localities.DeletePolicy })},
+	{"localities.reassign-to", "default locality id sellers are moved to under the reassign policy", integer(func(c *Config) *int { return &c.Localities.ReassignTo })},
+	{"cache.seller", "Cache-Control of GET /sellers/:id", str(func(c *Config) *string { return &c.Cache.Seller })},
+	{"cache.sellers", "Cache-Control of GET /sellers", str(func(c *Config) *string { return &c.Cache.Sellers })},
+	{"cache.report", "Cache-Control of GET /localities/reportSellers", str(func(c *Config) *string { return &c.Cache.Report })},
+	{"repository-cache.enabled", "cache seller and locality lookups in memory", boolean(func(c *Config) *bool { return &c.RepoCache.Enabled })},
+	{"repository-cache.size", "maximum entries of the repository cache", integer(func(c *Config) *int { return &c.RepoCache.Size })},
+	{"repository-cache.ttl", "how long a repository cache entry lives", duration(func(c *Config) *Duration { return &c.RepoCache.TTL })},
+	{"auth.enabled", "require an API token on every route", boolean(func(c *Config) *bool { return &c.Auth.Enabled })},
+	{"auth.tokens", "static API tokens as comma separated name:token:role triples", tokens(func(c *Config) *[]Token { return &c.Auth.Tokens })},
+	{"rate-limit.enabled", "limit the requests each client may make", boolean(func(c *Config) *bool { return &c.RateLimit.Enabled })},
+	{"rate-limit.client.requests", "requests of any route an IP may make every rate-limit.client.per", integer(func(c *Config) *int { return &c.RateLimit.Client.Requests })},
+	{"rate-limit.client.per", "period of rate-limit.client.requests", duration(func(c *Config) *Duration { return &c.RateLimit.Client.Per })},
+	{"rate-limit.read.requests", "GET requests a client may make every rate-limit.read.per", integer(func(c *Config) *int { return &c.RateLimit.Read.Requests })},
+	{"rate-limit.read.per", "period of rate-limit.read.requests", duration(func(c *Config) *Duration { return &c.RateLimit.Read.Per })},
+	{"rate-limit.write.requests", "PATCH, DELETE and POST requests other than creating sellers a client may make every rate-limit.write.per", integer(func(c *Config) *int { return &c.RateLimit.Write.Requests })},
+	{"rate-limit.write.per", "period of rate-limit.write.requests", duration(func(c *Config) *Duration { return &c.RateLimit.Write.Per })},
+	{"rate-limit.create.requests", "POST /sellers requests a client may make every rate-limit.create.per", integer(func(c *Config) *int { return &c.RateLimit.Create.Requests })},
+	{"rate-limit.create.per", "period of rate-limit.create.requests", duration(func(c *Config) *Duration { return &c.RateLimit.Create.Per })},
+	{"rate-limit.bulk.requests", "batch and import requests a client may make every rate-limit.bulk.per", integer(func(c *Config) *int { return &c.RateLimit.Bulk.Requests })},
+	{"rate-limit.bulk.per", "period of rate-limit.bulk.requests", duration(func(c *Config) *Duration { return &c.RateLimit.Bulk.Per })},
+	{"log-level", "log level: debug, info, warn or error", str(func(c *Config) *string { return &c.LogLevel })},
+}
+
+// Load builds the configuration from, in increasing order of precedence:
+// defaults, the optional config file (-config or SELLERS_CONFIG), environment
+// variables and command-line flags. The result is validated before returning.
+func Load(args []string) (Config, error) {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	path := fs.String("config", "", "path to a YAML or JSON config file (env "+EnvPrefix+"CONFIG)")
+	values := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		values[s.name] = fs.String(s.name, "", s.usage+" (env "+s.env()+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+	// Parsing stops at the first word that is not a flag, which would
+	// otherwise silently drop it and every flag after it.
+	if fs.NArg() > 0 {
+		return Config{}, fmt.Errorf("config: unexpected argument %q", fs.Arg(0))
+	}
+
+	cfg := Default()
+
+	if *path == "" {
+		*path = os.Getenv(EnvPrefix + "CONFIG")
+	}
+	if *path != "" {
+		if err := loadFile(&cfg, *path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	var errs []string
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(s.env()); ok {
+			if err := s.set(&cfg, v); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", s.env(), err))
+			}
+		}
+	}
+
+	byName := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		byName[s.name] = s
+	}
+	fs.Visit(func(f *flag.Flag) {
+		s, ok := byName[f.Name]
+		if !ok {
+			return
+		}
+		if err := s.set(&cfg, *values[f.Name]); err != nil {
+			errs = append(errs, fmt.Sprintf("-%s: %v", f.Name, err))
+		}
+	})
+	if len(errs) > 0 {
+		return Config{}, errors.New("config: " + strings.Join(errs, "; "))
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: reading %s: %w", path, err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = decodeJSON(b, cfg)
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(b, cfg)
+	default:
+		return fmt.Errorf("config: %s: unsupported file extension, use .json, .yaml or .yml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config: parsing %s: %w", path, err)
+	}
+	return nil
+}
+
+// decodeJSON decodes b into cfg, rejecting unknown keys as the YAML decoder
+// does, and anything after the object, as json.Unmarshal does.
+func decodeJSON(b []byte, cfg *Config) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("unexpected data after the top-level object")
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once.
+func (c Config) Validate() error {
+	var errs []string
+
+	if _, _, err := net.SplitHostPort(c.Server.Address); err != nil {
+		errs = append(errs, fmt.Sprintf("server.address %q is not a host:port", c.Server.Address))
+	}
+	if c.Server.ReadTimeout.Duration <= 0 {
+		errs = append(errs, "server.read_timeout must be positive")
+	}
+	if c.Server.WriteTimeout.Duration <= 0 {
+		errs = append(errs, "server.write_timeout must be positive")
+	}
+	if c.Server.IdleTimeout.Duration < 0 {
+		errs = append(errs, "server.idle_timeout cannot be negative")
+	}
+	if c.Server.HealthTimeout.Duration <= 0 {
+		errs = append(errs, "server.health_timeout must be positive")
+	}
+	if c.Server.DrainPeriod.Duration < 0 {
+		errs = append(errs, "server.drain_period cannot be negative")
+	}
+	if c.Server.ShutdownTimeout.Duration <= 0 {
+		errs = append(errs, "server.shutdown_timeout must be positive")
+	}
+	for i, p := range c.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(p); err != nil && net.ParseIP(p) == nil {
+			errs = append(errs, fmt.Sprintf("server.trusted_proxies[%d] %q is not an IP or CIDR", i, p))
+		}
+	}
+
+	if c.Database.User == "" {
+		errs = append(errs, "database.user is required")
+	}
+	if c.Database.Host == "" {
+		errs = append(errs, "database.host is required")
+	}
+	if c.Database.Port < 1 || c.Database.Port > 65535 {
+		errs = append(errs, fmt.Sprintf("database.port %d is out of range", c.Database.Port))
+	}
+	if c.Database.Name == "" {
+		errs = append(errs, "database.name is required")
+	}
+	if c.Database.MaxOpenConns < 0 {
+		errs = append(errs, "database.max_open_conns cannot be negative")
+	}
+	if c.Database.MaxIdleConns < 0 {
+		errs = append(errs, "database.max_idle_conns cannot be negative")
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		errs = append(errs, "database.max_idle_conns cannot exceed database.max_open_conns")
+	}
+	if c.Database.ConnMaxLifetime.Duration < 0 {
+		errs = append(errs, "database.conn_max_lifetime cannot be negative")
+	}
+	if c.Database.DialTimeout.Duration < 0 || c.Database.QueryTimeout.Duration < 0 {
+		errs = append(errs, "database timeouts cannot be negative")
+	}
+
+	if c.Sellers.Retention.Duration <= 0 {
+		errs = append(errs, "sellers.retention must be positive")
+	}
+
+	switch c.Localities.DeletePolicy {
+	case "block", "reassign":
+	default:
+		errs = append(errs, fmt.Sprintf("localities.delete_policy %q must be block or reassign", c.Localities.DeletePolicy))
+	}
+	if c.Localities.ReassignTo < 0 {
+		errs = append(errs, "localities.reassign_to cannot be negative")
+	}
+
+	for _, cc := range []struct{ name, value string }{
+		{"seller", c.Cache.Seller},
+		{"sellers", c.Cache.Sellers},
+		{"report", c.Cache.Report},
+	} {
+		if strings.ContainsAny(cc.value, "\r\n") {
+			errs = append(errs, fmt.Sprintf("cache.%s cannot contain line breaks", cc.name))
+		}
+	}
+
+	if c.RepoCache.Enabled {
+		if c.RepoCache.Size <= 0 {
+			errs = append(errs, "repository_cache.size must be positive")
+		}
+		if c.RepoCache.TTL.Duration <= 0 {
+			errs = append(errs, "repository_cache.ttl must be positive")
+		}
+	}
+
+	if c.RateLimit.Enabled {
+		for _, r := range []struct {
+			name string
+			rate Rate
+		}{
+			{"client", c.RateLimit.Client},
+			{"read", c.RateLimit.Read},
+			{"write", c.RateLimit.Write},
+			{"create", c.RateLimit.Create},
+			{"bulk", c.RateLimit.Bulk},
+		} {
+			if r.rate.Requests <= 0 {
+				errs = append(errs, fmt.Sprintf("rate_limit.%s.requests must be positive", r.name))
+			}
+			if r.rate.Per.Duration <= 0 {
+				errs = append(errs, fmt.Sprintf("rate_limit.%s.per must be positive", r.name))
+			}
+		}
+	}
+
+	seen := make(map[string]bool, len(c.Auth.Tokens))
+	for i, t := range c.Auth.Tokens {
+		if t.Name == "" {
+			errs = append(errs, fmt.Sprintf("auth.tokens[%d].name is required", i))
+		}
+		if len(t.Token) < MinTokenLength {
+			errs = append(errs, fmt.Sprintf("auth.tokens[%d].token must have at least %d characters", i, MinTokenLength))
+		}
+		if seen[t.Token] {
+			errs = append(errs, fmt.Sprintf("auth.tokens[%d].token is repeated", i))
+		}
+		switch t.Role {
+		case "reader", "editor", "admin":
+		default:
+			errs = append(errs, fmt.Sprintf("auth.tokens[%d].role must be reader, editor or admin", i))
+		}
+		seen[t.Token] = true
+	}
+
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Sprintf("log_level %q must be one of debug, info, warn, error", c.LogLevel))
+	}
+
+	if len(errs) > 0 {
+		return errors.New("config: invalid settings: " + strings.Join(errs, "; "))
+	}
+	return nil
+}
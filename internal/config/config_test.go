@@ -0,0 +1,259 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load(nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+server:
+  address: ":4000"
+  read_timeout: 3s
+database:
+  host: db.internal
+  port: 3307
+  name: fromfile
+log_level: warn
+`)
+	os.Setenv("SELLERS_DB_NAME", "fromenv")
+	os.Setenv("SELLERS_DB_PORT", "3308")
+	defer os.Unsetenv("SELLERS_DB_NAME")
+	defer os.Unsetenv("SELLERS_DB_PORT")
+
+	cfg, err := Load([]string{"-config", path, "-db.port", "3309"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, ":4000", cfg.Server.Address)
+	assert.Equal(t, 3*time.Second, cfg.Server.ReadTimeout.Duration)
+	assert.Equal(t, "db.internal", cfg.Database.Host)
+	assert.Equal(t, "fromenv", cfg.Database.Name)
+	assert.Equal(t, 3309, cfg.Database.Port)
+	assert.Equal(t, "warn", cfg.LogLevel)
+}
+
+func TestLoadJSONFileFromEnv(t *testing.T) {
+	path := writeFile(t, "config.json", `{"server":{"address":"127.0.0.1:8080"},"database":{"max_open_conns":20,"conn_max_lifetime":"1m"}}`)
+	os.Setenv("SELLERS_CONFIG", path)
+	defer os.Unsetenv("SELLERS_CONFIG")
+
+	cfg, err := Load(nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:8080", cfg.Server.Address)
+	assert.Equal(t, 20, cfg.Database.MaxOpenConns)
+	assert.Equal(t, time.Minute, cfg.Database.ConnMaxLifetime.Duration)
+}
+
+func TestLoadInvalidEnv(t *testing.T) {
+	os.Setenv("SELLERS_DB_PORT", "abc")
+	defer os.Unsetenv("SELLERS_DB_PORT")
+
+	_, err := Load(nil)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "SELLERS_DB_PORT")
+}
+
+func TestLoadRejectsPositionalArguments(t *testing.T) {
+	_, err := Load([]string{"-server.address", ":4000", "migrate", "up"})
+
+	assert.EqualError(t, err, `config: unexpected argument "migrate"`)
+}
+
+func TestLoadUnknownFileKey(t *testing.T) {
+	path := writeFile(t, "config.yml", "server:\n  adress: \":4000\"\n")
+
+	_, err := Load([]string{"-config", path})
+
+	assert.Error(t, err)
+}
+
+func TestLoadUnknownJSONFileKey(t *testing.T) {
+	path := writeFile(t, "config.json", `{"server":{"adress":":4000"}}`)
+
+	_, err := Load([]string{"-config", path})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown field "adress"`)
+}
+
+func TestLoadJSONFileWithTrailingData(t *testing.T) {
+	path := writeFile(t, "config.json", `{"server":{"address":":4000"}} {}`)
+
+	_, err := Load([]string{"-config", path})
+
+	assert.Error(t, err)
+}
+
+func TestValidateReportsAllErrors(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Address = "nope"
+	cfg.Database.Port = 0
+	cfg.LogLevel = "verbose"
+	cfg.Server.HealthTimeout = Duration{}
+	cfg.Server.DrainPeriod = Duration{-time.Second}
+
+	err := cfg.Validate()
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "server.address")
+	assert.Contains(t, err.Error(), "database.port")
+	assert.Contains(t, err.Error(), "log_level")
+	assert.Contains(t, err.Error(), "server.health_timeout")
+	assert.Contains(t, err.Error(), "server.drain_period")
+}
+
+func TestLoadCacheControlFromEnv(t *testing.T) {
+	os.Setenv("SELLERS_CACHE_SELLERS", "public, max-age=30")
+	defer os.Unsetenv("SELLERS_CACHE_SELLERS")
+
+	cfg, err := Load(nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "public, max-age=30", cfg.Cache.Sellers)
+	assert.Equal(t, "private, no-cache", cfg.Cache.Seller)
+}
+
+func TestValidateRejectsCacheControlLineBreaks(t *testing.T) {
+	cfg := Default()
+	cfg.Cache.Report = "no-cache\r\nX-Evil: 1"
+
+	err := cfg.Validate()
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cache.report")
+}
+
+func TestLoadRepoCache(t *testing.T) {
+	os.Setenv("SELLERS_REPOSITORY_CACHE_ENABLED", "true")
+	defer os.Unsetenv("SELLERS_REPOSITORY_CACHE_ENABLED")
+
+	cfg, err := Load([]string{"-repository-cache.ttl", "30s"})
+
+	assert.NoError(t, err)
+	assert.True(t, cfg.RepoCache.Enabled)
+	assert.Equal(t, 10000, cfg.RepoCache.Size)
+	assert.Equal(t, 30*time.Second, cfg.RepoCache.TTL.Duration)
+}
+
+func TestValidateRepoCacheOnlyWhenEnabled(t *testing.T) {
+	cfg := Default()
+	cfg.RepoCache.Size = 0
+
+	assert.NoError(t, cfg.Validate())
+
+	cfg.RepoCache.Enabled = true
+	err := cfg.Validate()
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "repository_cache.size")
+}
+
+func TestLoadShutdownSettings(t *testing.T) {
+	os.Setenv("SELLERS_SERVER_DRAIN_PERIOD", "0s")
+	defer os.Unsetenv("SELLERS_SERVER_DRAIN_PERIOD")
+
+	cfg, err := Load([]string{"-server.shutdown-timeout", "1m"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), cfg.Server.DrainPeriod.Duration)
+	assert.Equal(t, time.Minute, cfg.Server.ShutdownTimeout.Duration)
+}
+
+func TestLoadTrustedProxies(t *testing.T) {
+	cfg, err := Load([]string{"-server.trusted-proxies", "10.0.0.0/8, 192.168.1.1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.1"}, cfg.Server.TrustedProxies)
+
+	cfg.Server.TrustedProxies = append(cfg.Server.TrustedProxies, "proxy.local")
+	err = cfg.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `server.trusted_proxies[2] "proxy.local" is not an IP or CIDR`)
+}
+
+func TestLoadRateLimit(t *testing.T) {
+	os.Setenv("SELLERS_RATE_LIMIT_BULK_REQUESTS", "2")
+	defer os.Unsetenv("SELLERS_RATE_LIMIT_BULK_REQUESTS")
+
+	cfg, err := Load([]string{"-rate-limit.bulk.per", "1h"})
+
+	assert.NoError(t, err)
+	assert.True(t, cfg.RateLimit.Enabled)
+	assert.Equal(t, Rate{Requests: 2, Per: Duration{time.Hour}}, cfg.RateLimit.Bulk)
+	assert.Equal(t, Rate{Requests: 300, Per: Duration{time.Minute}}, cfg.RateLimit.Read)
+	assert.Equal(t, Rate{Requests: 20, Per: Duration{time.Minute}}, cfg.RateLimit.Create)
+}
+
+func TestValidateRateLimitOnlyWhenEnabled(t *testing.T) {
+	cfg := Default()
+	cfg.RateLimit.Enabled = false
+	cfg.RateLimit.Write = Rate{}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.RateLimit.Enabled = true
+	err := cfg.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "rate_limit.write.requests must be positive")
+	assert.Contains(t, err.Error(), "rate_limit.write.per must be positive")
+}
+
+func TestLoadAuthTokensFromEnv(t *testing.T) {
+	os.Setenv("SELLERS_AUTH_TOKENS", "frontend:0123456789abcdef:reader, batch:fedcba9876543210:admin")
+	defer os.Unsetenv("SELLERS_AUTH_TOKENS")
+
+	cfg, err := Load(nil)
+
+	assert.NoError(t, err)
+	assert.True(t, cfg.Auth.Enabled)
+	assert.Equal(t, []Token{{"frontend", "0123456789abcdef", "reader"}, {"batch", "fedcba9876543210", "admin"}}, cfg.Auth.Tokens)
+}
+
+func TestLoadAuthTokensWithoutRole(t *testing.T) {
+	os.Setenv("SELLERS_AUTH_TOKENS", "frontend:0123456789abcdef")
+	defer os.Unsetenv("SELLERS_AUTH_TOKENS")
+
+	_, err := Load(nil)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "name:token:role")
+}
+
+func TestValidateAuthTokens(t *testing.T) {
+	cfg := Default()
+	cfg.Auth.Tokens = []Token{{"", "0123456789abcdef", "admin"}, {"short", "abc", "editor"}, {"copy", "0123456789abcdef", "root"}}
+
+	err := cfg.Validate()
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "auth.tokens[0].name")
+	assert.Contains(t, err.Error(), "auth.tokens[1].token must have")
+	assert.Contains(t, err.Error(), "auth.tokens[2].token is repeated")
+	assert.Contains(t, err.Error(), "auth.tokens[2].role must be")
+}
+
+func TestDSN(t *testing.T) {
+	d := Default().Database
+	d.Password = "secret"
+
+	assert.Equal(t, "root:secret@tcp(127.0.0.1:3306)/meli?parseTime=true&readTimeout=30s&timeout=5s&writeTimeout=30s", d.DSN())
+}
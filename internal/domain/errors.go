@@ -0,0 +1,71 @@
+package domain
+
+import "errors"
+
+// Error kinds shared by repositories and services. Package errors wrap one of
+// them so the HTTP layer can pick a status code without knowing the package.
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrConflict        = errors.New("conflict")
+	ErrValidation      = errors.New("validation failed")
+	ErrUnavailable     = errors.New("service unavailable")
+	ErrPrecondition    = errors.New("precondition failed")
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrForbidden       = errors.New("forbidden")
+)
+
+// kindCodes are the codes of errors that were classified without one.
+var kindCodes = map[error]string{
+	ErrNotFound:        "not_found",
+	ErrConflict:        "conflict",
+	ErrValidation:      "validation_failed",
+	ErrUnavailable:     "unavailable",
+	ErrPrecondition:    "precondition_failed",
+	ErrUnauthenticated: "unauthenticated",
+	ErrForbidden:       "forbidden",
+}
+
+// kindError is an error classified under one of the kinds above.
+type kindError struct {
+	kind error
+	code string
+	err  error
+}
+
+func (e *kindError) Error() string { return e.err.Error() }
+
+func (e *kindError) Unwrap() error { return e.err }
+
+func (e *kindError) Is(target error) bool { return target == e.kind }
+
+// Code returns the stable machine-readable code of the error.
+func (e *kindError) Code() string {
+	if e.code != "" {
+		return e.code
+	}
+	return kindCodes[e.kind]
+}
+
+// NewError returns an error with the given code and message classified as
+// kind. The code is what clients match on and the key of its localized message.
+func NewError(kind error, code, msg string) error {
+	return &kindError{kind: kind, code: code, err: errors.New(msg)}
+}
+
+// WithKind classifies err as kind, keeping err reachable through errors.Is/As.
+func WithKind(kind error, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &kindError{kind: kind, err: err}
+}
+
+// Code returns the code of the first error in err's chain that has one, or ""
+// when there is none.
+func Code(err error) string {
+	var coded interface{ Code() string }
+	if errors.As(err, &coded) {
+		return coded.Code()
+	}
+	return ""
+}
@@ -1,11 +1,16 @@
 package domain
 
-type Locality struct{
+type Locality struct {
+	ID           int    `json:"id"`
+	ZipCode      string `json:"zip_code" validate:"required,max=16,alphanum"`
+	LocalityName string `json:"locality_name" validate:"required,max=128"`
+	ProvinceName string `json:"province_name" validate:"required,max=128"`
+	CountryName  string `json:"country_name" validate:"required,max=128"`
+}
 
-	ID          	int  `json:"id"`
-	ZipCode 		string	`json:"zip_code"`
-	LocalityName 	string `json:"locality_name"`
-	ProvinceName    string `json:"province_name"`
-	CountryName   	string `json:"country_name"`
-
-}
\ No newline at end of file
+// LocalityReport is a locality with the number of sellers registered in it.
+type LocalityReport struct {
+	ZipCode      string `json:"zip_code"`
+	LocalityName string `json:"locality_name"`
+	SellersCount int    `json:"sellers_count"`
+}
@@ -1,10 +1,19 @@
 package domain
 
+import "time"
+
+// Seller is a company selling in a locality. DeletedAt is set while the seller
+// is soft deleted. Version grows with every write and UpdatedAt records when
+// it happened; they are exposed as the ETag and Last-Modified of the seller
+// rather than in its body.
 type Seller struct {
-	ID          int    `json:"id"`
-	CID         int    `json:"cid"`
-	CompanyName string `json:"company_name"`
-	Address     string `json:"address"`
-	Telephone   string `json:"telephone"`
-	LocalitiesId int `json:"localities_id"`
+	ID           int        `json:"id"`
+	CID          int        `json:"cid" validate:"required,min=1"`
+	CompanyName  string     `json:"company_name" validate:"required,max=255"`
+	Address      string     `json:"address" validate:"required,max=255"`
+	Telephone    string     `json:"telephone" validate:"required,telephone"`
+	LocalitiesId int        `json:"localities_id" validate:"required,min=1"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+	Version      int        `json:"-"`
+	UpdatedAt    time.Time  `json:"-"`
 }
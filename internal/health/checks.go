@@ -0,0 +1,29 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"Sellers/internal/migration"
+)
+
+// Database checks that db answers a ping.
+func Database(db *sql.DB) CheckFunc {
+	return db.PingContext
+}
+
+// Migrations checks that every migration known to the binary is applied, so
+// the schema is the one the code expects.
+func Migrations(m *migration.Migrator) CheckFunc {
+	return func(ctx context.Context) error {
+		pending, err := m.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migrations, the first is %s", len(pending), pending[0])
+		}
+		return nil
+	}
+}
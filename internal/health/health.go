@@ -0,0 +1,119 @@
+// Package health runs the readiness checks of the service's dependencies.
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Statuses reported for checks and for the service as a whole.
+const (
+	StatusOK       = "ok"
+	StatusFail     = "fail"
+	StatusDraining = "draining"
+)
+
+// CheckFunc reports whether a dependency is usable. It must give up when ctx
+// is done.
+type CheckFunc func(ctx context.Context) error
+
+// Result is the outcome of one check.
+type Result struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report is the outcome of every check. Status is ok only when all of them
+// passed and the service is not draining.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Ready reports whether Status is ok.
+func (r Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker runs named checks, each bounded by a timeout.
+type Checker struct {
+	timeout  time.Duration
+	mu       sync.Mutex
+	checks   []check
+	draining int32
+}
+
+// New returns a Checker without checks giving each check timeout to pass.
+func New(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add registers a check under name.
+func (h *Checker) Add(name string, fn CheckFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, check{name, fn})
+}
+
+// Drain makes the service report itself not ready from now on, so load
+// balancers stop routing to it while in-flight requests finish.
+func (h *Checker) Drain() {
+	atomic.StoreInt32(&h.draining, 1)
+}
+
+// Draining reports whether Drain was called.
+func (h *Checker) Draining() bool {
+	return atomic.LoadInt32(&h.draining) == 1
+}
+
+// Check runs every check concurrently and reports their results.
+func (h *Checker) Check(ctx context.Context) Report {
+	h.mu.Lock()
+	checks := append([]check(nil), h.checks...)
+	h.mu.Unlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			results[i] = h.run(ctx, c.fn)
+		}(i, c)
+	}
+	wg.Wait()
+
+	r := Report{Status: StatusOK, Checks: make(map[string]Result, len(checks))}
+	for i, c := range checks {
+		r.Checks[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			r.Status = StatusFail
+		}
+	}
+	if h.Draining() {
+		r.Status = StatusDraining
+	}
+	return r
+}
+
+func (h *Checker) run(ctx context.Context, fn CheckFunc) Result {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	err := fn(ctx)
+	res := Result{Status: StatusOK}
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		res.Status, res.Error = StatusFail, err.Error()
+	}
+	return res
+}
@@ -0,0 +1,59 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckReportsEveryDependency(t *testing.T) {
+	h := New(time.Second)
+	h.Add("database", func(ctx context.Context) error { return nil })
+	h.Add("migrations", func(ctx context.Context) error { return errors.New("1 pending migrations") })
+
+	r := h.Check(context.Background())
+
+	assert.False(t, r.Ready())
+	assert.Equal(t, StatusFail, r.Status)
+	assert.Equal(t, StatusOK, r.Checks["database"].Status)
+	assert.Equal(t, Result{Status: StatusFail, Error: "1 pending migrations"}, r.Checks["migrations"])
+}
+
+func TestCheckTimesOut(t *testing.T) {
+	h := New(10 * time.Millisecond)
+	h.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	r := h.Check(context.Background())
+
+	assert.Equal(t, StatusFail, r.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), r.Checks["slow"].Error)
+}
+
+func TestDrainingIsNotReady(t *testing.T) {
+	h := New(time.Second)
+	h.Add("database", func(ctx context.Context) error { return nil })
+	assert.True(t, h.Check(context.Background()).Ready())
+
+	h.Drain()
+
+	r := h.Check(context.Background())
+	assert.False(t, r.Ready())
+	assert.Equal(t, StatusDraining, r.Status)
+	assert.Equal(t, StatusOK, r.Checks["database"].Status)
+}
+
+func TestDatabasePing(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	assert.NoError(t, err)
+	defer db.Close()
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+	assert.EqualError(t, Database(db)(context.Background()), "connection refused")
+}
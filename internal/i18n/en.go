@@ -0,0 +1,77 @@
+package i18n
+
+// en is the English bundle. It must define every key of es.
+var en = map[string]string{
+	// generic, one per domain error kind
+	"not_found":           "The resource does not exist",
+	"conflict":            "The resource conflicts with an existing one",
+	"validation_failed":   "The request is not valid",
+	"unavailable":         "The service is unavailable, try again later",
+	"unauthenticated":     "A valid API token is required",
+	"token_not_found":     "There is no active API token with that name",
+	"forbidden":           "Your role does not allow this operation",
+	"invalid_token":       "The API token is not valid",
+	"precondition_failed": "The resource has changed since it was read",
+	"rate_limited":        "Too many requests, try again in %d seconds",
+	"internal_error":      "Internal server error",
+	"bad_request":         "The request is not valid: %s",
+
+	// request parsing
+	"invalid_id":                   "The id is not valid",
+	"invalid_body":                 "Malformed request: %s",
+	"invalid_fields":               "The request has invalid fields",
+	"invalid_limit":                "limit must be a positive integer",
+	"invalid_offset":               "offset must be a non-negative integer",
+	"cursor_with_offset":           "cursor and offset cannot be combined",
+	"invalid_cid_filter":           "cid must be numeric",
+	"invalid_localities_id_filter": "localities_id must be numeric",
+	"invalid_reassign_to":          "reassign_to must be numeric",
+	"zip_code_empty":               "Parameter 'zip_code' is empty",
+
+	// sellers
+	"seller_not_found":                  "The seller does not exist",
+	"seller_cid_exists":                 "A seller with that CID already exists",
+	"seller_invalid_locality":           "Locality %d does not exist",
+	"seller_invalid_query":              "The sellers query is not valid",
+	"sellers_empty":                     "There are no sellers",
+	"seller_deleted":                    "Seller %d has been deleted",
+	"seller_invalid_retention":          "The purge retention must be positive",
+	"invalid_include_deleted":           "include_deleted must be true or false",
+	"seller_version_mismatch":           "The seller was modified by another request, read it again",
+	"seller_history_cursor_unsupported": "The history of a seller does not support cursor, use offset",
+	"seller_batch_cid_exists":           "Seller %d of the batch has a CID that already exists (%d)",
+	"batch_empty":                       "The batch has no sellers",
+	"batch_too_large":                   "The batch exceeds the maximum number of sellers",
+
+	// imports and exports
+	"import_format":         "Unsupported import format, use text/csv or application/x-ndjson",
+	"import_empty":          "The import has no rows",
+	"import_too_large":      "The import exceeds the maximum number of rows",
+	"import_body_too_large": "The import exceeds the maximum size of %d MiB",
+	"import_mode":           "mode must be all-or-nothing or best-effort",
+	"import_header":         "The import header has no seller columns",
+	"export_format":         "format must be csv, ndjson or json",
+
+	// localities
+	"locality_not_found":          "The locality does not exist",
+	"locality_zip_code_exists":    "A locality with that ZipCode already exists",
+	"locality_has_sellers":        "The locality still has sellers",
+	"locality_invalid_reassign":   "The locality to reassign the sellers to is not valid",
+	"locality_cursor_unsupported": "The localities listing does not support cursor, use offset",
+	"localities_zip_not_found":    "There are no localities with zip_code: %s",
+	"locality_deleted":            "Locality %d has been deleted",
+
+	// field validation, formatted with the field name and the rule parameter
+	"validation.required":  "%[1]s is required",
+	"validation.max_len":   "%[1]s must be at most %[2]s characters long",
+	"validation.max":       "%[1]s must be less than or equal to %[2]s",
+	"validation.min_len":   "%[1]s must be at least %[2]s characters long",
+	"validation.min":       "%[1]s must be greater than or equal to %[2]s",
+	"validation.alphanum":  "%[1]s only accepts letters and digits",
+	"validation.telephone": "%[1]s is not a valid telephone",
+	"validation.invalid":   "%[1]s is not valid",
+	"validation.numeric":   "%[1]s must be numeric",
+	"validation.unique":    "%[1]s is repeated in the request",
+	"validation.exists":    "%[1]s %[2]s does not exist",
+	"validation.malformed": "The row is malformed",
+}
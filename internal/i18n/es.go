@@ -0,0 +1,78 @@
+package i18n
+
+// es is the Spanish bundle, the default one. Message keys double as the stable
+// error codes returned to clients.
+var es = map[string]string{
+	// generic, one per domain error kind
+	"not_found":           "El recurso no existe",
+	"conflict":            "El recurso entra en conflicto con uno existente",
+	"validation_failed":   "La peticion no es valida",
+	"unavailable":         "El servicio no esta disponible, intente mas tarde",
+	"unauthenticated":     "Se requiere un token de API valido",
+	"token_not_found":     "No hay un token de API activo con ese nombre",
+	"forbidden":           "Su rol no permite esta operacion",
+	"invalid_token":       "El token de API no es valido",
+	"precondition_failed": "El recurso cambio desde que fue leido",
+	"rate_limited":        "Demasiadas solicitudes, intente de nuevo en %d segundos",
+	"internal_error":      "Error interno del servidor",
+	"bad_request":         "La peticion no es valida: %s",
+
+	// request parsing
+	"invalid_id":                   "El id no es valido",
+	"invalid_body":                 "Error en la peticion: %s",
+	"invalid_fields":               "La peticion contiene campos invalidos",
+	"invalid_limit":                "limit debe ser un entero positivo",
+	"invalid_offset":               "offset debe ser un entero no negativo",
+	"cursor_with_offset":           "cursor y offset no pueden usarse juntos",
+	"invalid_cid_filter":           "cid debe ser numerico",
+	"invalid_localities_id_filter": "localities_id debe ser numerico",
+	"invalid_reassign_to":          "reassign_to debe ser numerico",
+	"zip_code_empty":               "Parametro 'zip_code' vacío",
+
+	// sellers
+	"seller_not_found":                  "El seller no existe",
+	"seller_cid_exists":                 "Ya existe un seller con ese CID",
+	"seller_invalid_locality":           "La locality %d no existe",
+	"seller_invalid_query":              "La consulta de sellers no es valida",
+	"sellers_empty":                     "No hay sellers",
+	"seller_deleted":                    "El seller %d ha sido eliminado",
+	"seller_invalid_retention":          "La retencion del purgado debe ser positiva",
+	"invalid_include_deleted":           "include_deleted debe ser true o false",
+	"seller_version_mismatch":           "El seller fue modificado por otra solicitud, vuelva a leerlo",
+	"seller_history_cursor_unsupported": "El historial de un seller no admite cursor, use offset",
+	"seller_batch_cid_exists":           "El seller %d del lote tiene un CID que ya existe (%d)",
+	"batch_empty":                       "El lote no tiene sellers",
+	"batch_too_large":                   "El lote supera el maximo de sellers permitido",
+
+	// imports and exports
+	"import_format":         "Formato de importacion no soportado, use text/csv o application/x-ndjson",
+	"import_empty":          "La importacion no tiene filas",
+	"import_too_large":      "La importacion supera el maximo de filas permitido",
+	"import_body_too_large": "La importacion supera el tamano maximo de %d MiB",
+	"import_mode":           "mode debe ser all-or-nothing o best-effort",
+	"import_header":         "La cabecera de la importacion no tiene columnas de seller",
+	"export_format":         "format debe ser csv, ndjson o json",
+
+	// localities
+	"locality_not_found":          "La locality no existe",
+	"locality_zip_code_exists":    "Ya existe un locality con ese ZipCode",
+	"locality_has_sellers":        "La locality todavia tiene sellers",
+	"locality_invalid_reassign":   "La locality a la que reasignar los sellers no es valida",
+	"locality_cursor_unsupported": "El listado de localities no admite cursor, use offset",
+	"localities_zip_not_found":    "No existen localities con zip_code: %s",
+	"locality_deleted":            "La locality %d ha sido eliminada",
+
+	// field validation, formatted with the field name and the rule parameter
+	"validation.required":  "%[1]s es requerido",
+	"validation.max_len":   "%[1]s debe tener como maximo %[2]s caracteres",
+	"validation.max":       "%[1]s debe ser menor o igual a %[2]s",
+	"validation.min_len":   "%[1]s debe tener como minimo %[2]s caracteres",
+	"validation.min":       "%[1]s debe ser mayor o igual a %[2]s",
+	"validation.alphanum":  "%[1]s solo admite letras y numeros",
+	"validation.telephone": "%[1]s no es un telefono valido",
+	"validation.invalid":   "%[1]s no es valido",
+	"validation.numeric":   "%[1]s debe ser numerico",
+	"validation.unique":    "%[1]s esta repetido en la peticion",
+	"validation.exists":    "%[1]s %[2]s no existe",
+	"validation.malformed": "La fila esta mal formada",
+}
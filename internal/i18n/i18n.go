@@ -0,0 +1,100 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Lang is a language with a message bundle.
+type Lang string
+
+const (
+	ES Lang = "es"
+	EN Lang = "en"
+)
+
+// Default is used when the client accepts none of the bundled languages.
+const Default = ES
+
+var bundles = map[Lang]map[string]string{
+	ES: es,
+	EN: en,
+}
+
+// Supported reports whether lang has a bundle.
+func Supported(lang Lang) bool {
+	_, ok := bundles[lang]
+	return ok
+}
+
+// T returns the message for key in lang, formatted with args. Messages without
+// verbs ignore args. Keys missing in lang fall back to Default, and unknown
+// keys are returned as they are.
+func T(lang Lang, key string, args ...interface{}) string {
+	msg, ok := bundles[lang][key]
+	if !ok {
+		msg, ok = bundles[Default][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) == 0 || !strings.Contains(msg, "%") {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// Has reports whether key is in the Default bundle.
+func Has(key string) bool {
+	_, ok := bundles[Default][key]
+	return ok
+}
+
+// Negotiate picks the bundled language the client prefers according to an
+// Accept-Language header (RFC 9110), comparing primary subtags only, so
+// "en-US" selects EN. It returns Default when nothing matches.
+func Negotiate(header string) Lang {
+	type candidate struct {
+		lang Lang
+		q    float64
+	}
+
+	var cands []candidate
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		for _, p := range fields[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				v, err := strconv.ParseFloat(p[2:], 64)
+				if err != nil {
+					v = 0
+				}
+				q = v
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		if tag == "*" {
+			cands = append(cands, candidate{Default, q})
+			continue
+		}
+		primary := Lang(strings.SplitN(tag, "-", 2)[0])
+		if Supported(primary) {
+			cands = append(cands, candidate{primary, q})
+		}
+	}
+
+	if len(cands) == 0 {
+		return Default
+	}
+	sort.SliceStable(cands, func(i, j int) bool { return cands[i].q > cands[j].q })
+	return cands[0].lang
+}
@@ -0,0 +1,42 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	cases := map[string]Lang{
+		"":                       ES,
+		"en":                     EN,
+		"en-US,en;q=0.9":         EN,
+		"es-AR,en;q=0.8":         ES,
+		"fr, en;q=0.5, es;q=0.7": ES,
+		"es;q=0.2, en-GB;q=0.9":  EN,
+		"fr-FR, de":              ES,
+		"en;q=0, es":             ES,
+		"*":                      ES,
+	}
+	for header, want := range cases {
+		assert.Equal(t, want, Negotiate(header), header)
+	}
+}
+
+func TestT(t *testing.T) {
+	assert.Equal(t, "El seller 3 ha sido eliminado", T(ES, "seller_deleted", 3))
+	assert.Equal(t, "Seller 3 has been deleted", T(EN, "seller_deleted", 3))
+	assert.Equal(t, "cid must be at most 10 characters long", T(EN, "validation.max_len", "cid", "10"))
+	assert.Equal(t, "unknown_key", T(EN, "unknown_key"))
+}
+
+func TestBundlesHaveSameKeys(t *testing.T) {
+	for key := range es {
+		_, ok := en[key]
+		assert.True(t, ok, "en is missing %q", key)
+	}
+	for key := range en {
+		_, ok := es[key]
+		assert.True(t, ok, "es is missing %q", key)
+	}
+}
@@ -0,0 +1,68 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Server is a Component serving HTTP. Stopping it first drains the service:
+// the readiness check starts failing and, once load balancers had drainPeriod
+// to notice, the server stops accepting connections and waits for the
+// requests in flight.
+type Server struct {
+	srv         *http.Server
+	drain       func()
+	drainPeriod time.Duration
+	fail        func(error)
+	ln          net.Listener
+}
+
+// NewServer returns a Server for srv, calling drain when stopping and fail if
+// serving breaks down, usually Lifecycle.Fail. drain may be nil.
+func NewServer(srv *http.Server, drain func(), drainPeriod time.Duration, fail func(error)) *Server {
+	return &Server{srv: srv, drain: drain, drainPeriod: drainPeriod, fail: fail}
+}
+
+// Start listens on the address of the server, so a busy port is reported
+// here, and serves in the background.
+func (s *Server) Start(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return err
+	}
+	s.ln = ln
+	go func() {
+		if err := s.srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			s.fail(err)
+		}
+	}()
+	return nil
+}
+
+// Addr returns the address the server listens on, which tells the port
+// picked for a ":0" address. It is nil before Start.
+func (s *Server) Addr() net.Addr {
+	if s.ln == nil {
+		return nil
+	}
+	return s.ln.Addr()
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	if s.drain != nil {
+		s.drain()
+	}
+	select {
+	case <-time.After(s.drainPeriod):
+	case <-ctx.Done():
+	}
+	if err := s.srv.Shutdown(ctx); err != nil {
+		// The requests still in flight did not finish in time.
+		s.srv.Close()
+		return err
+	}
+	return nil
+}
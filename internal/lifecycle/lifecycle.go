@@ -0,0 +1,142 @@
+// Package lifecycle starts the components of the service in order and stops
+// them in reverse, so the HTTP server stops taking requests before the
+// workers and the database it relies on go away.
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"Sellers/internal/logging"
+)
+
+// Component is a part of the service with a lifetime of its own.
+type Component interface {
+	// Start must not block: long running work goes in goroutines, which
+	// report fatal errors through Lifecycle.Fail.
+	Start(ctx context.Context) error
+	// Stop releases the component, giving up when ctx is done.
+	Stop(ctx context.Context) error
+}
+
+// Hook adapts a pair of functions to Component; either may be nil.
+type Hook struct {
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+func (h Hook) Start(ctx context.Context) error {
+	if h.OnStart == nil {
+		return nil
+	}
+	return h.OnStart(ctx)
+}
+
+func (h Hook) Stop(ctx context.Context) error {
+	if h.OnStop == nil {
+		return nil
+	}
+	return h.OnStop(ctx)
+}
+
+type named struct {
+	name string
+	c    Component
+}
+
+// Lifecycle runs components in the order they were appended.
+type Lifecycle struct {
+	log        *logging.Logger
+	mu         sync.Mutex
+	components []named
+	started    int
+	failed     chan error
+}
+
+// New returns an empty Lifecycle logging to log.
+func New(log *logging.Logger) *Lifecycle {
+	return &Lifecycle{log: log, failed: make(chan error, 1)}
+}
+
+// Append adds c, started after and stopped before every component appended
+// earlier.
+func (l *Lifecycle) Append(name string, c Component) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.components = append(l.components, named{name, c})
+}
+
+// Start starts the components in order. If one fails, those already started
+// are stopped and its error is returned.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for l.started < len(l.components) {
+		c := l.components[l.started]
+		if err := c.c.Start(ctx); err != nil {
+			l.stop(ctx)
+			return fmt.Errorf("starting %s: %w", c.name, err)
+		}
+		l.log.Debug(ctx, "started", "component", c.name)
+		l.started++
+	}
+	return nil
+}
+
+// Stop stops the started components in reverse order. Every component is
+// stopped even if an earlier one fails; the first error is returned.
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stop(ctx)
+}
+
+func (l *Lifecycle) stop(ctx context.Context) error {
+	var first error
+	for ; l.started > 0; l.started-- {
+		c := l.components[l.started-1]
+		l.log.Info(ctx, "stopping", "component", c.name)
+		if err := c.c.Stop(ctx); err != nil {
+			l.log.Error(ctx, "stopping failed", "component", c.name, "err", err)
+			if first == nil {
+				first = fmt.Errorf("stopping %s: %w", c.name, err)
+			}
+		}
+	}
+	return first
+}
+
+// Fail reports that a started component cannot go on, which makes Run stop
+// the service. Only the first failure is kept.
+func (l *Lifecycle) Fail(err error) {
+	select {
+	case l.failed <- err:
+	default:
+	}
+}
+
+// Run starts the components and stops them when ctx is done or one of them
+// fails, giving them timeout to stop. It returns the failure, if any, or
+// else the error of stopping.
+func (l *Lifecycle) Run(ctx context.Context, timeout time.Duration) error {
+	if err := l.Start(ctx); err != nil {
+		return err
+	}
+
+	var failure error
+	select {
+	case <-ctx.Done():
+	case failure = <-l.failed:
+		l.log.Error(ctx, "component failed", "err", failure)
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := l.Stop(stopCtx); failure == nil {
+		return err
+	}
+	return failure
+}
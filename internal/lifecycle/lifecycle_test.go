@@ -0,0 +1,122 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"Sellers/internal/logging"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func recorder(events *[]string, name string, startErr error) Hook {
+	return Hook{
+		OnStart: func(context.Context) error {
+			*events = append(*events, "start "+name)
+			return startErr
+		},
+		OnStop: func(context.Context) error {
+			*events = append(*events, "stop "+name)
+			return nil
+		},
+	}
+}
+
+func TestStartInOrderStopInReverse(t *testing.T) {
+	var events []string
+	l := New(logging.Discard)
+	l.Append("database", recorder(&events, "database", nil))
+	l.Append("worker", recorder(&events, "worker", nil))
+	l.Append("http", recorder(&events, "http", nil))
+
+	assert.NoError(t, l.Start(context.Background()))
+	assert.NoError(t, l.Stop(context.Background()))
+	assert.NoError(t, l.Stop(context.Background()))
+
+	assert.Equal(t, []string{"start database", "start worker", "start http", "stop http", "stop worker", "stop database"}, events)
+}
+
+func TestStartFailureStopsStartedComponents(t *testing.T) {
+	var events []string
+	l := New(logging.Discard)
+	l.Append("database", recorder(&events, "database", nil))
+	l.Append("http", recorder(&events, "http", errors.New("address already in use")))
+	l.Append("never", recorder(&events, "never", nil))
+
+	err := l.Start(context.Background())
+
+	assert.EqualError(t, err, "starting http: address already in use")
+	assert.Equal(t, []string{"start database", "start http", "stop database"}, events)
+}
+
+func TestStopReturnsFirstErrorAndStopsEverything(t *testing.T) {
+	var events []string
+	l := New(logging.Discard)
+	l.Append("database", recorder(&events, "database", nil))
+	l.Append("http", Hook{OnStop: func(context.Context) error { return errors.New("timeout") }})
+
+	assert.NoError(t, l.Start(context.Background()))
+	err := l.Stop(context.Background())
+
+	assert.EqualError(t, err, "stopping http: timeout")
+	assert.Equal(t, []string{"start database", "stop database"}, events)
+}
+
+func TestRunStopsOnFailure(t *testing.T) {
+	var events []string
+	l := New(logging.Discard)
+	l.Append("database", recorder(&events, "database", nil))
+	l.Append("worker", Hook{OnStart: func(context.Context) error {
+		go l.Fail(errors.New("worker crashed"))
+		return nil
+	}})
+
+	err := l.Run(context.Background(), time.Second)
+
+	assert.EqualError(t, err, "worker crashed")
+	assert.Equal(t, []string{"start database", "stop database"}, events)
+}
+
+func TestServerDrainsBeforeShuttingDown(t *testing.T) {
+	inFlight := make(chan struct{})
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(inFlight)
+		<-release
+		fmt.Fprint(w, "done")
+	})
+	draining := make(chan struct{})
+	l := New(logging.Discard)
+	srv := NewServer(&http.Server{Addr: "127.0.0.1:0", Handler: mux}, func() { close(draining) }, 10*time.Millisecond, l.Fail)
+	l.Append("http", srv)
+	assert.NoError(t, l.Start(context.Background()))
+
+	res := make(chan string)
+	go func() {
+		r, err := http.Get("http://" + srv.Addr().String() + "/slow")
+		if err != nil {
+			res <- err.Error()
+			return
+		}
+		defer r.Body.Close()
+		b, _ := io.ReadAll(r.Body)
+		res <- string(b)
+	}()
+	<-inFlight
+
+	stopped := make(chan error)
+	go func() { stopped <- l.Stop(context.Background()) }()
+	<-draining
+	close(release)
+
+	assert.Equal(t, "done", <-res)
+	assert.NoError(t, <-stopped)
+	_, err := http.Get("http://" + srv.Addr().String() + "/slow")
+	assert.Error(t, err)
+}
@@ -0,0 +1,102 @@
+package locality
+
+import (
+	"context"
+	"strconv"
+
+	"Sellers/internal/cache"
+	"Sellers/internal/domain"
+)
+
+// cachedRepository decorates a Repository with a cache of Get, GetByZipCode
+// and Exists. Writes invalidate the entries they make stale, looking up the
+// zip code the locality had before when they do not carry it.
+type cachedRepository struct {
+	Repository
+	cache *cache.Cache
+}
+
+// NewCachedRepository returns next with its lookups cached in c.
+func NewCachedRepository(next Repository, c *cache.Cache) Repository {
+	return &cachedRepository{Repository: next, cache: c}
+}
+
+func idKey(id int) string             { return "locality:" + strconv.Itoa(id) }
+func zipKey(zipCode string) string    { return "locality:zip:" + zipCode }
+func existsKey(zipCode string) string { return "locality:exists:" + zipCode }
+
+func (r *cachedRepository) Get(ctx context.Context, id int) (domain.Locality, error) {
+	if v, ok := r.cache.Get(idKey(id)); ok {
+		return v.(domain.Locality), nil
+	}
+
+	l, err := r.Repository.Get(ctx, id)
+	if err != nil {
+		return domain.Locality{}, err
+	}
+	r.cache.Set(idKey(id), l)
+	return l, nil
+}
+
+func (r *cachedRepository) GetByZipCode(ctx context.Context, zipCode string) (domain.Locality, error) {
+	if v, ok := r.cache.Get(zipKey(zipCode)); ok {
+		return v.(domain.Locality), nil
+	}
+
+	l, err := r.Repository.GetByZipCode(ctx, zipCode)
+	if err != nil {
+		return domain.Locality{}, err
+	}
+	r.cache.Set(zipKey(zipCode), l)
+	return l, nil
+}
+
+func (r *cachedRepository) Exists(ctx context.Context, zipCode string) (bool, error) {
+	if v, ok := r.cache.Get(existsKey(zipCode)); ok {
+		return v.(bool), nil
+	}
+
+	exists, err := r.Repository.Exists(ctx, zipCode)
+	if err != nil {
+		return false, err
+	}
+	r.cache.Set(existsKey(zipCode), exists)
+	return exists, nil
+}
+
+func (r *cachedRepository) Save(ctx context.Context, l domain.Locality) (int, error) {
+	id, err := r.Repository.Save(ctx, l)
+	r.cache.Delete(zipKey(l.ZipCode), existsKey(l.ZipCode))
+	return id, err
+}
+
+func (r *cachedRepository) Update(ctx context.Context, l domain.Locality) error {
+	keys := append(r.keys(ctx, l.ID), zipKey(l.ZipCode), existsKey(l.ZipCode))
+	err := r.Repository.Update(ctx, l)
+	r.cache.Delete(keys...)
+	return err
+}
+
+func (r *cachedRepository) Delete(ctx context.Context, id int) error {
+	keys := r.keys(ctx, id)
+	err := r.Repository.Delete(ctx, id)
+	r.cache.Delete(keys...)
+	return err
+}
+
+// ReassignAndDelete moves sellers to another locality, which changes cached
+// sellers too, so it clears the whole cache.
+func (r *cachedRepository) ReassignAndDelete(ctx context.Context, id int, to int) error {
+	err := r.Repository.ReassignAndDelete(ctx, id, to)
+	r.cache.Clear()
+	return err
+}
+
+// keys returns the keys cached for locality id as it is stored now.
+func (r *cachedRepository) keys(ctx context.Context, id int) []string {
+	keys := []string{idKey(id)}
+	if old, err := r.Repository.Get(ctx, id); err == nil {
+		keys = append(keys, zipKey(old.ZipCode), existsKey(old.ZipCode))
+	}
+	return keys
+}
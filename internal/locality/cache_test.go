@@ -0,0 +1,72 @@
+package locality
+
+import (
+	"context"
+	"testing"
+
+	"Sellers/internal/cache"
+	"Sellers/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCachedGetByZipCode(t *testing.T) {
+	repo := new(repoM)
+	repo.On("GetByZipCode", mock.Anything, "6700").Return(domain.Locality{ID: 1, ZipCode: "6700"}, nil).Once()
+	c := cache.New(cache.NewLRU(100, 0))
+	cached := NewCachedRepository(repo, c)
+
+	for i := 0; i < 3; i++ {
+		l, err := cached.GetByZipCode(context.Background(), "6700")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, l.ID)
+	}
+
+	assert.Equal(t, cache.Stats{Hits: 2, Misses: 1}, c.Stats())
+	repo.AssertExpectations(t)
+}
+
+func TestCachedUpdateInvalidatesOldZipCode(t *testing.T) {
+	repo := new(repoM)
+	old := domain.Locality{ID: 1, ZipCode: "6700", LocalityName: "Lujan"}
+	repo.On("GetByZipCode", mock.Anything, "6700").Return(old, nil).Once()
+	repo.On("Exists", mock.Anything, "6701").Return(false, nil).Once()
+	repo.On("Get", mock.Anything, 1).Return(old, nil).Once()
+	repo.On("Update", mock.Anything, mock.Anything).Return(nil)
+	cached := NewCachedRepository(repo, cache.New(cache.NewLRU(100, 0)))
+
+	_, err := cached.GetByZipCode(context.Background(), "6700")
+	assert.NoError(t, err)
+	exists, err := cached.Exists(context.Background(), "6701")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	assert.NoError(t, cached.Update(context.Background(), domain.Locality{ID: 1, ZipCode: "6701", LocalityName: "Lujan"}))
+
+	repo.On("GetByZipCode", mock.Anything, "6700").Return(domain.Locality{}, ErrNotFound).Once()
+	repo.On("Exists", mock.Anything, "6701").Return(true, nil).Once()
+	_, err = cached.GetByZipCode(context.Background(), "6700")
+	assert.ErrorIs(t, err, ErrNotFound)
+	exists, err = cached.Exists(context.Background(), "6701")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	repo.AssertExpectations(t)
+}
+
+func TestCachedReassignAndDeleteClearsCache(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Get", mock.Anything, 2).Return(domain.Locality{ID: 2}, nil).Once()
+	repo.On("ReassignAndDelete", mock.Anything, 1, 2).Return(nil)
+	c := cache.New(cache.NewLRU(100, 0))
+	c.Set("seller:7", domain.Seller{ID: 7, LocalitiesId: 1})
+	cached := NewCachedRepository(repo, c)
+
+	_, err := cached.Get(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.NoError(t, cached.ReassignAndDelete(context.Background(), 1, 2))
+
+	_, ok := c.Get("seller:7")
+	assert.False(t, ok)
+	_, ok = c.Get(idKey(2))
+	assert.False(t, ok)
+}
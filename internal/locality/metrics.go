@@ -0,0 +1,111 @@
+package locality
+
+import (
+	"context"
+	"time"
+
+	"Sellers/internal/domain"
+	"Sellers/internal/metrics"
+)
+
+// instrumentedRepository decorates a Repository recording the latency and
+// errors of every operation.
+type instrumentedRepository struct {
+	next Repository
+	ops  *metrics.Operations
+}
+
+// NewInstrumentedRepository returns next with its operations recorded in ops
+// under the "locality" repository.
+func NewInstrumentedRepository(next Repository, ops *metrics.Operations) Repository {
+	return &instrumentedRepository{next: next, ops: ops}
+}
+
+func (r *instrumentedRepository) observe(op string, start time.Time, err error) {
+	r.ops.Observe("locality", op, start, err)
+}
+
+func (r *instrumentedRepository) Save(ctx context.Context, l domain.Locality) (int, error) {
+	start := time.Now()
+	id, err := r.next.Save(ctx, l)
+	r.observe("save", start, err)
+	return id, err
+}
+
+func (r *instrumentedRepository) List(ctx context.Context, q Query) (Page, error) {
+	start := time.Now()
+	p, err := r.next.List(ctx, q)
+	r.observe("list", start, err)
+	return p, err
+}
+
+// Stream is timed until the last row is handed to fn, fn's work included.
+func (r *instrumentedRepository) Stream(ctx context.Context, fn func(domain.Locality) error) error {
+	start := time.Now()
+	err := r.next.Stream(ctx, fn)
+	r.observe("stream", start, err)
+	return err
+}
+
+func (r *instrumentedRepository) Get(ctx context.Context, id int) (domain.Locality, error) {
+	start := time.Now()
+	l, err := r.next.Get(ctx, id)
+	r.observe("get", start, err)
+	return l, err
+}
+
+func (r *instrumentedRepository) GetByZipCode(ctx context.Context, zipCode string) (domain.Locality, error) {
+	start := time.Now()
+	l, err := r.next.GetByZipCode(ctx, zipCode)
+	r.observe("get_by_zip_code", start, err)
+	return l, err
+}
+
+func (r *instrumentedRepository) GetSellers(ctx context.Context, l domain.Locality) ([]domain.Seller, error) {
+	start := time.Now()
+	ss, err := r.next.GetSellers(ctx, l)
+	r.observe("get_sellers", start, err)
+	return ss, err
+}
+
+func (r *instrumentedRepository) CountSellers(ctx context.Context, id int) (int, error) {
+	start := time.Now()
+	n, err := r.next.CountSellers(ctx, id)
+	r.observe("count_sellers", start, err)
+	return n, err
+}
+
+func (r *instrumentedRepository) ReportSellers(ctx context.Context, zipCodes []string) ([]domain.LocalityReport, error) {
+	start := time.Now()
+	lr, err := r.next.ReportSellers(ctx, zipCodes)
+	r.observe("report_sellers", start, err)
+	return lr, err
+}
+
+func (r *instrumentedRepository) Exists(ctx context.Context, zipCode string) (bool, error) {
+	start := time.Now()
+	exists, err := r.next.Exists(ctx, zipCode)
+	r.observe("exists", start, err)
+	return exists, err
+}
+
+func (r *instrumentedRepository) Update(ctx context.Context, l domain.Locality) error {
+	start := time.Now()
+	err := r.next.Update(ctx, l)
+	r.observe("update", start, err)
+	return err
+}
+
+func (r *instrumentedRepository) Delete(ctx context.Context, id int) error {
+	start := time.Now()
+	err := r.next.Delete(ctx, id)
+	r.observe("delete", start, err)
+	return err
+}
+
+func (r *instrumentedRepository) ReassignAndDelete(ctx context.Context, id int, to int) error {
+	start := time.Now()
+	err := r.next.ReassignAndDelete(ctx, id, to)
+	r.observe("reassign_and_delete", start, err)
+	return err
+}
@@ -0,0 +1,28 @@
+package locality
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"Sellers/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestInstrumentedRepositoryRecordsErrors(t *testing.T) {
+	repo := new(repoM)
+	repo.On("CountSellers", mock.Anything, 1).Return(0, errors.New("driver: bad connection"))
+	reg := metrics.NewRegistry()
+	r := NewInstrumentedRepository(repo, metrics.NewOperations(reg))
+
+	_, err := r.CountSellers(context.Background(), 1)
+	assert.Error(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, reg.Write(context.Background(), &buf))
+	assert.Contains(t, buf.String(), `repository_operation_duration_seconds_count{repository="locality",operation="count_sellers"} 1`)
+	assert.Contains(t, buf.String(), `repository_operation_errors_total{repository="locality",operation="count_sellers",kind="other"} 1`)
+}
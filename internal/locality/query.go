@@ -0,0 +1,21 @@
+package locality
+
+import "Sellers/internal/domain"
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Query describes one page of the localities listing, ordered by id.
+type Query struct {
+	Limit  int
+	Offset int
+}
+
+// Page is one page of the localities listing. Limit is the page size actually applied.
+type Page struct {
+	Localities []domain.Locality
+	Total      int
+	Limit      int
+}
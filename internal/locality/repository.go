@@ -2,37 +2,78 @@ package locality
 
 import (
 	"Sellers/internal/domain"
+	"Sellers/internal/logging"
+	"Sellers/internal/reqctx"
+	"Sellers/internal/storage"
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
 )
 
+// Repository encapsulates the storage of a Locality.
 type Repository interface {
 	Save(ctx context.Context, l domain.Locality) (int, error)
-	//GetAll(ctx context.Context) ([]domain.Locality, error)
+	List(ctx context.Context, q Query) (Page, error)
+	// Stream calls fn with every locality ordered by id, one row at a time,
+	// stopping at the first error fn returns.
+	Stream(ctx context.Context, fn func(domain.Locality) error) error
+	Get(ctx context.Context, id int) (domain.Locality, error)
 	GetByZipCode(ctx context.Context, zipCode string) (domain.Locality, error)
 	GetSellers(ctx context.Context, l domain.Locality) ([]domain.Seller, error)
-	Exists(ctx context.Context, id string) bool
+	CountSellers(ctx context.Context, id int) (int, error)
+	ReportSellers(ctx context.Context, zipCodes []string) ([]domain.LocalityReport, error)
+	Exists(ctx context.Context, id string) (bool, error)
+	Update(ctx context.Context, l domain.Locality) error
+	Delete(ctx context.Context, id int) error
+	ReassignAndDelete(ctx context.Context, id int, to int) error
 }
 
 type repository struct {
-	db *sql.DB
+	db  *sql.DB
+	q   storage.Querier
+	log *logging.Logger
 }
 
-func NewRepository(db *sql.DB) Repository {
+// NewRepository returns a Repository on db logging its statements to log.
+func NewRepository(db *sql.DB, log *logging.Logger) Repository {
 	return &repository{
-		db: db,
+		db:  db,
+		q:   storage.Logged(db, log),
+		log: log,
 	}
 }
 
 
+func (r *repository) Stream(ctx context.Context, fn func(domain.Locality) error) error {
+	query := "SELECT id, zip_code, locality_name, province_name, country_name FROM localities ORDER BY id"
+	rows, err := r.q.QueryContext(ctx, query)
+	if err != nil {
+		return storage.Translate(err, ErrNotFound)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		l := domain.Locality{}
+		if err := rows.Scan(&l.ID, &l.ZipCode, &l.LocalityName, &l.ProvinceName, &l.CountryName); err != nil {
+			return storage.Translate(err, ErrNotFound)
+		}
+		if err := fn(l); err != nil {
+			return err
+		}
+	}
+	return storage.Translate(rows.Err(), ErrNotFound)
+}
+
 func (r *repository) GetByZipCode(ctx context.Context, zipCode string) (domain.Locality, error) {
 	 query := "SELECT * FROM localities WHERE zip_code=?"
 	
-	row := r.db.QueryRow(query, zipCode)
+	row := r.q.QueryRowContext(ctx, query, zipCode)
 	s := domain.Locality{}
 	err := row.Scan(&s.ID, &s.ZipCode, &s.LocalityName, &s.ProvinceName, &s.CountryName)
 	if err != nil {
-		return domain.Locality{}, err
+		return domain.Locality{}, storage.Translate(err, ErrNotFound)
 	}
 
 	return s, nil
@@ -41,52 +82,272 @@ func (r *repository) GetByZipCode(ctx context.Context, zipCode string) (domain.L
 
 func (r *repository) Save(ctx context.Context, l domain.Locality) (int, error) {
 	query := "INSERT INTO localities (zip_code, locality_name, province_name, country_name) VALUES (?,?, ?, ?)"
-	stmt, err := r.db.Prepare(query)
+	stmt, err := r.q.PrepareContext(ctx, query)
 	if err != nil {
-		return 0, err
+		return 0, storage.Translate(err, ErrNotFound)
 	}
 
-	res, err := stmt.Exec(l.ZipCode, l.LocalityName, l.ProvinceName, l.CountryName)
+	res, err := stmt.ExecContext(ctx, l.ZipCode, l.LocalityName, l.ProvinceName, l.CountryName)
 	if err != nil {
-		return 0, err
+		return 0, storage.Translate(err, ErrNotFound)
 	}
 
 	id, err := res.LastInsertId()
 	if err != nil {
-		return 0, err
+		return 0, storage.Translate(err, ErrNotFound)
 	}
 
 	return int(id), nil
 }
 
-func (r *repository) Exists(ctx context.Context, id string) bool {
+func (r *repository) Exists(ctx context.Context, id string) (bool, error) {
 	query := "SELECT zip_code FROM localities WHERE zip_code=?;"
-	row := r.db.QueryRow(query, id)
+	row := r.q.QueryRowContext(ctx, query, id)
 	err := row.Scan(&id)
-	return err == nil
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, storage.Translate(err, ErrNotFound)
+	}
+	return true, nil
 }
 
 func (r *repository) GetSellers(ctx context.Context, l domain.Locality) ([]domain.Seller, error) {
-	query := "SELECT * FROM sellers WHERE localities_id=?"
-	stmt, err := r.db.PrepareContext(ctx, query)
+	query := "SELECT id, cid, company_name, address, telephone, localities_id FROM sellers WHERE localities_id=? AND deleted_at IS NULL"
+	stmt, err := r.q.PrepareContext(ctx, query)
 	if err != nil {
-		return []domain.Seller{}, err
+		return []domain.Seller{}, storage.Translate(err, ErrNotFound)
 	}
+	defer stmt.Close()
 
-	rows, err := stmt.Query(l.ID)
+	rows, err := stmt.QueryContext(ctx, l.ID)
 	if err != nil {
-		return []domain.Seller{}, err
+		return []domain.Seller{}, storage.Translate(err, ErrNotFound)
 	}
+	defer rows.Close()
 
 	var sellers []domain.Seller
 
 	for rows.Next() {
 		s := domain.Seller{}
-		_ = rows.Scan(&s.ID, &s.CID, &s.CompanyName, &s.Address, &s.Telephone)
+		if err := rows.Scan(&s.ID, &s.CID, &s.CompanyName, &s.Address, &s.Telephone, &s.LocalitiesId); err != nil {
+			return []domain.Seller{}, storage.Translate(err, ErrNotFound)
+		}
 		sellers = append(sellers, s)
 	}
 
-	return sellers, nil
+	return sellers, storage.Translate(rows.Err(), ErrNotFound)
+}
+
+func (r *repository) List(ctx context.Context, q Query) (Page, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+
+	var total int
+	if err := r.q.QueryRowContext(ctx, "SELECT COUNT(*) FROM localities").Scan(&total); err != nil {
+		return Page{}, storage.Translate(err, ErrNotFound)
+	}
+
+	query := "SELECT id, zip_code, locality_name, province_name, country_name FROM localities ORDER BY id LIMIT ? OFFSET ?"
+	rows, err := r.q.QueryContext(ctx, query, q.Limit, q.Offset)
+	if err != nil {
+		return Page{}, storage.Translate(err, ErrNotFound)
+	}
+	defer rows.Close()
+
+	localities := []domain.Locality{}
+	for rows.Next() {
+		l := domain.Locality{}
+		if err := rows.Scan(&l.ID, &l.ZipCode, &l.LocalityName, &l.ProvinceName, &l.CountryName); err != nil {
+			return Page{}, storage.Translate(err, ErrNotFound)
+		}
+		localities = append(localities, l)
+	}
+	if err := rows.Err(); err != nil {
+		return Page{}, storage.Translate(err, ErrNotFound)
+	}
+
+	return Page{Localities: localities, Total: total, Limit: q.Limit}, nil
+}
+
+func (r *repository) Get(ctx context.Context, id int) (domain.Locality, error) {
+	query := "SELECT id, zip_code, locality_name, province_name, country_name FROM localities WHERE id=?"
+	row := r.q.QueryRowContext(ctx, query, id)
+	l := domain.Locality{}
+	err := row.Scan(&l.ID, &l.ZipCode, &l.LocalityName, &l.ProvinceName, &l.CountryName)
+	if err != nil {
+		return domain.Locality{}, storage.Translate(err, ErrNotFound)
+	}
+
+	return l, nil
+}
+
+// CountSellers also counts soft deleted sellers: they still reference the
+// locality, so it cannot be deleted while they exist.
+func (r *repository) CountSellers(ctx context.Context, id int) (int, error) {
+	var n int
+	err := r.q.QueryRowContext(ctx, "SELECT COUNT(*) FROM sellers WHERE localities_id=?", id).Scan(&n)
+	return n, storage.Translate(err, ErrNotFound)
+}
+
+func (r *repository) Update(ctx context.Context, l domain.Locality) error {
+	query := "UPDATE localities SET zip_code=?, locality_name=?, province_name=?, country_name=? WHERE id=?"
+	stmt, err := r.q.PrepareContext(ctx, query)
+	if err != nil {
+		return storage.Translate(err, ErrNotFound)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, l.ZipCode, l.LocalityName, l.ProvinceName, l.CountryName, l.ID)
+	return storage.Translate(err, ErrNotFound)
 }
 
+func (r *repository) Delete(ctx context.Context, id int) error {
+	stmt, err := r.q.PrepareContext(ctx, "DELETE FROM localities WHERE id=?")
+	if err != nil {
+		return storage.Translate(err, ErrNotFound)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, id)
+	if err != nil {
+		return storage.Translate(err, ErrNotFound)
+	}
+
+	affect, err := res.RowsAffected()
+	if err != nil {
+		return storage.Translate(err, ErrNotFound)
+	}
+	if affect < 1 {
+		return ErrNotFound
+	}
 
+	return nil
+}
+
+// ReassignAndDelete moves every seller of locality id to locality to and
+// deletes id, in a single transaction. Moved sellers get a new version, like
+// any other write to them, so their ETags change, and an update in their
+// history.
+func (r *repository) ReassignAndDelete(ctx context.Context, id int, to int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return storage.Translate(err, ErrNotFound)
+	}
+	defer tx.Rollback()
+	q := storage.Logged(tx, r.log)
+
+	moved, err := lockSellers(ctx, q, id)
+	if err != nil {
+		return err
+	}
+	if _, err := q.ExecContext(ctx, "UPDATE sellers SET localities_id=?, version=version+1, updated_at=UTC_TIMESTAMP() WHERE localities_id=?", to, id); err != nil {
+		return storage.Translate(err, ErrNotFound)
+	}
+	if err := recordMoves(ctx, q, moved, to); err != nil {
+		return err
+	}
+
+	res, err := q.ExecContext(ctx, "DELETE FROM localities WHERE id=?", id)
+	if err != nil {
+		return storage.Translate(err, ErrNotFound)
+	}
+	affect, err := res.RowsAffected()
+	if err != nil {
+		return storage.Translate(err, ErrNotFound)
+	}
+	if affect < 1 {
+		return ErrNotFound
+	}
+
+	return storage.Translate(tx.Commit(), ErrNotFound)
+}
+
+// lockSellers returns every seller of locality id, soft deleted ones too, and
+// locks them until the transaction of q ends.
+func lockSellers(ctx context.Context, q storage.Querier, id int) ([]domain.Seller, error) {
+	query := "SELECT id, cid, company_name, address, telephone, localities_id, deleted_at FROM sellers WHERE localities_id=? ORDER BY id FOR UPDATE"
+	rows, err := q.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, storage.Translate(err, ErrNotFound)
+	}
+	defer rows.Close()
+
+	var sellers []domain.Seller
+	for rows.Next() {
+		s := domain.Seller{}
+		var deletedAt sql.NullTime
+		if err := rows.Scan(&s.ID, &s.CID, &s.CompanyName, &s.Address, &s.Telephone, &s.LocalitiesId, &deletedAt); err != nil {
+			return nil, storage.Translate(err, ErrNotFound)
+		}
+		if deletedAt.Valid {
+			s.DeletedAt = &deletedAt.Time
+		}
+		sellers = append(sellers, s)
+	}
+	return sellers, storage.Translate(rows.Err(), ErrNotFound)
+}
+
+// recordMoves adds an update to the history of every seller moved to
+// locality to, in the format the seller repository records its own changes.
+func recordMoves(ctx context.Context, q storage.Querier, moved []domain.Seller, to int) error {
+	if len(moved) == 0 {
+		return nil
+	}
+	stmt, err := q.PrepareContext(ctx, "INSERT INTO seller_history (seller_id, action, before_data, after_data, actor, request_id, created_at) VALUES (?, 'update', ?, ?, ?, ?, UTC_TIMESTAMP(6))")
+	if err != nil {
+		return storage.Translate(err, ErrNotFound)
+	}
+	defer stmt.Close()
+
+	actor, requestID := reqctx.Actor(ctx), reqctx.RequestID(ctx)
+	for _, before := range moved {
+		after := before
+		after.LocalitiesId = to
+		b, err := json.Marshal(before)
+		if err != nil {
+			return err
+		}
+		a, err := json.Marshal(after)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.ExecContext(ctx, before.ID, string(b), string(a), actor, requestID); err != nil {
+			return storage.Translate(err, ErrNotFound)
+		}
+	}
+	return nil
+}
+
+// ReportSellers counts the sellers of every locality, or only of those with
+// the given zip codes, in a single query. Localities without sellers report 0
+// and soft deleted sellers are not counted.
+func (r *repository) ReportSellers(ctx context.Context, zipCodes []string) ([]domain.LocalityReport, error) {
+	query := "SELECT l.zip_code, l.locality_name, COUNT(s.id) FROM localities l LEFT JOIN sellers s ON s.localities_id = l.id AND s.deleted_at IS NULL"
+	var args []interface{}
+	if len(zipCodes) > 0 {
+		query += " WHERE l.zip_code IN (?" + strings.Repeat(", ?", len(zipCodes)-1) + ")"
+		for _, z := range zipCodes {
+			args = append(args, z)
+		}
+	}
+	query += " GROUP BY l.id, l.zip_code, l.locality_name ORDER BY l.id"
+
+	rows, err := r.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, storage.Translate(err, ErrNotFound)
+	}
+	defer rows.Close()
+
+	report := []domain.LocalityReport{}
+	for rows.Next() {
+		lr := domain.LocalityReport{}
+		if err := rows.Scan(&lr.ZipCode, &lr.LocalityName, &lr.SellersCount); err != nil {
+			return nil, storage.Translate(err, ErrNotFound)
+		}
+		report = append(report, lr)
+	}
+	return report, storage.Translate(rows.Err(), ErrNotFound)
+}
@@ -2,8 +2,11 @@ package locality
 
 import (
 	"Sellers/internal/domain"
+	"Sellers/internal/logging"
+	"Sellers/internal/reqctx"
 	"context"
 	"testing"
+	"time"
 	"github.com/DATA-DOG/go-sqlmock"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
@@ -34,7 +37,7 @@ func TestCreateOk( t *testing.T){
 	WillReturnResult(sqlmock.NewResult(1,1)).
 	WillReturnError(nil)
 
-	localityRepository := NewRepository(db)
+	localityRepository := NewRepository(db, logging.Discard)
 
 	actualId, err := localityRepository.Save(context.Background(),localityToSave)
 	assert.Nil(t, err)
@@ -64,7 +67,7 @@ func TestCreateConflict(t *testing.T){
 		WithArgs(localityToSave.ZipCode, localityToSave.LocalityName, localityToSave.ProvinceName, localityToSave.CountryName).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	localityRepository := NewRepository(db)
+	localityRepository := NewRepository(db, logging.Discard)
 
 	ctx := context.Background()
 	actualId, err := localityRepository.Save(ctx, localityToSave)
@@ -108,9 +111,10 @@ func TestDoesExistsZipCode(t *testing.T) {
 		WithArgs(sellerID).
 		WillReturnRows(rows)
 
-	localityRepository := NewRepository(db)
+	localityRepository := NewRepository(db, logging.Discard)
 
-	doesExist := localityRepository.Exists(context.Background(), sellerID)
+	doesExist, err := localityRepository.Exists(context.Background(), sellerID)
+	assert.NoError(t, err)
 	assert.True(t, doesExist)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -123,25 +127,19 @@ func TestDoesntExistsZipCode(t *testing.T) {
 
 	mock.
 		ExpectQuery("SELECT zip_code FROM localities WHERE zip_code=\\?;").
-		WithArgs(testID)
+		WithArgs(testID).
+		WillReturnRows(sqlmock.NewRows([]string{"zip_code"}))
 
-	localityRepository := NewRepository(db)
+	localityRepository := NewRepository(db, logging.Discard)
 
-	doesExist := localityRepository.Exists(context.Background(), testID)
+	doesExist, err := localityRepository.Exists(context.Background(), testID)
 
+	assert.NoError(t, err)
 	assert.False(t, doesExist)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestGetLocalityByZipCodeFail(t *testing.T) {
-	expectedLocality := domain.Locality{
-		ID: 			1,
-		ZipCode:      "6700",
-		LocalityName: "Lujan",
-		ProvinceName: "Buenos Aires",
-		CountryName:  "Argentina",
-	}
-
 	db, mock, sqlMockErr := sqlmock.New()
 	assert.Nil(t, sqlMockErr)
 	defer db.Close()
@@ -149,20 +147,17 @@ func TestGetLocalityByZipCodeFail(t *testing.T) {
 	columns := []string{"id", "zip_code", "locality_name","province_name", "country_name"}
 	rows := sqlmock.NewRows(columns)
 
-	sellerId := 1
-	rows.AddRow(sellerId, "6700", "Lujan", "Buenos Aires", "Argentina")
 	mock.
 		ExpectQuery("SELECT \\* FROM localities WHERE zip_code=\\?").
 		WithArgs("4000").
 		WillReturnRows(rows)
 
-	localityRepository := NewRepository(db)
+	localityRepository := NewRepository(db, logging.Discard)
 
 	actualResult, err := localityRepository.GetByZipCode(context.Background(), "4000")
 
-	assert.Nil(t, err)
-	assert.NotNil(t, actualResult)
-	assert.NotEqual(t, expectedLocality, actualResult)
+	assert.Equal(t, ErrNotFound, err)
+	assert.Equal(t, domain.Locality{}, actualResult)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -199,7 +194,7 @@ func TestGetLocalityByZipCodeFail(t *testing.T) {
 // 		WithArgs(expectedSeller).
 // 		WillReturnRows(rows)
 
-// 	localityRepository := NewRepository(db)
+// 	localityRepository := NewRepository(db, logging.Discard)
 
 // 	actualResult, err := localityRepository.GetSellers(context.Background(), expectedLocality)
 
@@ -209,4 +204,140 @@ func TestGetLocalityByZipCodeFail(t *testing.T) {
 // 	assert.NoError(t, mock.ExpectationsWereMet())
 
 
-// }
\ No newline at end of file
+// }
+
+func TestListLocalities(t *testing.T) {
+	db, mock, sqlMockErr := sqlmock.New()
+	assert.Nil(t, sqlMockErr)
+	defer db.Close()
+
+	mock.
+		ExpectQuery("SELECT COUNT\\(\\*\\) FROM localities").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	columns := []string{"id", "zip_code", "locality_name", "province_name", "country_name"}
+	mock.
+		ExpectQuery("SELECT id, zip_code, locality_name, province_name, country_name FROM localities ORDER BY id LIMIT \\? OFFSET \\?").
+		WithArgs(2, 2).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(3, "4000", "San Miguel de Tucuman", "Tucuman", "Argentina"))
+
+	localityRepository := NewRepository(db, logging.Discard)
+
+	page, err := localityRepository.List(context.Background(), Query{Limit: 2, Offset: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, page.Total)
+	assert.Len(t, page.Localities, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetLocalityNotFound(t *testing.T) {
+	db, mock, sqlMockErr := sqlmock.New()
+	assert.Nil(t, sqlMockErr)
+	defer db.Close()
+
+	mock.
+		ExpectQuery("SELECT id, zip_code, locality_name, province_name, country_name FROM localities WHERE id=\\?").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "zip_code", "locality_name", "province_name", "country_name"}))
+
+	localityRepository := NewRepository(db, logging.Discard)
+
+	_, err := localityRepository.Get(context.Background(), 7)
+	assert.Equal(t, ErrNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReassignAndDelete(t *testing.T) {
+	db, mock, sqlMockErr := sqlmock.New()
+	assert.Nil(t, sqlMockErr)
+	defer db.Close()
+
+	deletedAt := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, cid, company_name, address, telephone, localities_id, deleted_at FROM sellers WHERE localities_id=\\? ORDER BY id FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "cid", "company_name", "address", "telephone", "localities_id", "deleted_at"}).
+			AddRow(7, 70, "Meli", "Bulnes 10", "123456", 1, nil).
+			AddRow(8, 80, "Globant", "Libertador 1", "654321", 1, deletedAt))
+	mock.ExpectExec("UPDATE sellers SET localities_id=\\?, version=version\\+1, updated_at=UTC_TIMESTAMP\\(\\) WHERE localities_id=\\?").WithArgs(2, 1).WillReturnResult(sqlmock.NewResult(0, 2))
+	history := mock.ExpectPrepare("INSERT INTO seller_history \\(seller_id, action, before_data, after_data, actor, request_id, created_at\\) VALUES \\(\\?, 'update', \\?, \\?, \\?, \\?, UTC_TIMESTAMP\\(6\\)\\)")
+	history.ExpectExec().
+		WithArgs(7,
+			`{"id":7,"cid":70,"company_name":"Meli","address":"Bulnes 10","telephone":"123456","localities_id":1}`,
+			`{"id":7,"cid":70,"company_name":"Meli","address":"Bulnes 10","telephone":"123456","localities_id":2}`,
+			"ana", "req-1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	history.ExpectExec().
+		WithArgs(8,
+			`{"id":8,"cid":80,"company_name":"Globant","address":"Libertador 1","telephone":"654321","localities_id":1,"deleted_at":"2022-03-01T12:00:00Z"}`,
+			`{"id":8,"cid":80,"company_name":"Globant","address":"Libertador 1","telephone":"654321","localities_id":2,"deleted_at":"2022-03-01T12:00:00Z"}`,
+			"ana", "req-1").
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("DELETE FROM localities WHERE id=\\?").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	localityRepository := NewRepository(db, logging.Discard)
+	ctx := reqctx.WithRequestID(reqctx.WithActor(context.Background(), "ana"), "req-1")
+
+	err := localityRepository.ReassignAndDelete(ctx, 1, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReassignAndDeleteRollsBack(t *testing.T) {
+	db, mock, sqlMockErr := sqlmock.New()
+	assert.Nil(t, sqlMockErr)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, cid, company_name, address, telephone, localities_id, deleted_at FROM sellers").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "cid", "company_name", "address", "telephone", "localities_id", "deleted_at"}))
+	mock.ExpectExec("UPDATE sellers").WillReturnError(errors.New("fk violation"))
+	mock.ExpectRollback()
+
+	localityRepository := NewRepository(db, logging.Discard)
+
+	err := localityRepository.ReassignAndDelete(context.Background(), 1, 2)
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReportSellersByZipCodes(t *testing.T) {
+	db, mock, sqlMockErr := sqlmock.New()
+	assert.Nil(t, sqlMockErr)
+	defer db.Close()
+
+	mock.
+		ExpectQuery("SELECT l.zip_code, l.locality_name, COUNT\\(s.id\\) FROM localities l LEFT JOIN sellers s ON s.localities_id = l.id AND s.deleted_at IS NULL WHERE l.zip_code IN \\(\\?, \\?\\) GROUP BY l.id, l.zip_code, l.locality_name").
+		WithArgs("6700", "4000").
+		WillReturnRows(sqlmock.NewRows([]string{"zip_code", "locality_name", "count"}).
+			AddRow("6700", "Lujan", 2).
+			AddRow("4000", "San Miguel de Tucuman", 0))
+
+	localityRepository := NewRepository(db, logging.Discard)
+
+	report, err := localityRepository.ReportSellers(context.Background(), []string{"6700", "4000"})
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.LocalityReport{
+		{ZipCode: "6700", LocalityName: "Lujan", SellersCount: 2},
+		{ZipCode: "4000", LocalityName: "San Miguel de Tucuman", SellersCount: 0},
+	}, report)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReportSellersAll(t *testing.T) {
+	db, mock, sqlMockErr := sqlmock.New()
+	assert.Nil(t, sqlMockErr)
+	defer db.Close()
+
+	mock.
+		ExpectQuery("LEFT JOIN sellers s ON s.localities_id = l.id AND s.deleted_at IS NULL GROUP BY").
+		WithArgs().
+		WillReturnRows(sqlmock.NewRows([]string{"zip_code", "locality_name", "count"}).AddRow("6700", "Lujan", 0))
+
+	localityRepository := NewRepository(db, logging.Discard)
+
+	report, err := localityRepository.ReportSellers(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Len(t, report, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -2,33 +2,71 @@ package locality
 
 import (
 	"Sellers/internal/domain"
+	"Sellers/internal/logging"
+	"Sellers/internal/validation"
 	"context"
 	"errors"
 )
 
 var (
-	ErrNotFound = errors.New("locality not found")
+	ErrNotFound        = domain.NewError(domain.ErrNotFound, "locality_not_found", "locality not found")
+	ErrZipCodeExists   = domain.NewError(domain.ErrConflict, "locality_zip_code_exists", "a locality with that zip code already exists")
+	ErrHasSellers      = domain.NewError(domain.ErrConflict, "locality_has_sellers", "locality still has sellers")
+	ErrInvalidReassign = domain.NewError(domain.ErrValidation, "locality_invalid_reassign", "invalid locality to reassign sellers to")
 )
 
+// Delete policies for a locality that still has sellers.
+const (
+	DeleteBlock    = "block"
+	DeleteReassign = "reassign"
+)
+
+// DeletePolicy decides what Delete does with a locality that still has
+// sellers: refuse (DeleteBlock) or move them to another locality
+// (DeleteReassign). ReassignTo is the default target when the caller gives none.
+type DeletePolicy struct {
+	Mode       string
+	ReassignTo int
+}
+
 type Service interface {
+	List(ctx context.Context, q Query) (Page, error)
+	Export(ctx context.Context, fn func(domain.Locality) error) error
+	Get(ctx context.Context, id int) (domain.Locality, error)
 	GetByZipCode(ctx context.Context, zipCode string) (domain.Locality, error)
 	GetSellers(ctx context.Context, l domain.Locality) ([]domain.Seller, error)
+	ReportSellers(ctx context.Context, zipCodes []string) ([]domain.LocalityReport, error)
 	Save(ctx context.Context, lo domain.Locality) (domain.Locality, error)
-	Exists(ctx context.Context, zipC string) bool
+	Update(ctx context.Context, lo domain.Locality) error
+	Delete(ctx context.Context, id int, reassignTo int) error
+	Exists(ctx context.Context, zipC string) (bool, error)
 }
 
 type service struct {
-	repo Repository
+	repo   Repository
+	policy DeletePolicy
+	log    *logging.Logger
+}
+
+func NewService(l Repository, log *logging.Logger) Service {
+	return &service{repo: l, policy: DeletePolicy{Mode: DeleteBlock}, log: log}
 }
 
-func NewService(l Repository) Service {
-	return &service{repo: l}
+// NewServiceWithPolicy is NewService with a non-default DeletePolicy.
+func NewServiceWithPolicy(l Repository, p DeletePolicy, log *logging.Logger) Service {
+	return &service{repo: l, policy: p, log: log}
 }
 
 func (l *service) Save(ctx context.Context, lo domain.Locality) (domain.Locality, error) {
-	exist := l.repo.Exists(ctx, lo.ZipCode)
+	if err := validation.Struct(lo); err != nil {
+		return domain.Locality{}, err
+	}
+	exist, err := l.repo.Exists(ctx, lo.ZipCode)
+	if err != nil {
+		return domain.Locality{}, err
+	}
 	if exist {
-		return domain.Locality{}, ErrNotFound
+		return domain.Locality{}, ErrZipCodeExists
 	}
 
 	p, err := l.repo.Save(ctx, lo)
@@ -43,7 +81,7 @@ func (l *service) Save(ctx context.Context, lo domain.Locality) (domain.Locality
 
 }
 
-func (l *service) Exists(ctx context.Context, zipC string) bool {
+func (l *service) Exists(ctx context.Context, zipC string) (bool, error) {
 	return l.repo.Exists(ctx, zipC)
 }
 
@@ -59,4 +97,92 @@ func (l *service) GetByZipCode(ctx context.Context, zipCode string) (domain.Loca
 
 func (s *service) GetSellers(ctx context.Context, l domain.Locality) ([]domain.Seller, error) {
 	return s.repo.GetSellers(ctx, l)
-}
\ No newline at end of file
+}
+
+// ReportSellers returns the seller count per locality; an empty zipCodes
+// means every locality.
+func (s *service) ReportSellers(ctx context.Context, zipCodes []string) ([]domain.LocalityReport, error) {
+	return s.repo.ReportSellers(ctx, zipCodes)
+}
+
+func (l *service) List(ctx context.Context, q Query) (Page, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+	return l.repo.List(ctx, q)
+}
+
+// Export streams every locality to fn, ordered by id.
+func (l *service) Export(ctx context.Context, fn func(domain.Locality) error) error {
+	return l.repo.Stream(ctx, fn)
+}
+
+func (l *service) Get(ctx context.Context, id int) (domain.Locality, error) {
+	return l.repo.Get(ctx, id)
+}
+
+// Update rejects a zip code change that collides with another locality.
+func (l *service) Update(ctx context.Context, lo domain.Locality) error {
+	if err := validation.Struct(lo); err != nil {
+		return err
+	}
+	current, err := l.repo.Get(ctx, lo.ID)
+	if err != nil {
+		return err
+	}
+	if lo.ZipCode != current.ZipCode {
+		exist, err := l.repo.Exists(ctx, lo.ZipCode)
+		if err != nil {
+			return err
+		}
+		if exist {
+			return ErrZipCodeExists
+		}
+	}
+	return l.repo.Update(ctx, lo)
+}
+
+// Delete removes a locality, applying the delete policy when it still has
+// sellers. reassignTo overrides the policy's default target when non-zero.
+func (l *service) Delete(ctx context.Context, id int, reassignTo int) error {
+	if _, err := l.repo.Get(ctx, id); err != nil {
+		return err
+	}
+
+	n, err := l.repo.CountSellers(ctx, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return l.repo.Delete(ctx, id)
+	}
+
+	if l.policy.Mode != DeleteReassign {
+		return ErrHasSellers
+	}
+	to := reassignTo
+	if to == 0 {
+		to = l.policy.ReassignTo
+	}
+	if to == 0 {
+		return ErrHasSellers
+	}
+	if to == id {
+		return ErrInvalidReassign
+	}
+	if _, err := l.repo.Get(ctx, to); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrInvalidReassign
+		}
+		return err
+	}
+
+	if err := l.repo.ReassignAndDelete(ctx, id, to); err != nil {
+		return err
+	}
+	l.log.Info(ctx, "locality deleted, sellers reassigned", "id", id, "reassigned_to", to, "sellers", n)
+	return nil
+}
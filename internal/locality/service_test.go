@@ -3,6 +3,7 @@ package locality
 import (
 	"context"
 	"Sellers/internal/domain"
+	"Sellers/internal/logging"
 	"testing"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -13,9 +14,9 @@ type repoM struct{
 	mock.Mock
 }
 
-func (r *repoM) Exists(ctx context.Context, zipC string) bool {
+func (r *repoM) Exists(ctx context.Context, zipC string) (bool, error) {
 	args := r.Called(ctx, zipC)
-	return args.Bool(0)
+	return args.Bool(0), args.Error(1)
 }
 
 func (r *repoM) Save(ctx context.Context, loc domain.Locality) (int, error) {
@@ -28,6 +29,51 @@ func (r *repoM) GetAll(ctx context.Context) ([]domain.Locality, error) {
 	return args.Get(0).([]domain.Locality), args.Error(1)
 }
 
+func (r *repoM) List(ctx context.Context, q Query) (Page, error) {
+	args := r.Called(ctx, q)
+	return args.Get(0).(Page), args.Error(1)
+}
+
+func (r *repoM) Stream(ctx context.Context, fn func(domain.Locality) error) error {
+	args := r.Called(ctx)
+	for _, l := range args.Get(0).([]domain.Locality) {
+		if err := fn(l); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (r *repoM) Get(ctx context.Context, id int) (domain.Locality, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).(domain.Locality), args.Error(1)
+}
+
+func (r *repoM) CountSellers(ctx context.Context, id int) (int, error) {
+	args := r.Called(ctx, id)
+	return args.Int(0), args.Error(1)
+}
+
+func (r *repoM) ReportSellers(ctx context.Context, zipCodes []string) ([]domain.LocalityReport, error) {
+	args := r.Called(ctx, zipCodes)
+	return args.Get(0).([]domain.LocalityReport), args.Error(1)
+}
+
+func (r *repoM) Update(ctx context.Context, l domain.Locality) error {
+	args := r.Called(ctx, l)
+	return args.Error(0)
+}
+
+func (r *repoM) Delete(ctx context.Context, id int) error {
+	args := r.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (r *repoM) ReassignAndDelete(ctx context.Context, id int, to int) error {
+	args := r.Called(ctx, id, to)
+	return args.Error(0)
+}
+
 func (r *repoM) GetByZipCode(ctx context.Context, zipCode string) (domain.Locality, error) {
 	args := r.Called(ctx, zipCode)
 	return args.Get(0).(domain.Locality), args.Error(1)
@@ -45,9 +91,9 @@ func TestCreateLocalitiesOK(t *testing.T) {
 	
 	repo.On("Save", mock.Anything, mock.Anything).Return(1, nil) 
 	
-	repo.On("Exists", mock.Anything, mock.Anything).Return(false)
+	repo.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
 
-	s := NewService(repo) 
+	s := NewService(repo, logging.Discard) 
 
 
 	objetoAPersistir := domain.Locality{
@@ -69,11 +115,12 @@ func TestCreateLocalitiesOK(t *testing.T) {
 
 func TestCreateLocalitiesConflict(t *testing.T){
 	repo := new(repoM)
-	repo.On("Exists", mock.Anything, mock.Anything).Return(true)
+	repo.On("Exists", mock.Anything, mock.Anything).Return(true, nil)
 	repo.On("Save", mock.Anything, mock.Anything).Return(domain.Locality{}, ErrNotFound) 
-	serviceT := NewService(repo)
+	serviceT := NewService(repo, logging.Discard)
 	ctx := context.Background()
-	result := serviceT.Exists(ctx, domain.Locality{}.ZipCode)
+	result, err := serviceT.Exists(ctx, domain.Locality{}.ZipCode)
+	assert.NoError(t, err)
 	assert.True(t, result)
 
 
@@ -100,7 +147,7 @@ func TestGetByZipCodeOk(t *testing.T){
 		ProvinceName: "Tucuman",
 		CountryName: "Argentina",
 	}
-	s := NewService(repo)
+	s := NewService(repo, logging.Discard)
 
 	objetoRecuperado, err := s.GetByZipCode(context.Background(),"4000")
 
@@ -131,7 +178,7 @@ func TestGetByZipCodeFail(t *testing.T){
 		ProvinceName: "Tucuman",
 		CountryName: "Argentina",
 	}
-	s := NewService(repo)
+	s := NewService(repo, logging.Discard)
 
 	objetoRecuperado, err := s.GetByZipCode(context.Background(),"6700")
 
@@ -168,7 +215,7 @@ func TestGetSellersOk(t *testing.T){
 	}, nil)
 
 	
-	s:= NewService(repo)
+	s:= NewService(repo, logging.Discard)
 
 	objetosRecuperados, err := s.GetSellers(context.Background(),obj)
 
@@ -190,7 +237,7 @@ func TestGetSellersFail(t *testing.T){
 	repo.On("GetSellers", mock.Anything, mock.Anything).Return([]domain.Seller{}, nil)
 
 	
-	s:= NewService(repo)
+	s:= NewService(repo, logging.Discard)
 
 	objetosRecuperados, err := s.GetSellers(context.Background(),obj)
 
@@ -202,9 +249,73 @@ func TestGetSellersFail(t *testing.T){
 
 func TestExist(t *testing.T){
 	repo := new(repoM)
-	repo.On("Exists", mock.Anything, mock.Anything).Return(false)
-	s :=NewService(repo)
+	repo.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
+	s :=NewService(repo, logging.Discard)
 	ctx := context.Background()
-	err := s.Exists(ctx, "4000")
-	assert.Equal(t,false, err)
+	exists, err := s.Exists(ctx, "4000")
+	assert.NoError(t, err)
+	assert.Equal(t,false, exists)
+}
+
+func TestUpdateZipCodeConflict(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Get", mock.Anything, 1).Return(domain.Locality{ID: 1, ZipCode: "4000"}, nil)
+	repo.On("Exists", mock.Anything, "6700").Return(true, nil)
+	s := NewService(repo, logging.Discard)
+
+	err := s.Update(context.Background(), domain.Locality{ID: 1, ZipCode: "6700", LocalityName: "Lujan", ProvinceName: "Buenos Aires", CountryName: "Argentina"})
+
+	assert.Equal(t, ErrZipCodeExists, err)
+	repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestDeleteWithoutSellers(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Get", mock.Anything, 1).Return(domain.Locality{ID: 1}, nil)
+	repo.On("CountSellers", mock.Anything, 1).Return(0, nil)
+	repo.On("Delete", mock.Anything, 1).Return(nil)
+	s := NewService(repo, logging.Discard)
+
+	err := s.Delete(context.Background(), 1, 0)
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestDeleteBlockedBySellers(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Get", mock.Anything, 1).Return(domain.Locality{ID: 1}, nil)
+	repo.On("CountSellers", mock.Anything, 1).Return(2, nil)
+	s := NewService(repo, logging.Discard)
+
+	err := s.Delete(context.Background(), 1, 5)
+
+	assert.Equal(t, ErrHasSellers, err)
+	repo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestDeleteReassignsSellers(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Get", mock.Anything, 1).Return(domain.Locality{ID: 1}, nil)
+	repo.On("Get", mock.Anything, 2).Return(domain.Locality{ID: 2}, nil)
+	repo.On("CountSellers", mock.Anything, 1).Return(2, nil)
+	repo.On("ReassignAndDelete", mock.Anything, 1, 2).Return(nil)
+	s := NewServiceWithPolicy(repo, DeletePolicy{Mode: DeleteReassign, ReassignTo: 2}, logging.Discard)
+
+	err := s.Delete(context.Background(), 1, 0)
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestDeleteReassignToMissingLocality(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Get", mock.Anything, 1).Return(domain.Locality{ID: 1}, nil)
+	repo.On("Get", mock.Anything, 9).Return(domain.Locality{}, ErrNotFound)
+	repo.On("CountSellers", mock.Anything, 1).Return(2, nil)
+	s := NewServiceWithPolicy(repo, DeletePolicy{Mode: DeleteReassign}, logging.Discard)
+
+	err := s.Delete(context.Background(), 1, 9)
+
+	assert.Equal(t, ErrInvalidReassign, err)
 }
@@ -0,0 +1,170 @@
+// Package logging writes structured log lines as JSON, one object per line,
+// in the style of log/slog: a level, a message and key/value pairs. Lines
+// logged with a request context carry its request ID, which correlates the
+// access log with the SQL and service events of the request.
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"Sellers/internal/reqctx"
+)
+
+// Level is the severity of a log line; its values are those of slog.
+type Level int
+
+const (
+	LevelDebug Level = -4
+	LevelInfo  Level = 0
+	LevelWarn  Level = 4
+	LevelError Level = 8
+)
+
+func (l Level) String() string {
+	switch {
+	case l < LevelInfo:
+		return "DEBUG"
+	case l < LevelWarn:
+		return "INFO"
+	case l < LevelError:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+// ParseLevel parses debug, info, warn or error, in any case.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return 0, fmt.Errorf("log level %q must be one of debug, info, warn, error", s)
+}
+
+// Logger writes the lines of level or above to its writer. It is safe for
+// concurrent use.
+type Logger struct {
+	mu    *sync.Mutex
+	w     io.Writer
+	level Level
+	// attrs are the key/value pairs added by With, already encoded.
+	attrs []byte
+	now   func() time.Time
+}
+
+// Discard logs nothing; it is meant for tests and tools.
+var Discard = New(io.Discard, LevelError+1)
+
+// New returns a Logger writing to w the lines of level or above.
+func New(w io.Writer, level Level) *Logger {
+	return &Logger{mu: new(sync.Mutex), w: w, level: level, now: time.Now}
+}
+
+// With returns a Logger adding the key/value pairs of args to every line.
+func (l *Logger) With(args ...interface{}) *Logger {
+	c := *l
+	c.attrs = appendAttrs(append([]byte(nil), l.attrs...), args)
+	return &c
+}
+
+// Enabled reports whether lines of level are written.
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+func (l *Logger) Debug(ctx context.Context, msg string, args ...interface{}) {
+	l.Log(ctx, LevelDebug, msg, args...)
+}
+
+func (l *Logger) Info(ctx context.Context, msg string, args ...interface{}) {
+	l.Log(ctx, LevelInfo, msg, args...)
+}
+
+func (l *Logger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	l.Log(ctx, LevelWarn, msg, args...)
+}
+
+func (l *Logger) Error(ctx context.Context, msg string, args ...interface{}) {
+	l.Log(ctx, LevelError, msg, args...)
+}
+
+// Log writes msg at level with the alternating keys and values of args. A
+// key without a value is logged under "!BADKEY", as slog does.
+func (l *Logger) Log(ctx context.Context, level Level, msg string, args ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	buf := make([]byte, 0, 256)
+	buf = append(buf, `{"time":`...)
+	buf = appendJSON(buf, l.now().UTC().Format(time.RFC3339Nano))
+	buf = appendPair(buf, "level", level.String())
+	buf = appendPair(buf, "msg", msg)
+	if id := reqctx.RequestID(ctx); id != "" {
+		buf = appendPair(buf, "request_id", id)
+	}
+	buf = append(buf, l.attrs...)
+	buf = appendAttrs(buf, args)
+	buf = append(buf, '}', '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(buf)
+}
+
+func appendAttrs(buf []byte, args []interface{}) []byte {
+	for len(args) > 0 {
+		key, ok := args[0].(string)
+		if !ok || len(args) == 1 {
+			buf = appendPair(buf, "!BADKEY", args[0])
+			args = args[1:]
+			continue
+		}
+		buf = appendPair(buf, key, args[1])
+		args = args[2:]
+	}
+	return buf
+}
+
+// appendPair appends ,"key":value. Errors are logged as their message and
+// durations in milliseconds.
+func appendPair(buf []byte, key string, value interface{}) []byte {
+	buf = append(buf, ',')
+	buf = appendJSON(buf, key)
+	buf = append(buf, ':')
+
+	switch v := value.(type) {
+	case error:
+		value = v.Error()
+	case time.Duration:
+		value = float64(v) / float64(time.Millisecond)
+	case fmt.Stringer:
+		value = v.String()
+	}
+	return appendJSON(buf, value)
+}
+
+func appendJSON(buf []byte, v interface{}) []byte {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		b.Reset()
+		enc.Encode(fmt.Sprint(v))
+	}
+	return append(buf, bytes.TrimRight(b.Bytes(), "\n")...)
+}
@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"Sellers/internal/reqctx"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLogger(level Level) (*Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	l := New(&buf, level)
+	l.now = func() time.Time { return time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC) }
+	return l, &buf
+}
+
+func TestLogWritesJSONLine(t *testing.T) {
+	l, buf := newTestLogger(LevelInfo)
+	ctx := reqctx.WithRequestID(context.Background(), "req-1")
+
+	l.With("component", "seller").Info(ctx, "seller created", "id", 7, "err", errors.New("boom"), "took", 1500*time.Microsecond)
+
+	assert.Equal(t, `{"time":"2022-03-01T12:00:00Z","level":"INFO","msg":"seller created","request_id":"req-1",`+
+		`"component":"seller","id":7,"err":"boom","took":1.5}`+"\n", buf.String())
+}
+
+func TestLogSkipsLinesBelowLevel(t *testing.T) {
+	l, buf := newTestLogger(LevelWarn)
+
+	l.Info(context.Background(), "ignored")
+	l.Debug(context.Background(), "ignored")
+	l.Error(context.Background(), "kept")
+
+	assert.Equal(t, `{"time":"2022-03-01T12:00:00Z","level":"ERROR","msg":"kept"}`+"\n", buf.String())
+	assert.False(t, Discard.Enabled(LevelError))
+}
+
+func TestLogBadKey(t *testing.T) {
+	l, buf := newTestLogger(LevelInfo)
+
+	l.Info(context.Background(), "odd", 1, "dangling")
+
+	assert.Equal(t, `{"time":"2022-03-01T12:00:00Z","level":"INFO","msg":"odd","!BADKEY":1,"!BADKEY":"dangling"}`+"\n", buf.String())
+}
+
+func TestParseLevel(t *testing.T) {
+	l, err := ParseLevel("WARN")
+	assert.NoError(t, err)
+	assert.Equal(t, LevelWarn, l)
+	assert.Equal(t, "WARN", l.String())
+
+	_, err = ParseLevel("verbose")
+	assert.Error(t, err)
+}
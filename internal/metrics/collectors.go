@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"Sellers/internal/cache"
+	"Sellers/internal/domain"
+)
+
+// HTTP counts requests and their latency by method, route template and
+// status.
+type HTTP struct {
+	requests *CounterVec
+	duration *HistogramVec
+}
+
+// NewHTTP returns an HTTP registered in r.
+func NewHTTP(r *Registry) *HTTP {
+	h := &HTTP{
+		requests: NewCounterVec("http_requests_total", "HTTP requests answered.", "method", "route", "status"),
+		duration: NewHistogramVec("http_request_duration_seconds", "Time taken to answer HTTP requests.", DefaultBuckets, "method", "route", "status"),
+	}
+	r.Register(h.requests, h.duration)
+	return h
+}
+
+// Observe records a request to route, the template it matched, answered with
+// status after d.
+func (h *HTTP) Observe(method, route string, status int, d time.Duration) {
+	s := strconv.Itoa(status)
+	h.requests.Inc(method, route, s)
+	h.duration.Observe(d.Seconds(), method, route, s)
+}
+
+// Operations records the latency and errors of repository operations.
+type Operations struct {
+	duration *HistogramVec
+	errors   *CounterVec
+}
+
+// NewOperations returns an Operations registered in r.
+func NewOperations(r *Registry) *Operations {
+	o := &Operations{
+		duration: NewHistogramVec("repository_operation_duration_seconds", "Time taken by repository operations.", DefaultBuckets, "repository", "operation"),
+		errors:   NewCounterVec("repository_operation_errors_total", "Repository operations that failed, by error kind.", "repository", "operation", "kind"),
+	}
+	r.Register(o.duration, o.errors)
+	return o
+}
+
+// Observe records operation of repository, started at start, which failed
+// with err unless it is nil.
+func (o *Operations) Observe(repository, operation string, start time.Time, err error) {
+	o.duration.Observe(time.Since(start).Seconds(), repository, operation)
+	if err != nil {
+		o.errors.Inc(repository, operation, errorKind(err))
+	}
+}
+
+// errorKind names the domain kind of err, "other" when it has none.
+func errorKind(err error) string {
+	for _, k := range []struct {
+		kind error
+		name string
+	}{
+		{domain.ErrNotFound, "not_found"},
+		{domain.ErrConflict, "conflict"},
+		{domain.ErrValidation, "validation"},
+		{domain.ErrPrecondition, "precondition"},
+		{domain.ErrUnavailable, "unavailable"},
+	} {
+		if errors.Is(err, k.kind) {
+			return k.name
+		}
+	}
+	return "other"
+}
+
+// CacheStats exposes the hits and misses of the repository cache.
+type CacheStats struct {
+	cache *cache.Cache
+}
+
+func NewCacheStats(c *cache.Cache) *CacheStats {
+	return &CacheStats{cache: c}
+}
+
+func (s *CacheStats) Collect(ctx context.Context) []Family {
+	st := s.cache.Stats()
+	counter := func(name, help string, v float64) Family {
+		return Family{Name: name, Help: help, Type: "counter", Samples: []Sample{{Name: name, Value: v}}}
+	}
+	return []Family{
+		counter("repository_cache_hits_total", "Repository lookups answered from the cache.", float64(st.Hits)),
+		counter("repository_cache_misses_total", "Repository lookups that went to the database.", float64(st.Misses)),
+	}
+}
+
+// DBStats exposes the connection pool statistics of a sql.DB.
+type DBStats struct {
+	db *sql.DB
+}
+
+func NewDBStats(db *sql.DB) *DBStats {
+	return &DBStats{db: db}
+}
+
+func (d *DBStats) Collect(ctx context.Context) []Family {
+	st := d.db.Stats()
+	gauge := func(name, help string, v float64) Family {
+		return Family{Name: name, Help: help, Type: "gauge", Samples: []Sample{{Name: name, Value: v}}}
+	}
+	counter := func(name, help string, v float64) Family {
+		return Family{Name: name, Help: help, Type: "counter", Samples: []Sample{{Name: name, Value: v}}}
+	}
+	return []Family{
+		gauge("db_max_open_connections", "Maximum open connections to the database.", float64(st.MaxOpenConnections)),
+		gauge("db_open_connections", "Established connections, in use or idle.", float64(st.OpenConnections)),
+		gauge("db_in_use_connections", "Connections currently in use.", float64(st.InUse)),
+		gauge("db_idle_connections", "Idle connections.", float64(st.Idle)),
+		counter("db_wait_count_total", "Connections waited for.", float64(st.WaitCount)),
+		counter("db_wait_duration_seconds_total", "Time blocked waiting for a connection.", st.WaitDuration.Seconds()),
+		counter("db_max_idle_closed_total", "Connections closed due to the idle connections limit.", float64(st.MaxIdleClosed)),
+		counter("db_max_idle_time_closed_total", "Connections closed due to the idle time limit.", float64(st.MaxIdleTimeClosed)),
+		counter("db_max_lifetime_closed_total", "Connections closed due to the connection lifetime limit.", float64(st.MaxLifetimeClosed)),
+	}
+}
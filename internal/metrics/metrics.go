@@ -0,0 +1,258 @@
+// Package metrics keeps counters, histograms and gauges and writes them in the
+// Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the media type of what Registry.Write produces.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are the upper bounds, in seconds, of latency histograms.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Family is a named metric and its samples, as exposed to Prometheus.
+type Family struct {
+	Name    string
+	Help    string
+	Type    string // counter, gauge or histogram
+	Samples []Sample
+}
+
+// Sample is one value of a family. Name is the family name, plus _bucket,
+// _sum or _count for histograms.
+type Sample struct {
+	Name   string
+	Labels []Label
+	Value  float64
+}
+
+type Label struct {
+	Name, Value string
+}
+
+// Collector produces families when the registry is scraped.
+type Collector interface {
+	Collect(ctx context.Context) []Family
+}
+
+// Registry is the set of collectors a scrape exposes, in registration order.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []Collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) Register(cs ...Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, cs...)
+}
+
+// Write collects every family and writes it to w in the text format.
+func (r *Registry) Write(ctx context.Context, w io.Writer) error {
+	r.mu.Lock()
+	collectors := append([]Collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		for _, f := range c.Collect(ctx) {
+			writeFamily(bw, f)
+		}
+	}
+	return bw.Flush()
+}
+
+func writeFamily(w *bufio.Writer, f Family) {
+	w.WriteString("# HELP " + f.Name + " " + helpEscaper.Replace(f.Help) + "\n")
+	w.WriteString("# TYPE " + f.Name + " " + f.Type + "\n")
+	for _, s := range f.Samples {
+		w.WriteString(s.Name)
+		if len(s.Labels) > 0 {
+			w.WriteByte('{')
+			for i, l := range s.Labels {
+				if i > 0 {
+					w.WriteByte(',')
+				}
+				w.WriteString(l.Name + `="` + labelEscaper.Replace(l.Value) + `"`)
+			}
+			w.WriteByte('}')
+		}
+		w.WriteString(" " + formatValue(s.Value) + "\n")
+	}
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func formatValue(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// vec holds one value per combination of label values.
+type vec struct {
+	labels []string
+	mu     sync.Mutex
+	series map[string]interface{}
+}
+
+func newVec(labels []string) vec {
+	return vec{labels: labels, series: make(map[string]interface{})}
+}
+
+// get returns the value of values, creating it with create when missing.
+// It must be called with mu held.
+func (v *vec) get(values []string, create func() interface{}) interface{} {
+	if len(values) != len(v.labels) {
+		panic("metrics: wrong number of label values")
+	}
+	key := strings.Join(values, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = create()
+		v.series[key] = s
+	}
+	return s
+}
+
+// each calls fn with the label pairs and value of every series, sorted by
+// label values so scrapes are stable.
+func (v *vec) each(fn func(labels []Label, s interface{})) {
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var labels []Label
+		if len(v.labels) > 0 {
+			values := strings.Split(k, "\xff")
+			labels = make([]Label, len(v.labels))
+			for i, name := range v.labels {
+				labels[i] = Label{name, values[i]}
+			}
+		}
+		fn(labels, v.series[k])
+	}
+}
+
+// CounterVec is a counter per combination of label values.
+type CounterVec struct {
+	name, help string
+	vec
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{name: name, help: help, vec: newVec(labels)}
+}
+
+// Inc adds 1 to the counter of the label values, given in label order.
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+func (c *CounterVec) Add(n float64, values ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*c.get(values, func() interface{} { return new(float64) }).(*float64) += n
+}
+
+func (c *CounterVec) Collect(ctx context.Context) []Family {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f := Family{Name: c.name, Help: c.help, Type: "counter"}
+	c.each(func(labels []Label, s interface{}) {
+		f.Samples = append(f.Samples, Sample{Name: c.name, Labels: labels, Value: *s.(*float64)})
+	})
+	return []Family{f}
+}
+
+// HistogramVec is a histogram per combination of label values.
+type HistogramVec struct {
+	name, help string
+	buckets    []float64
+	vec
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// NewHistogramVec returns a histogram with the given sorted bucket upper
+// bounds; +Inf is implied.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{name: name, help: help, buckets: buckets, vec: newVec(labels)}
+}
+
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.get(values, func() interface{} { return &histogram{counts: make([]uint64, len(h.buckets))} }).(*histogram)
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *HistogramVec) Collect(ctx context.Context) []Family {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	f := Family{Name: h.name, Help: h.help, Type: "histogram"}
+	h.each(func(labels []Label, s interface{}) {
+		hs := s.(*histogram)
+		var cumulative uint64
+		for i, le := range h.buckets {
+			cumulative += hs.counts[i]
+			f.Samples = append(f.Samples, Sample{h.name + "_bucket", withLe(labels, le), float64(cumulative)})
+		}
+		f.Samples = append(f.Samples,
+			Sample{h.name + "_bucket", withLe(labels, math.Inf(1)), float64(hs.count)},
+			Sample{h.name + "_sum", labels, hs.sum},
+			Sample{h.name + "_count", labels, float64(hs.count)},
+		)
+	})
+	return []Family{f}
+}
+
+func withLe(labels []Label, le float64) []Label {
+	return append(append([]Label(nil), labels...), Label{"le", formatValue(le)})
+}
+
+// GaugeFunc is a gauge whose value is read when the registry is scraped. It
+// is left out of the scrape when fn fails.
+type GaugeFunc struct {
+	name, help string
+	fn         func(ctx context.Context) (float64, error)
+}
+
+func NewGaugeFunc(name, help string, fn func(ctx context.Context) (float64, error)) *GaugeFunc {
+	return &GaugeFunc{name: name, help: help, fn: fn}
+}
+
+func (g *GaugeFunc) Collect(ctx context.Context) []Family {
+	v, err := g.fn(ctx)
+	if err != nil {
+		return nil
+	}
+	return []Family{{Name: g.name, Help: g.help, Type: "gauge", Samples: []Sample{{Name: g.name, Value: v}}}}
+}
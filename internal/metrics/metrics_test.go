@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"Sellers/internal/cache"
+	"Sellers/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(t *testing.T, r *Registry) string {
+	var buf bytes.Buffer
+	assert.NoError(t, r.Write(context.Background(), &buf))
+	return buf.String()
+}
+
+func TestCounterVec(t *testing.T) {
+	r := NewRegistry()
+	c := NewCounterVec("requests_total", "Requests.", "path")
+	r.Register(c)
+	c.Inc("/b")
+	c.Inc("/a")
+	c.Add(2, `/"quoted"`)
+	c.Inc("/a")
+
+	assert.Equal(t, `# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{path="/\"quoted\""} 2
+requests_total{path="/a"} 2
+requests_total{path="/b"} 1
+`, scrape(t, r))
+}
+
+func TestHistogramVec(t *testing.T) {
+	r := NewRegistry()
+	h := NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1}, "op")
+	r.Register(h)
+	h.Observe(0.05, "get")
+	h.Observe(0.1, "get")
+	h.Observe(3, "get")
+
+	assert.Equal(t, `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{op="get",le="0.1"} 2
+latency_seconds_bucket{op="get",le="1"} 2
+latency_seconds_bucket{op="get",le="+Inf"} 3
+latency_seconds_sum{op="get"} 3.15
+latency_seconds_count{op="get"} 3
+`, scrape(t, r))
+}
+
+func TestGaugeFuncSkippedOnError(t *testing.T) {
+	r := NewRegistry()
+	r.Register(
+		NewGaugeFunc("sellers", "Sellers.", func(ctx context.Context) (float64, error) { return 42, nil }),
+		NewGaugeFunc("localities", "Localities.", func(ctx context.Context) (float64, error) { return 0, errors.New("down") }),
+	)
+
+	assert.Equal(t, "# HELP sellers Sellers.\n# TYPE sellers gauge\nsellers 42\n", scrape(t, r))
+}
+
+func TestOperationsCountErrorsByKind(t *testing.T) {
+	r := NewRegistry()
+	o := NewOperations(r)
+	start := time.Now()
+	o.Observe("seller", "get", start, nil)
+	o.Observe("seller", "get", start, domain.NewError(domain.ErrNotFound, "seller_not_found", "seller not found"))
+	o.Observe("seller", "save", start, errors.New("driver: bad connection"))
+
+	out := scrape(t, r)
+	assert.Contains(t, out, `repository_operation_duration_seconds_count{repository="seller",operation="get"} 2`)
+	assert.Contains(t, out, `repository_operation_errors_total{repository="seller",operation="get",kind="not_found"} 1`)
+	assert.Contains(t, out, `repository_operation_errors_total{repository="seller",operation="save",kind="other"} 1`)
+}
+
+func TestDBStats(t *testing.T) {
+	db, _, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(7)
+
+	r := NewRegistry()
+	r.Register(NewDBStats(db))
+
+	out := scrape(t, r)
+	assert.Contains(t, out, "# TYPE db_max_open_connections gauge\ndb_max_open_connections 7\n")
+	assert.Contains(t, out, "# TYPE db_wait_count_total counter\n")
+}
+
+func TestCacheStats(t *testing.T) {
+	c := cache.New(cache.NewLRU(10, time.Minute))
+	c.Set("a", 1)
+	c.Get("a")
+	c.Get("a")
+	c.Get("b")
+	r := NewRegistry()
+	r.Register(NewCacheStats(c))
+
+	out := scrape(t, r)
+	assert.Contains(t, out, "# TYPE repository_cache_hits_total counter\nrepository_cache_hits_total 2\n")
+	assert.Contains(t, out, "repository_cache_misses_total 1\n")
+}
@@ -0,0 +1,340 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// Errors
+var (
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+	ErrUnknownVersion   = errors.New("database has a migration unknown to this binary")
+	ErrNoMigration      = errors.New("no migration to roll back")
+)
+
+// Migration is one versioned schema change with its rollback.
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Status describes a known migration and whether it has been applied.
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+type applied struct {
+	version   int
+	checksum  string
+	appliedAt time.Time
+}
+
+// Migrator applies and rolls back migrations, tracking them in schema_migrations.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator returns a Migrator for the migrations embedded in the binary.
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	sub, err := fs.Sub(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+	ms, err := Load(sub)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: ms}, nil
+}
+
+// Load reads NNNN_name.up.sql / NNNN_name.down.sql pairs from fsys, sorted by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		name := e.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		parts := strings.SplitN(base, "_", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("migration %s: name must be NNNN_description", name)
+		}
+		version, err := strconv.Atoi(parts[0])
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version %q", name, parts[0])
+		}
+
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: parts[1]}
+			byVersion[version] = m
+		} else if m.Name != parts[1] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, parts[1])
+		}
+		if direction == "up" {
+			m.Up = string(b)
+			sum := sha256.Sum256(b)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(b)
+		}
+	}
+
+	ms := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		if m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s has no down script", m.Version, m.Name)
+		}
+		ms = append(ms, *m)
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Version < ms[j].Version })
+	return ms, nil
+}
+
+// Migrations returns the known migrations in version order.
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	query := `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    checksum CHAR(64) NOT NULL,
+    applied_at DATETIME NOT NULL,
+    PRIMARY KEY (version)
+)`
+	_, err := m.db.ExecContext(ctx, query)
+	return err
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]applied, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	return m.read(ctx)
+}
+
+// read returns the applied migrations by version.
+func (m *Migrator) read(ctx context.Context) (map[int]applied, error) {
+	rows, err := m.db.QueryContext(ctx, "SELECT version, checksum, applied_at FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := map[int]applied{}
+	for rows.Next() {
+		var a applied
+		if err := rows.Scan(&a.version, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		done[a.version] = a
+	}
+	return done, rows.Err()
+}
+
+// verify checks that every applied migration is still known and unchanged.
+func (m *Migrator) verify(done map[int]applied) error {
+	known := make(map[int]Migration, len(m.migrations))
+	for _, mg := range m.migrations {
+		known[mg.Version] = mg
+	}
+	for v, a := range done {
+		mg, ok := known[v]
+		if !ok {
+			return fmt.Errorf("%w: version %d", ErrUnknownVersion, v)
+		}
+		if mg.Checksum != a.checksum {
+			return fmt.Errorf("%w: %s was modified after being applied", ErrChecksumMismatch, mg)
+		}
+	}
+	return nil
+}
+
+// Up applies every pending migration in order and returns the ones it ran.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	done, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(done); err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, mg := range m.migrations {
+		if _, ok := done[mg.Version]; ok {
+			continue
+		}
+		if err := m.exec(ctx, mg.Up); err != nil {
+			return ran, fmt.Errorf("applying %s: %w", mg, err)
+		}
+		query := "INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)"
+		if _, err := m.db.ExecContext(ctx, query, mg.Version, mg.Name, mg.Checksum, time.Now().UTC()); err != nil {
+			return ran, fmt.Errorf("recording %s: %w", mg, err)
+		}
+		ran = append(ran, mg)
+	}
+	return ran, nil
+}
+
+// Down rolls back the given number of most recently applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	done, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(done); err != nil {
+		return nil, err
+	}
+
+	var rolled []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(rolled) < steps; i-- {
+		mg := m.migrations[i]
+		if _, ok := done[mg.Version]; !ok {
+			continue
+		}
+		if err := m.exec(ctx, mg.Down); err != nil {
+			return rolled, fmt.Errorf("rolling back %s: %w", mg, err)
+		}
+		if _, err := m.db.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version=?", mg.Version); err != nil {
+			return rolled, fmt.Errorf("unrecording %s: %w", mg, err)
+		}
+		rolled = append(rolled, mg)
+	}
+	if len(rolled) == 0 {
+		return nil, ErrNoMigration
+	}
+	return rolled, nil
+}
+
+// Status lists every known migration with the time it was applied, if any.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	done, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(done); err != nil {
+		return nil, err
+	}
+
+	st := make([]Status, 0, len(m.migrations))
+	for _, mg := range m.migrations {
+		s := Status{Version: mg.Version, Name: mg.Name}
+		if a, ok := done[mg.Version]; ok {
+			t := a.appliedAt
+			s.AppliedAt = &t
+		}
+		st = append(st, s)
+	}
+	return st, nil
+}
+
+// Pending returns the known migrations not applied yet, in version order.
+// Unlike Status it only reads, so it can back a readiness check; a database
+// without schema_migrations has every migration pending.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	done, err := m.read(ctx)
+	var me *mysql.MySQLError
+	if errors.As(err, &me) && me.Number == errNoSuchTable {
+		done, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(done); err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, mg := range m.migrations {
+		if _, ok := done[mg.Version]; !ok {
+			pending = append(pending, mg)
+		}
+	}
+	return pending, nil
+}
+
+// errNoSuchTable is the MySQL error number of a missing table.
+const errNoSuchTable = 1146
+
+// exec runs each statement of a script; MySQL does not accept several
+// statements in one Exec unless multiStatements is enabled in the DSN.
+func (m *Migrator) exec(ctx context.Context, script string) error {
+	for _, stmt := range statements(script) {
+		if _, err := m.db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statements splits a script on semicolons that end a line.
+func statements(script string) []string {
+	var out []string
+	var cur strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		cur.WriteString(line)
+		cur.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			out = append(out, strings.TrimSuffix(strings.TrimSpace(cur.String()), ";"))
+			cur.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(cur.String()); rest != "" {
+		out = append(out, rest)
+	}
+	return out
+}
+
+// String returns the file name stem of a migration, e.g. 0002_create_sellers.
+func (mg Migration) String() string {
+	return fmt.Sprintf("%04d_%s", mg.Version, mg.Name)
+}
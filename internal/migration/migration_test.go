@@ -0,0 +1,168 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"0002_second.up.sql":   {Data: []byte("CREATE TABLE b (id INT);\nCREATE INDEX ib ON b (id);\n")},
+		"0002_second.down.sql": {Data: []byte("DROP TABLE b;\n")},
+		"0001_first.up.sql":    {Data: []byte("CREATE TABLE a (id INT);\n")},
+		"0001_first.down.sql":  {Data: []byte("DROP TABLE a;\n")},
+		"README.md":            {Data: []byte("ignored")},
+	}
+}
+
+func newTestMigrator(t *testing.T) (*Migrator, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+	ms, err := Load(testFS())
+	assert.Nil(t, err)
+	return &Migrator{db: db, migrations: ms}, mock, func() { db.Close() }
+}
+
+func TestLoadSortsByVersion(t *testing.T) {
+	ms, err := Load(testFS())
+
+	assert.NoError(t, err)
+	assert.Len(t, ms, 2)
+	assert.Equal(t, 1, ms[0].Version)
+	assert.Equal(t, "first", ms[0].Name)
+	assert.Equal(t, "0002_second", ms[1].String())
+	assert.Len(t, ms[0].Checksum, 64)
+}
+
+func TestLoadMissingDown(t *testing.T) {
+	fsys := testFS()
+	delete(fsys, "0002_second.down.sql")
+
+	_, err := Load(fsys)
+
+	assert.Error(t, err)
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	m, err := NewMigrator(nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "0001_create_localities", m.Migrations()[0].String())
+	assert.Equal(t, "0002_create_sellers", m.Migrations()[1].String())
+	assert.Contains(t, m.Migrations()[1].Up, "REFERENCES localities (id)")
+}
+
+func TestStatements(t *testing.T) {
+	stmts := statements("-- comment\nCREATE TABLE a (\n  id INT\n);\n\nDROP TABLE b;\n")
+
+	assert.Equal(t, []string{"CREATE TABLE a (\n  id INT\n)", "DROP TABLE b"}, stmts)
+}
+
+func TestUpAppliesPending(t *testing.T) {
+	m, mock, done := newTestMigrator(t)
+	defer done()
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, checksum, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum", "applied_at"}).
+			AddRow(1, m.migrations[0].Checksum, time.Now()))
+	mock.ExpectExec("CREATE TABLE b").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE INDEX ib").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").
+		WithArgs(2, "second", m.migrations[1].Checksum, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ran, err := m.Up(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, ran, 1)
+	assert.Equal(t, 2, ran[0].Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpChecksumMismatch(t *testing.T) {
+	m, mock, done := newTestMigrator(t)
+	defer done()
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, checksum, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum", "applied_at"}).
+			AddRow(1, "tampered", time.Now()))
+
+	ran, err := m.Up(context.Background())
+
+	assert.True(t, errors.Is(err, ErrChecksumMismatch))
+	assert.Empty(t, ran)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDownRollsBackLatest(t *testing.T) {
+	m, mock, done := newTestMigrator(t)
+	defer done()
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, checksum, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum", "applied_at"}).
+			AddRow(1, m.migrations[0].Checksum, time.Now()).
+			AddRow(2, m.migrations[1].Checksum, time.Now()))
+	mock.ExpectExec("DROP TABLE b").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM schema_migrations WHERE version=\\?").WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rolled, err := m.Down(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Len(t, rolled, 1)
+	assert.Equal(t, 2, rolled[0].Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStatusReportsPending(t *testing.T) {
+	m, mock, done := newTestMigrator(t)
+	defer done()
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, checksum, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum", "applied_at"}).
+			AddRow(1, m.migrations[0].Checksum, time.Now()))
+
+	st, err := m.Status(context.Background())
+
+	assert.NoError(t, err)
+	assert.NotNil(t, st[0].AppliedAt)
+	assert.Nil(t, st[1].AppliedAt)
+}
+
+func TestPendingOnlyReads(t *testing.T) {
+	m, mock, done := newTestMigrator(t)
+	defer done()
+
+	mock.ExpectQuery("SELECT version, checksum, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum", "applied_at"}).
+			AddRow(1, m.migrations[0].Checksum, time.Now()))
+
+	pending, err := m.Pending(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Migration{m.migrations[1]}, pending)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPendingWithoutMigrationsTable(t *testing.T) {
+	m, mock, done := newTestMigrator(t)
+	defer done()
+
+	mock.ExpectQuery("SELECT version, checksum, applied_at FROM schema_migrations").
+		WillReturnError(&mysql.MySQLError{Number: 1146, Message: "Table 'meli.schema_migrations' doesn't exist"})
+
+	pending, err := m.Pending(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, pending, 2)
+}
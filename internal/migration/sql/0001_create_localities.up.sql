@@ -0,0 +1,9 @@
+CREATE TABLE localities (
+    id INT NOT NULL AUTO_INCREMENT,
+    zip_code VARCHAR(16) NOT NULL,
+    locality_name VARCHAR(128) NOT NULL,
+    province_name VARCHAR(128) NOT NULL,
+    country_name VARCHAR(128) NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY uq_localities_zip_code (zip_code)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,12 @@
+CREATE TABLE sellers (
+    id INT NOT NULL AUTO_INCREMENT,
+    cid INT NOT NULL,
+    company_name VARCHAR(255) NOT NULL,
+    address VARCHAR(255) NOT NULL,
+    telephone VARCHAR(32) NOT NULL,
+    localities_id INT NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY uq_sellers_cid (cid),
+    KEY idx_sellers_localities_id (localities_id),
+    CONSTRAINT fk_sellers_localities FOREIGN KEY (localities_id) REFERENCES localities (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
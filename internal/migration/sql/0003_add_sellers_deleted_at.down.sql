@@ -0,0 +1,3 @@
+ALTER TABLE sellers
+    DROP KEY idx_sellers_deleted_at,
+    DROP COLUMN deleted_at;
@@ -0,0 +1,3 @@
+ALTER TABLE sellers
+    ADD COLUMN deleted_at DATETIME NULL,
+    ADD KEY idx_sellers_deleted_at (deleted_at);
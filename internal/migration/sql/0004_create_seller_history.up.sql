@@ -0,0 +1,12 @@
+CREATE TABLE seller_history (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    seller_id INT NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    before_data JSON NULL,
+    after_data JSON NULL,
+    actor VARCHAR(255) NOT NULL,
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    created_at DATETIME(6) NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_seller_history_seller_id (seller_id, id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
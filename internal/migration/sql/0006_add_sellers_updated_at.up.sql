@@ -0,0 +1,2 @@
+ALTER TABLE sellers
+    ADD COLUMN updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP;
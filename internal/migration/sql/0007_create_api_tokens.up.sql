@@ -0,0 +1,10 @@
+CREATE TABLE api_tokens (
+    id INT NOT NULL AUTO_INCREMENT,
+    name VARCHAR(64) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    created_at DATETIME NOT NULL,
+    revoked_at DATETIME NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY uq_api_tokens_token_hash (token_hash),
+    KEY idx_api_tokens_name (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
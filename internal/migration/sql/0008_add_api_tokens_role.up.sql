@@ -0,0 +1,4 @@
+ALTER TABLE api_tokens
+    ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'admin' AFTER name;
+ALTER TABLE api_tokens
+    ALTER COLUMN role SET DEFAULT 'reader';
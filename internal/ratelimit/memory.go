@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory is a Store keeping its buckets in process memory. Buckets that have
+// refilled are dropped every sweepEvery so idle clients do not pile up.
+type Memory struct {
+	mu sync.Mutex
+	// full holds, per key, when its bucket is full again. Every request taken
+	// pushes it one interval later; the bucket is empty once it is a whole
+	// Per away.
+	full      map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+const sweepEvery = time.Minute
+
+// NewMemory returns an empty Memory store.
+func NewMemory() *Memory {
+	return &Memory{full: make(map[string]time.Time), now: time.Now}
+}
+
+func (m *Memory) Take(ctx context.Context, key string, l Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	full := m.full[key]
+	if full.Before(now) {
+		full = now
+	}
+	next := full.Add(l.interval())
+	if next.Sub(now) > l.Per {
+		return Result{
+			Limit:      l.Requests,
+			Reset:      full.Sub(now),
+			RetryAfter: next.Sub(now) - l.Per,
+		}, nil
+	}
+
+	m.full[key] = next
+	return Result{
+		Allowed:   true,
+		Limit:     l.Requests,
+		Remaining: int((l.Per - next.Sub(now)) / l.interval()),
+		Reset:     next.Sub(now),
+	}, nil
+}
+
+// Len returns the number of buckets kept.
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.full)
+}
+
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepEvery {
+		return
+	}
+	m.lastSweep = now
+	for key, full := range m.full {
+		if !full.After(now) {
+			delete(m.full, key)
+		}
+	}
+}
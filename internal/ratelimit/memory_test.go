@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestMemory(now *time.Time) *Memory {
+	m := NewMemory()
+	m.now = func() time.Time { return *now }
+	return m
+}
+
+func TestMemoryAllowsBurstThenRejects(t *testing.T) {
+	now := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	m := newTestMemory(&now)
+	l := Limit{Requests: 3, Per: 3 * time.Second}
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		res, err := m.Take(context.Background(), "ana", l)
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, remaining, res.Remaining)
+	}
+
+	res, err := m.Take(context.Background(), "ana", l)
+	assert.NoError(t, err)
+	assert.Equal(t, Result{Limit: 3, Reset: 3 * time.Second, RetryAfter: time.Second}, res)
+}
+
+func TestMemoryRefills(t *testing.T) {
+	now := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	m := newTestMemory(&now)
+	l := Limit{Requests: 2, Per: time.Minute}
+	m.Take(context.Background(), "ana", l)
+	m.Take(context.Background(), "ana", l)
+
+	now = now.Add(30 * time.Second)
+	res, _ := m.Take(context.Background(), "ana", l)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, time.Minute, res.Reset)
+
+	res, _ = m.Take(context.Background(), "ana", l)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 30*time.Second, res.RetryAfter)
+}
+
+func TestMemoryKeysAreIndependent(t *testing.T) {
+	now := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	m := newTestMemory(&now)
+	l := Limit{Requests: 1, Per: time.Minute}
+
+	res, _ := m.Take(context.Background(), "ana", l)
+	assert.True(t, res.Allowed)
+	res, _ = m.Take(context.Background(), "eli", l)
+	assert.True(t, res.Allowed)
+	res, _ = m.Take(context.Background(), "ana", l)
+	assert.False(t, res.Allowed)
+}
+
+func TestMemorySweepsFullBuckets(t *testing.T) {
+	now := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	m := newTestMemory(&now)
+	l := Limit{Requests: 10, Per: time.Second}
+	m.Take(context.Background(), "ana", l)
+	m.Take(context.Background(), "eli", l)
+	assert.Equal(t, 2, m.Len())
+
+	now = now.Add(sweepEvery)
+	m.Take(context.Background(), "rui", l)
+	assert.Equal(t, 1, m.Len())
+}
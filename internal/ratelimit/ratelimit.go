@@ -0,0 +1,43 @@
+// Package ratelimit holds the token buckets that limit how often each client
+// may call the API.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit lets a client make Requests requests every Per. Requests is also the
+// burst: a client that has been idle for Per may spend them all at once.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// interval is the time it takes to earn back one request, at least 1ns.
+func (l Limit) interval() time.Duration {
+	if i := l.Per / time.Duration(l.Requests); i > 0 {
+		return i
+	}
+	return 1
+}
+
+// Result is the outcome of taking a request from a bucket.
+type Result struct {
+	Allowed bool
+	// Limit is the size of the bucket and Remaining the requests left in it.
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request is allowed; 0 when
+	// Allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps a token bucket per key. Memory keeps them in process; a store
+// shared between instances can be plugged in by implementing Store.
+type Store interface {
+	// Take spends one request of the bucket of key, which holds l.
+	Take(ctx context.Context, key string, l Limit) (Result, error)
+}
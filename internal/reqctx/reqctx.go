@@ -0,0 +1,39 @@
+// Package reqctx carries request-scoped values, such as who made the request
+// and its ID, from the HTTP layer down to services and repositories.
+package reqctx
+
+import "context"
+
+type key int
+
+const (
+	actorKey key = iota
+	requestIDKey
+)
+
+// Anonymous is the actor of requests without an identity.
+const Anonymous = "anonymous"
+
+// WithActor returns a copy of ctx carrying the identity making the request.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// Actor returns the identity carried by ctx, or Anonymous.
+func Actor(ctx context.Context) string {
+	if a, ok := ctx.Value(actorKey).(string); ok && a != "" {
+		return a
+	}
+	return Anonymous
+}
+
+// WithRequestID returns a copy of ctx carrying the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request ID carried by ctx, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
@@ -0,0 +1,117 @@
+package seller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"Sellers/internal/domain"
+	"Sellers/internal/i18n"
+	"Sellers/internal/validation"
+)
+
+// MaxBatchSize bounds how many sellers SaveBatch accepts at once.
+const MaxBatchSize = 1000
+
+var (
+	ErrBatchEmpty    = domain.NewError(domain.ErrValidation, "batch_empty", "the batch has no sellers")
+	ErrBatchTooLarge = domain.NewError(domain.ErrValidation, "batch_too_large", "the batch has too many sellers")
+)
+
+// BatchCIDExistsError is returned when a seller of a batch has a CID that is
+// already stored. Index is its position in the batch.
+type BatchCIDExistsError struct {
+	Index int
+	CID   int
+}
+
+func (e *BatchCIDExistsError) Error() string {
+	return fmt.Sprintf("seller %d of the batch: cid %d already exists", e.Index, e.CID)
+}
+
+// Is classifies the error as a conflict.
+func (e *BatchCIDExistsError) Is(target error) bool {
+	return target == domain.ErrConflict
+}
+
+// Code returns the stable code of the error.
+func (e *BatchCIDExistsError) Code() string { return "seller_batch_cid_exists" }
+
+// Args returns the arguments of the localized message.
+func (e *BatchCIDExistsError) Args() []interface{} { return []interface{}{e.Index, e.CID} }
+
+// SaveBatch creates sellers in a single transaction, all of them or none. The
+// whole batch is validated first, including CIDs repeated inside it, and
+// every invalid field is reported with its index, e.g. "[2].cid", as is a
+// locality that does not exist, e.g. "[1].localities_id". The stored
+// sellers are returned in the same order, with their IDs.
+func (s *service) SaveBatch(ctx context.Context, sellers []domain.Seller) ([]domain.Seller, error) {
+	if len(sellers) == 0 {
+		return nil, ErrBatchEmpty
+	}
+	if len(sellers) > MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+
+	var verrs validation.Errors
+	seen := make(map[int]bool, len(sellers))
+	for i, se := range sellers {
+		var errs validation.Errors
+		if err := validation.Struct(se); errors.As(err, &errs) {
+			for _, fe := range errs {
+				verrs = append(verrs, batchField(i, fe))
+			}
+		} else if err != nil {
+			return nil, err
+		}
+
+		if se.CID != 0 && seen[se.CID] {
+			verrs = append(verrs, batchField(i, validation.FieldError{Field: "cid", Rule: "unique", Key: "validation.unique"}))
+		}
+		seen[se.CID] = true
+	}
+	if len(verrs) > 0 {
+		return nil, verrs
+	}
+	// Localities are checked before the transaction begins, see check.
+	for i, se := range sellers {
+		var invalid *InvalidLocalityError
+		if err := s.checkLocality(ctx, se); errors.As(err, &invalid) {
+			verrs = append(verrs, batchField(i, validation.FieldError{
+				Field: "localities_id", Rule: "exists", Param: strconv.Itoa(invalid.LocalitiesId), Key: "validation.exists",
+			}))
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if len(verrs) > 0 {
+		return nil, verrs
+	}
+
+	created := make([]domain.Seller, len(sellers))
+	err := s.repo.WithTx(ctx, func(repo Repository) error {
+		for i, se := range sellers {
+			saved, err := s.save(ctx, repo, se)
+			if errors.Is(err, domain.ErrConflict) {
+				return &BatchCIDExistsError{Index: i, CID: se.CID}
+			}
+			if err != nil {
+				return err
+			}
+			created[i] = saved
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// batchField prefixes fe.Field with the index of its seller in the batch.
+func batchField(i int, fe validation.FieldError) validation.FieldError {
+	fe.Field = fmt.Sprintf("[%d].%s", i, fe.Field)
+	fe.Message = i18n.T(i18n.Default, fe.Key, fe.Field, fe.Param)
+	return fe
+}
@@ -0,0 +1,94 @@
+package seller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"Sellers/internal/domain"
+	"Sellers/internal/locality"
+	"Sellers/internal/logging"
+	"Sellers/internal/validation"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func batch() []domain.Seller {
+	return []domain.Seller{
+		{CID: 1, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "123456", LocalitiesId: 1},
+		{CID: 2, CompanyName: "Globant", Address: "Libertador 1", Telephone: "654321", LocalitiesId: 1},
+	}
+}
+
+func TestSaveBatchOk(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
+	repo.On("Save", mock.Anything, batch()[0]).Return(10, nil)
+	repo.On("Save", mock.Anything, batch()[1]).Return(11, nil)
+	s := NewService(repo, localityFound(), logging.Discard)
+
+	created, err := s.SaveBatch(context.Background(), batch())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 10, created[0].ID)
+	assert.Equal(t, 11, created[1].ID)
+	assert.Equal(t, "Globant", created[1].CompanyName)
+}
+
+func TestSaveBatchRepeatedCID(t *testing.T) {
+	repo := new(repoM)
+	s := NewService(repo, localityFound(), logging.Discard)
+	sellers := batch()
+	sellers[1].CID = 1
+	sellers[1].Telephone = ""
+
+	_, err := s.SaveBatch(context.Background(), sellers)
+
+	assert.True(t, errors.Is(err, domain.ErrValidation))
+	assert.Equal(t, "validation failed: [1].telephone es requerido; [1].cid esta repetido en la peticion", err.Error())
+	repo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestSaveBatchInvalidLocality(t *testing.T) {
+	repo := new(repoM)
+	localities := new(localityRepoM)
+	localities.On("Get", mock.Anything, 1).Return(domain.Locality{ID: 1}, nil)
+	localities.On("Get", mock.Anything, 9).Return(domain.Locality{}, locality.ErrNotFound)
+	s := NewService(repo, localities, logging.Discard)
+	sellers := batch()
+	sellers[1].LocalitiesId = 9
+
+	_, err := s.SaveBatch(context.Background(), sellers)
+
+	var verrs validation.Errors
+	assert.True(t, errors.As(err, &verrs))
+	assert.Len(t, verrs, 1)
+	assert.Equal(t, "[1].localities_id", verrs[0].Field)
+	assert.Equal(t, "exists", verrs[0].Rule)
+	assert.Equal(t, "9", verrs[0].Param)
+	repo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestSaveBatchCIDExists(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Exists", mock.Anything, 1).Return(false, nil)
+	repo.On("Exists", mock.Anything, 2).Return(true, nil)
+	repo.On("Save", mock.Anything, mock.Anything).Return(10, nil)
+	s := NewService(repo, localityFound(), logging.Discard)
+
+	_, err := s.SaveBatch(context.Background(), batch())
+
+	var exists *BatchCIDExistsError
+	assert.True(t, errors.As(err, &exists))
+	assert.Equal(t, BatchCIDExistsError{Index: 1, CID: 2}, *exists)
+	assert.True(t, errors.Is(err, domain.ErrConflict))
+}
+
+func TestSaveBatchEmpty(t *testing.T) {
+	s := NewService(new(repoM), localityFound(), logging.Discard)
+
+	_, err := s.SaveBatch(context.Background(), nil)
+
+	assert.Equal(t, ErrBatchEmpty, err)
+}
@@ -0,0 +1,124 @@
+package seller
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"Sellers/internal/cache"
+	"Sellers/internal/domain"
+)
+
+// cachedRepository decorates a Repository with a cache of Get and Exists.
+// Writes invalidate the entries they make stale. Inside a transaction reads go
+// straight to the database, so they see the transaction's own writes, and the
+// stale keys are invalidated again once it ends, in case a concurrent read
+// cached the old row before the commit.
+type cachedRepository struct {
+	Repository
+	cache *cache.Cache
+	// stale collects the keys invalidated inside a transaction; nil outside.
+	stale *[]string
+}
+
+// NewCachedRepository returns next with its lookups cached in c.
+func NewCachedRepository(next Repository, c *cache.Cache) Repository {
+	return &cachedRepository{Repository: next, cache: c}
+}
+
+func idKey(id int) string   { return "seller:" + strconv.Itoa(id) }
+func cidKey(cid int) string { return "seller:cid:" + strconv.Itoa(cid) }
+
+func (r *cachedRepository) Get(ctx context.Context, id int) (domain.Seller, error) {
+	if r.stale != nil {
+		return r.Repository.Get(ctx, id)
+	}
+	if v, ok := r.cache.Get(idKey(id)); ok {
+		return v.(domain.Seller), nil
+	}
+
+	s, err := r.Repository.Get(ctx, id)
+	if err != nil {
+		return domain.Seller{}, err
+	}
+	r.cache.Set(idKey(id), s)
+	return s, nil
+}
+
+func (r *cachedRepository) Exists(ctx context.Context, cid int) (bool, error) {
+	if r.stale != nil {
+		return r.Repository.Exists(ctx, cid)
+	}
+	if v, ok := r.cache.Get(cidKey(cid)); ok {
+		return v.(bool), nil
+	}
+
+	exists, err := r.Repository.Exists(ctx, cid)
+	if err != nil {
+		return false, err
+	}
+	r.cache.Set(cidKey(cid), exists)
+	return exists, nil
+}
+
+func (r *cachedRepository) Save(ctx context.Context, s domain.Seller) (int, error) {
+	id, err := r.Repository.Save(ctx, s)
+	r.invalidate(cidKey(s.CID))
+	return id, err
+}
+
+// Update also invalidates the CID the seller had before, which is free again
+// if it changed.
+func (r *cachedRepository) Update(ctx context.Context, s domain.Seller) error {
+	keys := []string{idKey(s.ID), cidKey(s.CID)}
+	if old, err := r.Repository.Get(ctx, s.ID); err == nil && old.CID != s.CID {
+		keys = append(keys, cidKey(old.CID))
+	}
+
+	err := r.Repository.Update(ctx, s)
+	r.invalidate(keys...)
+	return err
+}
+
+func (r *cachedRepository) Delete(ctx context.Context, id int, version int) error {
+	err := r.Repository.Delete(ctx, id, version)
+	r.invalidate(idKey(id))
+	return err
+}
+
+func (r *cachedRepository) Restore(ctx context.Context, id int) error {
+	err := r.Repository.Restore(ctx, id)
+	r.invalidate(idKey(id))
+	return err
+}
+
+// Purge frees the CIDs of the sellers it removes, so it clears the cache. The
+// purge command runs in its own process without a cache, though, so a server
+// keeps reporting purged CIDs as taken until their entries expire.
+func (r *cachedRepository) Purge(ctx context.Context, olderThan time.Duration) (int64, error) {
+	n, err := r.Repository.Purge(ctx, olderThan)
+	if n > 0 {
+		r.cache.Clear()
+	}
+	return n, err
+}
+
+func (r *cachedRepository) WithTx(ctx context.Context, fn func(Repository) error) error {
+	if r.stale != nil {
+		return fn(r)
+	}
+
+	var stale []string
+	err := r.Repository.WithTx(ctx, func(tx Repository) error {
+		return fn(&cachedRepository{Repository: tx, cache: r.cache, stale: &stale})
+	})
+	r.cache.Delete(stale...)
+	return err
+}
+
+func (r *cachedRepository) invalidate(keys ...string) {
+	r.cache.Delete(keys...)
+	if r.stale != nil {
+		*r.stale = append(*r.stale, keys...)
+	}
+}
@@ -0,0 +1,125 @@
+package seller
+
+import (
+	"context"
+	"testing"
+
+	"Sellers/internal/cache"
+	"Sellers/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newCached(repo Repository) (Repository, *cache.Cache) {
+	c := cache.New(cache.NewLRU(100, 0))
+	return NewCachedRepository(repo, c), c
+}
+
+func exists(t *testing.T, repo Repository, cid int) bool {
+	t.Helper()
+	ok, err := repo.Exists(context.Background(), cid)
+	assert.NoError(t, err)
+	return ok
+}
+
+func TestCachedGet(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, CID: 123}, nil).Once()
+	cached, c := newCached(repo)
+
+	first, err := cached.Get(context.Background(), 1)
+	assert.NoError(t, err)
+	second, err := cached.Get(context.Background(), 1)
+	assert.NoError(t, err)
+
+	assert.Equal(t, first, second)
+	assert.Equal(t, cache.Stats{Hits: 1, Misses: 1}, c.Stats())
+	repo.AssertExpectations(t)
+}
+
+func TestCachedGetDoesNotCacheErrors(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Get", mock.Anything, 1).Return(domain.Seller{}, ErrNotFound).Twice()
+	cached, _ := newCached(repo)
+
+	_, err := cached.Get(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = cached.Get(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	repo.AssertExpectations(t)
+}
+
+func TestCachedExistsInvalidatedBySave(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Exists", mock.Anything, 123).Return(false, nil).Once()
+	repo.On("Save", mock.Anything, mock.Anything).Return(1, nil)
+	cached, _ := newCached(repo)
+
+	assert.False(t, exists(t, cached, 123))
+	assert.False(t, exists(t, cached, 123))
+	_, err := cached.Save(context.Background(), domain.Seller{CID: 123})
+	assert.NoError(t, err)
+
+	repo.On("Exists", mock.Anything, 123).Return(true, nil).Once()
+	assert.True(t, exists(t, cached, 123))
+	repo.AssertExpectations(t)
+}
+
+func TestCachedExistsDoesNotCacheErrors(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Exists", mock.Anything, 123).Return(false, domain.ErrUnavailable).Once()
+	repo.On("Exists", mock.Anything, 123).Return(true, nil).Once()
+	cached, _ := newCached(repo)
+
+	_, err := cached.Exists(context.Background(), 123)
+	assert.ErrorIs(t, err, domain.ErrUnavailable)
+	assert.True(t, exists(t, cached, 123))
+	assert.True(t, exists(t, cached, 123))
+	repo.AssertExpectations(t)
+}
+
+func TestCachedUpdateInvalidatesOldCID(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Exists", mock.Anything, 123).Return(true, nil).Once()
+	repo.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, CID: 123}, nil).Once()
+	repo.On("Update", mock.Anything, mock.Anything).Return(nil)
+	cached, _ := newCached(repo)
+
+	assert.True(t, exists(t, cached, 123))
+	_, err := cached.Get(context.Background(), 1)
+	assert.NoError(t, err)
+
+	repo.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, CID: 123}, nil).Once()
+	assert.NoError(t, cached.Update(context.Background(), domain.Seller{ID: 1, CID: 456}))
+
+	repo.On("Exists", mock.Anything, 123).Return(false, nil).Once()
+	repo.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, CID: 456}, nil).Once()
+	assert.False(t, exists(t, cached, 123))
+	s, err := cached.Get(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 456, s.CID)
+	repo.AssertExpectations(t)
+}
+
+func TestCachedWithTxReadsThroughAndInvalidates(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, CID: 123}, nil).Twice()
+	repo.On("Delete", mock.Anything, 1, 0).Return(nil)
+	cached, c := newCached(repo)
+
+	_, err := cached.Get(context.Background(), 1)
+	assert.NoError(t, err)
+	err = cached.WithTx(context.Background(), func(tx Repository) error {
+		if _, err := tx.Get(context.Background(), 1); err != nil {
+			return err
+		}
+		return tx.Delete(context.Background(), 1, 0)
+	})
+	assert.NoError(t, err)
+
+	_, ok := c.Get(idKey(1))
+	assert.False(t, ok)
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,45 @@
+package seller
+
+import (
+	"time"
+
+	"Sellers/internal/domain"
+)
+
+// Action is what a Change did to a seller.
+type Action string
+
+const (
+	ActionCreate  Action = "create"
+	ActionUpdate  Action = "update"
+	ActionDelete  Action = "delete"
+	ActionRestore Action = "restore"
+)
+
+// Change is one entry of a seller's audit trail. Before is nil for creations
+// and restorations, After is nil for deletions.
+type Change struct {
+	ID        int64          `json:"id"`
+	SellerID  int            `json:"seller_id"`
+	Action    Action         `json:"action"`
+	Before    *domain.Seller `json:"before"`
+	After     *domain.Seller `json:"after"`
+	Actor     string         `json:"actor"`
+	RequestID string         `json:"request_id,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// HistoryQuery describes one page of a seller's changes, newest first.
+type HistoryQuery struct {
+	SellerID int
+	Limit    int
+	Offset   int
+}
+
+// HistoryPage is one page of a seller's changes. Limit is the page size
+// actually applied.
+type HistoryPage struct {
+	Changes []Change
+	Total   int
+	Limit   int
+}
@@ -0,0 +1,351 @@
+package seller
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"Sellers/internal/domain"
+	"Sellers/internal/validation"
+)
+
+// MaxImportRows bounds how many sellers a single import may carry.
+const MaxImportRows = 5000
+
+// ImportMode decides what happens to the valid rows of an import when some
+// rows fail.
+type ImportMode string
+
+const (
+	// ImportAtomic creates every row or none of them.
+	ImportAtomic ImportMode = "all-or-nothing"
+	// ImportBestEffort creates the valid rows and reports the others.
+	ImportBestEffort ImportMode = "best-effort"
+)
+
+// ImportStatus is the outcome of one imported row.
+type ImportStatus string
+
+const (
+	ImportCreated         ImportStatus = "created"
+	ImportDuplicate       ImportStatus = "skipped_duplicate"
+	ImportInvalidLocality ImportStatus = "invalid_locality"
+	ImportInvalid         ImportStatus = "invalid"
+	ImportRolledBack      ImportStatus = "rolled_back"
+)
+
+var (
+	ErrImportFormat   = domain.NewError(domain.ErrValidation, "import_format", "unsupported import format")
+	ErrImportEmpty    = domain.NewError(domain.ErrValidation, "import_empty", "the import has no rows")
+	ErrImportTooLarge = domain.NewError(domain.ErrValidation, "import_too_large", "the import has too many rows")
+	ErrImportMode     = domain.NewError(domain.ErrValidation, "import_mode", "unknown import mode")
+	ErrImportHeader   = domain.NewError(domain.ErrValidation, "import_header", "the import header is not valid")
+
+	// errImportRejected rolls back an all-or-nothing import with failed rows.
+	errImportRejected = errors.New("import rejected")
+)
+
+// Record is one row read from an import file. Err holds the fields that could
+// not be parsed, if any.
+type Record struct {
+	Line   int
+	Seller domain.Seller
+	Err    validation.Errors
+}
+
+// ImportResult is the outcome of one Record.
+type ImportResult struct {
+	Line   int
+	CID    int
+	Status ImportStatus
+	ID     int
+	Errors validation.Errors
+}
+
+// ImportReport summarises an import. Committed is false when an
+// all-or-nothing import was rolled back.
+type ImportReport struct {
+	Mode      ImportMode
+	Committed bool
+	Created   int
+	Skipped   int
+	Failed    int
+	Rows      []ImportResult
+}
+
+// ParseImportMode returns the mode named s, ImportAtomic when s is empty.
+func ParseImportMode(s string) (ImportMode, error) {
+	switch ImportMode(s) {
+	case "", ImportAtomic:
+		return ImportAtomic, nil
+	case ImportBestEffort:
+		return ImportBestEffort, nil
+	}
+	return "", ErrImportMode
+}
+
+// importFields are the seller fields an import can set, by JSON name.
+var importFields = map[string]bool{
+	"cid":           true,
+	"company_name":  true,
+	"address":       true,
+	"telephone":     true,
+	"localities_id": true,
+}
+
+// ReadCSV reads sellers from CSV with a header row. Columns are matched to
+// seller fields by their JSON name unless mapping renames them (header ->
+// field); unknown columns are ignored. Rows that are not valid CSV are
+// reported as malformed; errors reading r are returned.
+func ReadCSV(r io.Reader, mapping map[string]string) ([]Record, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, ErrImportEmpty
+	}
+	var perr *csv.ParseError
+	if errors.As(err, &perr) {
+		return nil, fmt.Errorf("%w: %v", ErrImportHeader, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]string, len(header))
+	known := 0
+	for i, h := range header {
+		columns[i] = fieldName(h, mapping)
+		if importFields[columns[i]] {
+			known++
+		}
+	}
+	if known == 0 {
+		return nil, ErrImportHeader
+	}
+
+	var records []Record
+	for line := 2; ; line++ {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil && !errors.As(err, &perr) {
+			return nil, err
+		}
+		if len(records) == MaxImportRows {
+			return nil, ErrImportTooLarge
+		}
+		if err != nil {
+			records = append(records, Record{Line: line, Err: validation.Errors{malformed("row")}})
+			continue
+		}
+		fields := make(map[string]string, len(row))
+		for i, v := range row {
+			if i < len(columns) {
+				fields[columns[i]] = v
+			}
+		}
+		records = append(records, newRecord(line, fields))
+	}
+	if len(records) == 0 {
+		return nil, ErrImportEmpty
+	}
+	return records, nil
+}
+
+// ReadNDJSON reads sellers from newline-delimited JSON objects, renaming keys
+// through mapping like ReadCSV. Blank lines are skipped and lines that are not
+// JSON objects are reported as malformed; errors reading r are returned.
+func ReadNDJSON(r io.Reader, mapping map[string]string) ([]Record, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var records []Record
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		if len(records) == MaxImportRows {
+			return nil, ErrImportTooLarge
+		}
+
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(text), &obj); err != nil {
+			records = append(records, Record{Line: line, Err: validation.Errors{malformed("row")}})
+			continue
+		}
+		fields := make(map[string]string, len(obj))
+		for k, raw := range obj {
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				s = string(raw)
+			}
+			fields[fieldName(k, mapping)] = s
+		}
+		records = append(records, newRecord(line, fields))
+	}
+	if err := sc.Err(); errors.Is(err, bufio.ErrTooLong) {
+		return nil, fmt.Errorf("%w: %v", ErrImportFormat, err)
+	} else if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, ErrImportEmpty
+	}
+	return records, nil
+}
+
+func fieldName(name string, mapping map[string]string) string {
+	name = strings.TrimSpace(name)
+	if f, ok := mapping[name]; ok {
+		return f
+	}
+	return strings.ToLower(name)
+}
+
+// newRecord builds a seller from the fields of one row, collecting the
+// numeric fields that do not parse.
+func newRecord(line int, fields map[string]string) Record {
+	rec := Record{Line: line}
+	rec.Seller.CompanyName = strings.TrimSpace(fields["company_name"])
+	rec.Seller.Address = strings.TrimSpace(fields["address"])
+	rec.Seller.Telephone = strings.TrimSpace(fields["telephone"])
+
+	numeric := []struct {
+		name string
+		dst  *int
+	}{
+		{"cid", &rec.Seller.CID},
+		{"localities_id", &rec.Seller.LocalitiesId},
+	}
+	for _, f := range numeric {
+		name, dst := f.name, f.dst
+		v := strings.TrimSpace(fields[name])
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			rec.Err = append(rec.Err, validation.FieldError{
+				Field: name, Rule: "numeric", Key: "validation.numeric",
+				Message: fmt.Sprintf("%s must be numeric", name),
+			})
+			continue
+		}
+		*dst = n
+	}
+	return rec
+}
+
+func malformed(field string) validation.FieldError {
+	return validation.FieldError{Field: field, Rule: "malformed", Key: "validation.malformed", Message: "malformed row"}
+}
+
+// Import saves records in a single transaction, applying the same rules as
+// Save to each of them. Failed rows are reported rather than returned as
+// errors; an error is only returned when the import could not run at all.
+func (s *service) Import(ctx context.Context, records []Record, mode ImportMode) (ImportReport, error) {
+	if len(records) == 0 {
+		return ImportReport{}, ErrImportEmpty
+	}
+	if len(records) > MaxImportRows {
+		return ImportReport{}, ErrImportTooLarge
+	}
+
+	// Rows are checked before the transaction begins, see check.
+	checked := make([]error, len(records))
+	for i, rec := range records {
+		if len(rec.Err) == 0 {
+			checked[i] = s.check(ctx, rec.Seller)
+		}
+	}
+
+	report := ImportReport{Mode: mode, Rows: make([]ImportResult, len(records))}
+	err := s.repo.WithTx(ctx, func(repo Repository) error {
+		for i, rec := range records {
+			res := ImportResult{Line: rec.Line, CID: rec.Seller.CID}
+			if len(rec.Err) > 0 {
+				res.Status, res.Errors = ImportInvalid, rec.Err
+			} else {
+				err := checked[i]
+				var se domain.Seller
+				if err == nil {
+					se, err = s.save(ctx, repo, rec.Seller)
+				}
+				var verrs validation.Errors
+				var invalid *InvalidLocalityError
+				switch {
+				case err == nil:
+					res.Status, res.ID = ImportCreated, se.ID
+				case errors.As(err, &verrs):
+					res.Status, res.Errors = ImportInvalid, verrs
+				case errors.As(err, &invalid):
+					res.Status = ImportInvalidLocality
+					res.Errors = validation.Errors{{
+						Field: "localities_id", Rule: "exists", Param: strconv.Itoa(invalid.LocalitiesId),
+						Key: "validation.exists", Message: invalid.Error(),
+					}}
+				case errors.Is(err, domain.ErrConflict):
+					res.Status = ImportDuplicate
+				case errors.Is(err, domain.ErrValidation):
+					// The database rejected the row, e.g. its locality
+					// was deleted after check accepted it.
+					res.Status = ImportInvalid
+					res.Errors = validation.Errors{{
+						Field: "localities_id", Rule: "exists", Param: strconv.Itoa(rec.Seller.LocalitiesId),
+						Key: "validation.exists", Message: err.Error(),
+					}}
+				default:
+					return err
+				}
+			}
+			report.Rows[i] = res
+		}
+
+		report.tally()
+		if mode == ImportAtomic && report.Skipped+report.Failed > 0 {
+			return errImportRejected
+		}
+		return nil
+	})
+
+	if errors.Is(err, errImportRejected) {
+		for i := range report.Rows {
+			if report.Rows[i].Status == ImportCreated {
+				report.Rows[i].Status, report.Rows[i].ID = ImportRolledBack, 0
+			}
+		}
+		report.Created = 0
+		s.log.Info(ctx, "import rolled back", "mode", mode, "rows", len(records), "skipped", report.Skipped, "failed", report.Failed)
+		return report, nil
+	}
+	if err != nil {
+		return ImportReport{}, err
+	}
+	report.Committed = true
+	s.log.Info(ctx, "import committed", "mode", mode, "rows", len(records), "created", report.Created, "skipped", report.Skipped, "failed", report.Failed)
+	return report, nil
+}
+
+func (r *ImportReport) tally() {
+	r.Created, r.Skipped, r.Failed = 0, 0, 0
+	for _, row := range r.Rows {
+		switch row.Status {
+		case ImportCreated:
+			r.Created++
+		case ImportDuplicate:
+			r.Skipped++
+		default:
+			r.Failed++
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package seller
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"Sellers/internal/domain"
+	"Sellers/internal/locality"
+	"Sellers/internal/logging"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestReadCSVWithMapping(t *testing.T) {
+	body := "CUIT,Razon Social,address,telephone,localities_id,notes\n" +
+		"1,Meli,Bulnes 10,123456,1,first\n" +
+		"abc,Globant,Libertador 1,654321,x,\n"
+
+	records, err := ReadCSV(strings.NewReader(body), map[string]string{"CUIT": "cid", "Razon Social": "company_name"})
+
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, Record{Line: 2, Seller: domain.Seller{CID: 1, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "123456", LocalitiesId: 1}}, records[0])
+	assert.Equal(t, 3, records[1].Line)
+	assert.Len(t, records[1].Err, 2)
+	assert.Equal(t, "cid", records[1].Err[0].Field)
+	assert.Equal(t, "localities_id", records[1].Err[1].Field)
+}
+
+func TestReadCSVWithoutSellerColumns(t *testing.T) {
+	_, err := ReadCSV(strings.NewReader("a,b\n1,2\n"), nil)
+
+	assert.Equal(t, ErrImportHeader, err)
+}
+
+func TestReadReturnsReadErrors(t *testing.T) {
+	broken := errors.New("connection reset")
+	body := func() io.Reader {
+		return io.MultiReader(strings.NewReader("cid,company_name\n1,Meli\n"), iotest.ErrReader(broken))
+	}
+
+	_, err := ReadCSV(body(), nil)
+	assert.Equal(t, broken, err)
+
+	_, err = ReadNDJSON(body(), nil)
+	assert.Equal(t, broken, err)
+}
+
+func TestReadNDJSON(t *testing.T) {
+	body := `{"cid": 1, "company_name": "Meli", "address": "Bulnes 10", "telephone": "123456", "localities_id": 1}` + "\n\n" +
+		`{"cid": 2,` + "\n"
+
+	records, err := ReadNDJSON(strings.NewReader(body), nil)
+
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, domain.Seller{CID: 1, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "123456", LocalitiesId: 1}, records[0].Seller)
+	assert.Equal(t, 3, records[1].Line)
+	assert.Equal(t, "malformed", records[1].Err[0].Rule)
+}
+
+func importRecords() []Record {
+	valid := domain.Seller{CID: 1, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "123456", LocalitiesId: 1}
+	duplicate := valid
+	duplicate.CID = 2
+	badLocality := valid
+	badLocality.CID, badLocality.LocalitiesId = 3, 9
+	return []Record{
+		{Line: 2, Seller: valid},
+		{Line: 3, Seller: duplicate},
+		{Line: 4, Seller: badLocality},
+		{Line: 5, Seller: domain.Seller{CID: 4}},
+	}
+}
+
+func importService() (*repoM, Service) {
+	repo := new(repoM)
+	repo.On("Exists", mock.Anything, 1).Return(false, nil)
+	repo.On("Exists", mock.Anything, 2).Return(true, nil)
+	repo.On("Exists", mock.Anything, 3).Return(false, nil)
+	repo.On("Save", mock.Anything, mock.Anything).Return(10, nil)
+	localities := new(localityRepoM)
+	localities.On("Get", mock.Anything, 1).Return(domain.Locality{ID: 1}, nil)
+	localities.On("Get", mock.Anything, 9).Return(domain.Locality{}, locality.ErrNotFound)
+	return repo, NewService(repo, localities, logging.Discard)
+}
+
+func TestImportBestEffort(t *testing.T) {
+	_, s := importService()
+
+	report, err := s.Import(context.Background(), importRecords(), ImportBestEffort)
+
+	assert.NoError(t, err)
+	assert.True(t, report.Committed)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, 2, report.Failed)
+	assert.Equal(t, ImportResult{Line: 2, CID: 1, Status: ImportCreated, ID: 10}, report.Rows[0])
+	assert.Equal(t, ImportDuplicate, report.Rows[1].Status)
+	assert.Equal(t, ImportInvalidLocality, report.Rows[2].Status)
+	assert.Equal(t, "9", report.Rows[2].Errors[0].Param)
+	assert.Equal(t, ImportInvalid, report.Rows[3].Status)
+}
+
+func TestImportAtomicRollsBack(t *testing.T) {
+	_, s := importService()
+
+	report, err := s.Import(context.Background(), importRecords(), ImportAtomic)
+
+	assert.NoError(t, err)
+	assert.False(t, report.Committed)
+	assert.Equal(t, 0, report.Created)
+	assert.Equal(t, ImportResult{Line: 2, CID: 1, Status: ImportRolledBack}, report.Rows[0])
+}
+
+func TestImportAtomicCommits(t *testing.T) {
+	repo, s := importService()
+
+	report, err := s.Import(context.Background(), importRecords()[:1], ImportAtomic)
+
+	assert.NoError(t, err)
+	assert.True(t, report.Committed)
+	assert.Equal(t, 1, report.Created)
+	repo.AssertNumberOfCalls(t, "Save", 1)
+}
+
+func TestImportChecksLocalitiesBeforeTransaction(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
+	repo.On("Save", mock.Anything, mock.Anything).Return(10, nil)
+	localities := localityOutsideTx(t, repo)
+	s := NewService(repo, localities, logging.Discard)
+	records := importRecords()[:1]
+	records = append(records, Record{Line: 3, Seller: domain.Seller{CID: 3, CompanyName: "Globant", Address: "Libertador 1", Telephone: "654321", LocalitiesId: 1}})
+
+	report, err := s.Import(context.Background(), records, ImportAtomic)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Created)
+	localities.AssertNumberOfCalls(t, "Get", 2)
+}
+
+func TestImportBestEffortReportsRowsTheDatabaseRejects(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Exists", mock.Anything, 1).Return(false, nil)
+	repo.On("Save", mock.Anything, mock.Anything).Return(0, domain.WithKind(domain.ErrValidation, errors.New("Cannot add or update a child row")))
+	localities := new(localityRepoM)
+	localities.On("Get", mock.Anything, 1).Return(domain.Locality{ID: 1}, nil)
+	s := NewService(repo, localities, logging.Discard)
+
+	report, err := s.Import(context.Background(), importRecords()[:1], ImportBestEffort)
+
+	assert.NoError(t, err)
+	assert.True(t, report.Committed)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, ImportInvalid, report.Rows[0].Status)
+	assert.Equal(t, "localities_id", report.Rows[0].Errors[0].Field)
+}
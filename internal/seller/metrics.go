@@ -0,0 +1,122 @@
+package seller
+
+import (
+	"context"
+	"time"
+
+	"Sellers/internal/domain"
+	"Sellers/internal/metrics"
+)
+
+// instrumentedRepository decorates a Repository recording the latency and
+// errors of every operation.
+type instrumentedRepository struct {
+	next Repository
+	ops  *metrics.Operations
+}
+
+// NewInstrumentedRepository returns next with its operations recorded in ops
+// under the "seller" repository.
+func NewInstrumentedRepository(next Repository, ops *metrics.Operations) Repository {
+	return &instrumentedRepository{next: next, ops: ops}
+}
+
+func (r *instrumentedRepository) observe(op string, start time.Time, err error) {
+	r.ops.Observe("seller", op, start, err)
+}
+
+func (r *instrumentedRepository) GetAll(ctx context.Context) ([]domain.Seller, error) {
+	start := time.Now()
+	ss, err := r.next.GetAll(ctx)
+	r.observe("get_all", start, err)
+	return ss, err
+}
+
+func (r *instrumentedRepository) List(ctx context.Context, q Query) (Page, error) {
+	start := time.Now()
+	p, err := r.next.List(ctx, q)
+	r.observe("list", start, err)
+	return p, err
+}
+
+// Stream is timed until the last row is handed to fn, fn's work included.
+func (r *instrumentedRepository) Stream(ctx context.Context, q Query, fn func(domain.Seller) error) error {
+	start := time.Now()
+	err := r.next.Stream(ctx, q, fn)
+	r.observe("stream", start, err)
+	return err
+}
+
+func (r *instrumentedRepository) Get(ctx context.Context, id int) (domain.Seller, error) {
+	start := time.Now()
+	s, err := r.next.Get(ctx, id)
+	r.observe("get", start, err)
+	return s, err
+}
+
+func (r *instrumentedRepository) Exists(ctx context.Context, cid int) (bool, error) {
+	start := time.Now()
+	exists, err := r.next.Exists(ctx, cid)
+	r.observe("exists", start, err)
+	return exists, err
+}
+
+func (r *instrumentedRepository) Save(ctx context.Context, s domain.Seller) (int, error) {
+	start := time.Now()
+	id, err := r.next.Save(ctx, s)
+	r.observe("save", start, err)
+	return id, err
+}
+
+func (r *instrumentedRepository) Update(ctx context.Context, s domain.Seller) error {
+	start := time.Now()
+	err := r.next.Update(ctx, s)
+	r.observe("update", start, err)
+	return err
+}
+
+func (r *instrumentedRepository) Delete(ctx context.Context, id int, version int) error {
+	start := time.Now()
+	err := r.next.Delete(ctx, id, version)
+	r.observe("delete", start, err)
+	return err
+}
+
+func (r *instrumentedRepository) Restore(ctx context.Context, id int) error {
+	start := time.Now()
+	err := r.next.Restore(ctx, id)
+	r.observe("restore", start, err)
+	return err
+}
+
+func (r *instrumentedRepository) Purge(ctx context.Context, olderThan time.Duration) (int64, error) {
+	start := time.Now()
+	n, err := r.next.Purge(ctx, olderThan)
+	r.observe("purge", start, err)
+	return n, err
+}
+
+func (r *instrumentedRepository) Record(ctx context.Context, c Change) error {
+	start := time.Now()
+	err := r.next.Record(ctx, c)
+	r.observe("record", start, err)
+	return err
+}
+
+func (r *instrumentedRepository) History(ctx context.Context, q HistoryQuery) (HistoryPage, error) {
+	start := time.Now()
+	p, err := r.next.History(ctx, q)
+	r.observe("history", start, err)
+	return p, err
+}
+
+// WithTx records the operations of fn one by one, and the transaction as a
+// whole, commit included, as "tx".
+func (r *instrumentedRepository) WithTx(ctx context.Context, fn func(Repository) error) error {
+	start := time.Now()
+	err := r.next.WithTx(ctx, func(tx Repository) error {
+		return fn(&instrumentedRepository{next: tx, ops: r.ops})
+	})
+	r.observe("tx", start, err)
+	return err
+}
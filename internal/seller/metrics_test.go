@@ -0,0 +1,35 @@
+package seller
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"Sellers/internal/domain"
+	"Sellers/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestInstrumentedRepositoryRecordsOperations(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1}, nil)
+	repo.On("Get", mock.Anything, 2).Return(domain.Seller{}, ErrNotFound)
+	reg := metrics.NewRegistry()
+	r := NewInstrumentedRepository(repo, metrics.NewOperations(reg))
+
+	err := r.WithTx(context.Background(), func(tx Repository) error {
+		_, err := tx.Get(context.Background(), 1)
+		return err
+	})
+	assert.NoError(t, err)
+	_, err = r.Get(context.Background(), 2)
+	assert.Equal(t, ErrNotFound, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, reg.Write(context.Background(), &buf))
+	assert.Contains(t, buf.String(), `repository_operation_duration_seconds_count{repository="seller",operation="get"} 2`)
+	assert.Contains(t, buf.String(), `repository_operation_duration_seconds_count{repository="seller",operation="tx"} 1`)
+	assert.Contains(t, buf.String(), `repository_operation_errors_total{repository="seller",operation="get",kind="not_found"} 1`)
+}
@@ -0,0 +1,146 @@
+package seller
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"Sellers/internal/domain"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// ErrInvalidQuery is returned when a listing has an unknown sort column, a
+// malformed cursor or inconsistent pagination parameters.
+var ErrInvalidQuery = domain.NewError(domain.ErrValidation, "seller_invalid_query", "invalid sellers query")
+
+// Filter narrows a sellers listing. Zero values are ignored. Soft deleted
+// sellers are left out unless IncludeDeleted is set.
+type Filter struct {
+	CompanyName    string
+	CID            int
+	LocalitiesID   int
+	Province       string
+	Country        string
+	IncludeDeleted bool
+}
+
+// Query describes a filtered, sorted and paginated sellers listing. Sort is a
+// column name optionally prefixed by "-" for descending order. Cursor and
+// Offset are mutually exclusive.
+type Query struct {
+	Filter Filter
+	Sort   string
+	Limit  int
+	Offset int
+	Cursor string
+}
+
+// Page is one page of a sellers listing. Limit is the page size actually applied.
+type Page struct {
+	Sellers    []domain.Seller
+	Total      int
+	Limit      int
+	NextCursor string
+}
+
+type sortColumn struct {
+	expr    string
+	numeric bool
+	value   func(s domain.Seller) string
+}
+
+var sortColumns = map[string]sortColumn{
+	"id":            {"s.id", true, func(s domain.Seller) string { return strconv.Itoa(s.ID) }},
+	"cid":           {"s.cid", true, func(s domain.Seller) string { return strconv.Itoa(s.CID) }},
+	"company_name":  {"s.company_name", false, func(s domain.Seller) string { return s.CompanyName }},
+	"address":       {"s.address", false, func(s domain.Seller) string { return s.Address }},
+	"telephone":     {"s.telephone", false, func(s domain.Seller) string { return s.Telephone }},
+	"localities_id": {"s.localities_id", true, func(s domain.Seller) string { return strconv.Itoa(s.LocalitiesId) }},
+}
+
+// cursor is the decoded form of the opaque pagination token: the sort it was
+// issued for and the sort value and id of the last row returned.
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(token string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	return c, nil
+}
+
+// parseSort resolves the sort parameter against the whitelist.
+func parseSort(sort string) (name string, col sortColumn, desc bool, err error) {
+	name = sort
+	if name == "" {
+		name = "id"
+	}
+	if strings.HasPrefix(name, "-") {
+		desc = true
+		name = name[1:]
+	}
+	col, ok := sortColumns[name]
+	if !ok {
+		return "", sortColumn{}, false, fmt.Errorf("%w: cannot sort by %q", ErrInvalidQuery, name)
+	}
+	return name, col, desc, nil
+}
+
+// whereClause builds the WHERE clause shared by the listing and its count.
+func (f Filter) whereClause() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+
+	if !f.IncludeDeleted {
+		conds = append(conds, "s.deleted_at IS NULL")
+	}
+	if f.CompanyName != "" {
+		conds = append(conds, "s.company_name LIKE ?")
+		args = append(args, "%"+escapeLike(f.CompanyName)+"%")
+	}
+	if f.CID != 0 {
+		conds = append(conds, "s.cid = ?")
+		args = append(args, f.CID)
+	}
+	if f.LocalitiesID != 0 {
+		conds = append(conds, "s.localities_id = ?")
+		args = append(args, f.LocalitiesID)
+	}
+	if f.Province != "" {
+		conds = append(conds, "l.province_name = ?")
+		args = append(args, f.Province)
+	}
+	if f.Country != "" {
+		conds = append(conds, "l.country_name = ?")
+		args = append(args, f.Country)
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -3,128 +3,455 @@ package seller
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
 
 	//"github.com/extlurosell/meli_bootcamp_go_w3-7/internal/domain"
 	"Sellers/internal/domain"
+	"Sellers/internal/logging"
+	"Sellers/internal/storage"
 )
 
 // Repository encapsulates the storage of a Seller.
 type Repository interface {
 	GetAll(ctx context.Context) ([]domain.Seller, error)
+	List(ctx context.Context, q Query) (Page, error)
+	// Stream calls fn with every seller matching q.Filter in q.Sort order,
+	// one row at a time, stopping at the first error fn returns.
+	Stream(ctx context.Context, q Query, fn func(domain.Seller) error) error
 	Get(ctx context.Context, id int) (domain.Seller, error)
-	Exists(ctx context.Context, cid int) bool
+	Exists(ctx context.Context, cid int) (bool, error)
 	Save(ctx context.Context, s domain.Seller) (int, error)
+	// Update and Delete are conditional on the version of the seller they
+	// were based on and return ErrVersionMismatch when it has changed.
 	Update(ctx context.Context, s domain.Seller) error
-	Delete(ctx context.Context, id int) error
+	// Delete soft deletes a seller; Restore undoes it and Purge removes for
+	// good the sellers deleted more than olderThan ago.
+	Delete(ctx context.Context, id int, version int) error
+	Restore(ctx context.Context, id int) error
+	Purge(ctx context.Context, olderThan time.Duration) (int64, error)
+	// Record appends c to the audit trail; History reads it back, or
+	// returns ErrNotFound when the seller does not exist, not even soft
+	// deleted.
+	Record(ctx context.Context, c Change) error
+	History(ctx context.Context, q HistoryQuery) (HistoryPage, error)
+	// WithTx runs fn with a Repository bound to a single transaction, which
+	// is committed if fn returns nil and rolled back otherwise.
+	WithTx(ctx context.Context, fn func(Repository) error) error
+}
+
+// sellerColumns are the columns scanSeller reads, in order.
+const sellerColumns = "s.id, s.cid, s.company_name, s.address, s.telephone, s.localities_id, s.deleted_at, s.version, s.updated_at"
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSeller(sc scanner) (domain.Seller, error) {
+	s := domain.Seller{}
+	var deletedAt sql.NullTime
+	if err := sc.Scan(&s.ID, &s.CID, &s.CompanyName, &s.Address, &s.Telephone, &s.LocalitiesId, &deletedAt, &s.Version, &s.UpdatedAt); err != nil {
+		return domain.Seller{}, err
+	}
+	if deletedAt.Valid {
+		s.DeletedAt = &deletedAt.Time
+	}
+	return s, nil
 }
 
 type repository struct {
-	db *sql.DB
+	db  *sql.DB
+	q   storage.Querier
+	log *logging.Logger
+	// inTx is set on the repositories WithTx binds to a transaction.
+	inTx bool
 }
 
-func NewRepository(db *sql.DB) Repository {
+// NewRepository returns a Repository on db logging its statements to log.
+func NewRepository(db *sql.DB, log *logging.Logger) Repository {
 	return &repository{
-		db: db,
+		db:  db,
+		q:   storage.Logged(db, log),
+		log: log,
+	}
+}
+
+func (r *repository) WithTx(ctx context.Context, fn func(Repository) error) error {
+	if r.inTx {
+		return fn(r)
 	}
+	err := storage.InTx(ctx, r.db, func(tx *sql.Tx) error {
+		return fn(&repository{db: r.db, q: storage.Logged(tx, r.log), log: r.log, inTx: true})
+	})
+	return storage.Translate(err, ErrNotFound)
 }
 
 func (r *repository) GetAll(ctx context.Context) ([]domain.Seller, error) {
-	query := "SELECT * FROM sellers"
-	rows, err := r.db.Query(query)
+	query := "SELECT " + sellerColumns + " FROM sellers s WHERE s.deleted_at IS NULL"
+	rows, err := r.q.QueryContext(ctx, query)
 	if err != nil {
-		return nil, err
+		return nil, storage.Translate(err, ErrNotFound)
 	}
+	defer rows.Close()
 
 	var sellers []domain.Seller
 
 	for rows.Next() {
-		s := domain.Seller{}
-		_ = rows.Scan(&s.ID, &s.CID, &s.CompanyName, &s.Address, &s.Telephone, &s.LocalitiesId)
+		s, err := scanSeller(rows)
+		if err != nil {
+			return nil, storage.Translate(err, ErrNotFound)
+		}
 		sellers = append(sellers, s)
 	}
 
-	return sellers, nil
+	return sellers, storage.Translate(rows.Err(), ErrNotFound)
+}
+
+// List returns one page of sellers matching q, filtered, sorted and paginated in SQL.
+func (r *repository) List(ctx context.Context, q Query) (Page, error) {
+	sortName, col, desc, err := parseSort(q.Sort)
+	if err != nil {
+		return Page{}, err
+	}
+	if q.Cursor != "" && q.Offset != 0 {
+		return Page{}, fmt.Errorf("%w: cursor and offset cannot be combined", ErrInvalidQuery)
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+
+	from := " FROM sellers s LEFT JOIN localities l ON l.id = s.localities_id"
+	where, args := q.Filter.whereClause()
+
+	cmp, dir := ">", "ASC"
+	if desc {
+		cmp, dir = "<", "DESC"
+	}
+
+	// The keyset condition only applies to the page query, not to the total.
+	var keyset string
+	var keysetArgs []interface{}
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return Page{}, err
+		}
+		if c.Sort != q.Sort {
+			return Page{}, fmt.Errorf("%w: cursor was issued for a different sort", ErrInvalidQuery)
+		}
+		var v interface{} = c.Value
+		if col.numeric {
+			n, err := strconv.Atoi(c.Value)
+			if err != nil {
+				return Page{}, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+			}
+			v = n
+		}
+
+		keyset = fmt.Sprintf("s.id %s ?", cmp)
+		keysetArgs = []interface{}{c.ID}
+		if sortName != "id" {
+			keyset = fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND s.id %[2]s ?))", col.expr, cmp)
+			keysetArgs = []interface{}{v, v, c.ID}
+		}
+	}
+
+	var total int
+	countQuery := "SELECT COUNT(*)" + from + where
+	if err := r.q.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return Page{}, storage.Translate(err, ErrNotFound)
+	}
+
+	if keyset != "" {
+		if where == "" {
+			where = " WHERE " + keyset
+		} else {
+			where += " AND " + keyset
+		}
+		args = append(args, keysetArgs...)
+	}
+
+	order := fmt.Sprintf(" ORDER BY %s %s", col.expr, dir)
+	if sortName != "id" {
+		order += fmt.Sprintf(", s.id %s", dir)
+	}
+
+	// One extra row tells us whether there is a next page.
+	query := "SELECT " + sellerColumns + from + where + order + " LIMIT ?"
+	args = append(args, q.Limit+1)
+	if q.Offset > 0 {
+		query += " OFFSET ?"
+		args = append(args, q.Offset)
+	}
+
+	rows, err := r.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return Page{}, storage.Translate(err, ErrNotFound)
+	}
+	defer rows.Close()
+
+	sellers := []domain.Seller{}
+	for rows.Next() {
+		s, err := scanSeller(rows)
+		if err != nil {
+			return Page{}, storage.Translate(err, ErrNotFound)
+		}
+		sellers = append(sellers, s)
+	}
+	if err := rows.Err(); err != nil {
+		return Page{}, storage.Translate(err, ErrNotFound)
+	}
+
+	page := Page{Sellers: sellers, Total: total, Limit: q.Limit}
+	if len(sellers) > q.Limit {
+		page.Sellers = sellers[:q.Limit]
+		last := page.Sellers[q.Limit-1]
+		page.NextCursor = encodeCursor(cursor{Sort: q.Sort, Value: col.value(last), ID: last.ID})
+	}
+	return page, nil
+}
+
+func (r *repository) Stream(ctx context.Context, q Query, fn func(domain.Seller) error) error {
+	sortName, col, desc, err := parseSort(q.Sort)
+	if err != nil {
+		return err
+	}
+	where, args := q.Filter.whereClause()
+
+	dir := "ASC"
+	if desc {
+		dir = "DESC"
+	}
+	order := fmt.Sprintf(" ORDER BY %s %s", col.expr, dir)
+	if sortName != "id" {
+		order += fmt.Sprintf(", s.id %s", dir)
+	}
+
+	query := "SELECT " + sellerColumns + " FROM sellers s LEFT JOIN localities l ON l.id = s.localities_id" + where + order
+	rows, err := r.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return storage.Translate(err, ErrNotFound)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		s, err := scanSeller(rows)
+		if err != nil {
+			return storage.Translate(err, ErrNotFound)
+		}
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	return storage.Translate(rows.Err(), ErrNotFound)
 }
 
 func (r *repository) Get(ctx context.Context, id int) (domain.Seller, error) {
-	query := "SELECT * FROM sellers WHERE id=?;"
-	row := r.db.QueryRow(query, id)
-	s := domain.Seller{}
-	err := row.Scan(&s.ID, &s.CID, &s.CompanyName, &s.Address, &s.Telephone, &s.LocalitiesId)
+	query := "SELECT " + sellerColumns + " FROM sellers s WHERE s.id=? AND s.deleted_at IS NULL"
+	s, err := scanSeller(r.q.QueryRowContext(ctx, query, id))
 	if err != nil {
-		return domain.Seller{}, err
+		return domain.Seller{}, storage.Translate(err, ErrNotFound)
 	}
 
 	return s, nil
 }
 
-func (r *repository) Exists(ctx context.Context, cid int) bool {
+// Exists also sees soft deleted sellers, since their CID is still taken.
+func (r *repository) Exists(ctx context.Context, cid int) (bool, error) {
 	query := "SELECT cid FROM sellers WHERE cid=?;"
-	row := r.db.QueryRow(query, cid)
+	row := r.q.QueryRowContext(ctx, query, cid)
 	err := row.Scan(&cid)
-	return err == nil
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, storage.Translate(err, ErrNotFound)
+	}
+	return true, nil
 }
 
 func (r *repository) Save(ctx context.Context, s domain.Seller) (int, error) {
-	query := "INSERT INTO sellers (cid, company_name, address, telephone, localities_id) VALUES (?, ?, ?, ?, ?)"
-	stmt, err := r.db.Prepare(query)
+	query := "INSERT INTO sellers (cid, company_name, address, telephone, localities_id, updated_at) VALUES (?, ?, ?, ?, ?, UTC_TIMESTAMP())"
+	stmt, err := r.q.PrepareContext(ctx, query)
 	if err != nil {
-		return 0, err
+		return 0, storage.Translate(err, ErrNotFound)
 	}
 
-	res, err := stmt.Exec(s.CID, s.CompanyName, s.Address, s.Telephone, s.LocalitiesId)
+	res, err := stmt.ExecContext(ctx, s.CID, s.CompanyName, s.Address, s.Telephone, s.LocalitiesId)
 	if err != nil {
-		return 0, err
+		return 0, storage.Translate(err, ErrNotFound)
 	}
 
 	id, err := res.LastInsertId()
 	if err != nil {
-		return 0, err
+		return 0, storage.Translate(err, ErrNotFound)
 	}
 
 	return int(id), nil
 }
 
+// Update only writes s if its version is still s.Version, and bumps it.
 func (r *repository) Update(ctx context.Context, s domain.Seller) error {
-	query := "UPDATE sellers SET cid=?, company_name=?, address=?, telephone=? WHERE id=?"
-	stmt, err := r.db.Prepare(query)
+	query := "UPDATE sellers SET cid=?, company_name=?, address=?, telephone=?, localities_id=?, version=version+1, updated_at=UTC_TIMESTAMP() WHERE id=? AND version=? AND deleted_at IS NULL"
+	stmt, err := r.q.PrepareContext(ctx, query)
 	if err != nil {
-		return err
+		return storage.Translate(err, ErrNotFound)
 	}
 
-	res, err := stmt.Exec(s.CID, s.CompanyName, s.Address, s.Telephone, s.ID)
+	res, err := stmt.ExecContext(ctx, s.CID, s.CompanyName, s.Address, s.Telephone, s.LocalitiesId, s.ID, s.Version)
 	if err != nil {
-		return err
+		return storage.Translate(err, ErrNotFound)
 	}
 
-	_, err = res.RowsAffected()
+	affect, err := res.RowsAffected()
 	if err != nil {
-		return err
+		return storage.Translate(err, ErrNotFound)
+	}
+	if affect < 1 {
+		return r.missed(ctx, s.ID)
 	}
 
 	return nil
 }
 
-func (r *repository) Delete(ctx context.Context, id int) error {
-	query := "DELETE FROM sellers WHERE id=?"
-	stmt, err := r.db.Prepare(query)
+// Delete only soft deletes id if its version is still version, and bumps it.
+func (r *repository) Delete(ctx context.Context, id int, version int) error {
+	query := "UPDATE sellers SET deleted_at=UTC_TIMESTAMP(), version=version+1, updated_at=UTC_TIMESTAMP() WHERE id=? AND version=? AND deleted_at IS NULL"
+	res, err := r.q.ExecContext(ctx, query, id, version)
 	if err != nil {
-		return err
+		return storage.Translate(err, ErrNotFound)
 	}
 
-	res, err := stmt.Exec(id)
+	affect, err := res.RowsAffected()
 	if err != nil {
-		return err
+		return storage.Translate(err, ErrNotFound)
+	}
+	if affect < 1 {
+		return r.missed(ctx, id)
+	}
+
+	return nil
+}
+
+// missed explains why a conditional write of id changed no row: the seller
+// does not exist or another write changed its version first.
+func (r *repository) missed(ctx context.Context, id int) error {
+	var version int
+	err := r.q.QueryRowContext(ctx, "SELECT version FROM sellers WHERE id=? AND deleted_at IS NULL", id).Scan(&version)
+	if err != nil {
+		return storage.Translate(err, ErrNotFound)
+	}
+	return ErrVersionMismatch
+}
+
+func (r *repository) Restore(ctx context.Context, id int) error {
+	query := "UPDATE sellers SET deleted_at=NULL, version=version+1, updated_at=UTC_TIMESTAMP() WHERE id=? AND deleted_at IS NOT NULL"
+	res, err := r.q.ExecContext(ctx, query, id)
+	if err != nil {
+		return storage.Translate(err, ErrNotFound)
 	}
 
 	affect, err := res.RowsAffected()
 	if err != nil {
-		return err
+		return storage.Translate(err, ErrNotFound)
 	}
 
 	if affect < 1 {
-		return err
+		return ErrNotFound
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+func (r *repository) Purge(ctx context.Context, olderThan time.Duration) (int64, error) {
+	query := "DELETE FROM sellers WHERE deleted_at IS NOT NULL AND deleted_at < UTC_TIMESTAMP() - INTERVAL ? SECOND"
+	res, err := r.q.ExecContext(ctx, query, int64(olderThan/time.Second))
+	if err != nil {
+		return 0, storage.Translate(err, ErrNotFound)
+	}
+	n, err := res.RowsAffected()
+	return n, storage.Translate(err, ErrNotFound)
+}
+
+func (r *repository) Record(ctx context.Context, c Change) error {
+	before, err := snapshot(c.Before)
+	if err != nil {
+		return err
+	}
+	after, err := snapshot(c.After)
+	if err != nil {
+		return err
+	}
+
+	query := "INSERT INTO seller_history (seller_id, action, before_data, after_data, actor, request_id, created_at) VALUES (?, ?, ?, ?, ?, ?, UTC_TIMESTAMP(6))"
+	_, err = r.q.ExecContext(ctx, query, c.SellerID, c.Action, before, after, c.Actor, c.RequestID)
+	return storage.Translate(err, ErrNotFound)
+}
+
+func (r *repository) History(ctx context.Context, q HistoryQuery) (HistoryPage, error) {
+	var total int
+	var exists bool
+	query := "SELECT COUNT(*), EXISTS(SELECT 1 FROM sellers WHERE id=?) FROM seller_history WHERE seller_id=?"
+	err := r.q.QueryRowContext(ctx, query, q.SellerID, q.SellerID).Scan(&total, &exists)
+	if err != nil {
+		return HistoryPage{}, storage.Translate(err, ErrNotFound)
+	}
+	if !exists {
+		return HistoryPage{}, ErrNotFound
+	}
+
+	query = "SELECT id, seller_id, action, before_data, after_data, actor, request_id, created_at FROM seller_history" +
+		" WHERE seller_id=? ORDER BY id DESC LIMIT ? OFFSET ?"
+	rows, err := r.q.QueryContext(ctx, query, q.SellerID, q.Limit, q.Offset)
+	if err != nil {
+		return HistoryPage{}, storage.Translate(err, ErrNotFound)
+	}
+	defer rows.Close()
+
+	changes := []Change{}
+	for rows.Next() {
+		var c Change
+		var before, after []byte
+		if err := rows.Scan(&c.ID, &c.SellerID, &c.Action, &before, &after, &c.Actor, &c.RequestID, &c.CreatedAt); err != nil {
+			return HistoryPage{}, storage.Translate(err, ErrNotFound)
+		}
+		if c.Before, err = fromSnapshot(before); err != nil {
+			return HistoryPage{}, err
+		}
+		if c.After, err = fromSnapshot(after); err != nil {
+			return HistoryPage{}, err
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return HistoryPage{}, storage.Translate(err, ErrNotFound)
+	}
+
+	return HistoryPage{Changes: changes, Total: total, Limit: q.Limit}, nil
+}
+
+// snapshot encodes s for a JSON column; nil stays NULL.
+func snapshot(s *domain.Seller) (interface{}, error) {
+	if s == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func fromSnapshot(b []byte) (*domain.Seller, error) {
+	if b == nil {
+		return nil, nil
+	}
+	s := &domain.Seller{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
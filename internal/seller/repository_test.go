@@ -2,16 +2,22 @@ package seller
 
 import (
 	"Sellers/internal/domain"
+	"Sellers/internal/logging"
 	"context"
+	"database/sql"
 	"errors"
 	//"fmt"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
 )
 
+// updatedAt is the updated_at of the seller rows the tests return.
+var updatedAt = time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+
 
 func TestCreateSellerOk( t *testing.T){
 
@@ -37,7 +43,7 @@ func TestCreateSellerOk( t *testing.T){
 	WillReturnResult(sqlmock.NewResult(1,1)).
 	WillReturnError(nil)
 
-	sellerRepository := NewRepository(db)
+	sellerRepository := NewRepository(db, logging.Discard)
 
 	actualId, err := sellerRepository.Save(context.Background(),sellerToSave)
 	assert.Nil(t, err)
@@ -71,7 +77,7 @@ func TestCreateConflict(t *testing.T){
 		WithArgs(sellerToSave.CID, sellerToSave.CompanyName, sellerToSave.Address, sellerToSave.Telephone, sellerToSave.LocalitiesId).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	localityRepository := NewRepository(db)
+	localityRepository := NewRepository(db, logging.Discard)
 
 	ctx := context.Background()
 	actualId, err := localityRepository.Save(ctx, sellerToSave)
@@ -107,17 +113,17 @@ func TestGetAllSellersOk(t *testing.T) {
 	assert.Nil(t, sqlMockErr)
 	defer db.Close()
 
-	columns := []string{"id", "cid", "company_name", "address", "telephone", "localities_id"}
+	columns := []string{"id", "cid", "company_name", "address", "telephone", "localities_id", "deleted_at", "version", "updated_at"}
 	rows := sqlmock.NewRows(columns)
-	rows.AddRow(1, 1, "Meli", "Bulnes 10", "123456", 1)
-	rows.AddRow(2, 2, "Baires Dev", "Belgrano 3200", "3814471789", 2)
+	rows.AddRow(1, 1, "Meli", "Bulnes 10", "123456", 1, nil, 1, updatedAt)
+	rows.AddRow(2, 2, "Baires Dev", "Belgrano 3200", "3814471789", 2, nil, 1, updatedAt)
 
 	// TODO
 	mock.
-		ExpectQuery("SELECT \\* FROM sellers").
+		ExpectQuery("SELECT s.id, .* FROM sellers s WHERE s.deleted_at IS NULL").
 		WillReturnRows(rows)
 
-	sellerRepository := NewRepository(db)
+	sellerRepository := NewRepository(db, logging.Discard)
 
 	ctx := context.Background()
 	sellers, err := sellerRepository.GetAll(ctx)
@@ -133,23 +139,25 @@ func TestGetSellerByIdOk(t *testing.T) {
 		Address:      "Bulnes 10",
 		Telephone:    "123456",
 		LocalitiesId: 1,
+		Version:      1,
+		UpdatedAt:    updatedAt,
 	}
 
 	db, mock, sqlMockErr := sqlmock.New()
 	assert.Nil(t, sqlMockErr)
 	defer db.Close()
 
-	columns := []string{"id", "cid", "company_name", "address", "telephone", "localities_id"}
+	columns := []string{"id", "cid", "company_name", "address", "telephone", "localities_id", "deleted_at", "version", "updated_at"}
 	rows := sqlmock.NewRows(columns)
 
 	sellerId := 1
-	rows.AddRow(sellerId, 1, "Meli", "Bulnes 10", "123456", 1)
+	rows.AddRow(sellerId, 1, "Meli", "Bulnes 10", "123456", 1, nil, 1, updatedAt)
 	mock.
-		ExpectQuery("SELECT \\* FROM sellers WHERE id=\\?;").
+		ExpectQuery("FROM sellers s WHERE s.id=\\? AND s.deleted_at IS NULL").
 		WithArgs(1).
 		WillReturnRows(rows)
 
-	sellerRepository := NewRepository(db)
+	sellerRepository := NewRepository(db, logging.Discard)
 
 	actualResult, err := sellerRepository.Get(context.Background(), 1)
 
@@ -174,9 +182,10 @@ func TestDoesExists(t *testing.T) {
 		WithArgs(sellerID).
 		WillReturnRows(rows)
 
-	sellerRepository := NewRepository(db)
+	sellerRepository := NewRepository(db, logging.Discard)
 
-	doesExist := sellerRepository.Exists(context.Background(), sellerID)
+	doesExist, err := sellerRepository.Exists(context.Background(), sellerID)
+	assert.NoError(t, err)
 	assert.True(t, doesExist)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -190,16 +199,32 @@ func TestDoesntExists(t *testing.T) {
 
 	mock.
 		ExpectQuery("SELECT cid FROM sellers WHERE cid=\\?;").
-		WithArgs(testID)
+		WithArgs(testID).
+		WillReturnRows(sqlmock.NewRows([]string{"cid"}))
 
-	sellerRepository := NewRepository(db)
+	sellerRepository := NewRepository(db, logging.Discard)
 
-	doesExist := sellerRepository.Exists(context.Background(), testID)
+	doesExist, err := sellerRepository.Exists(context.Background(), testID)
 
+	assert.NoError(t, err)
 	assert.False(t, doesExist)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestExistsReturnsQueryError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery("SELECT cid FROM sellers WHERE cid=\\?;").
+		WithArgs(3).
+		WillReturnError(mysql.ErrInvalidConn)
+
+	exists, err := NewRepository(db, logging.Discard).Exists(context.Background(), 3)
+
+	assert.False(t, exists)
+	assert.ErrorIs(t, err, domain.ErrUnavailable)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
 
 func TestUpdateSuccess(t *testing.T) {
 	sellerToUpdate := domain.Seller{
@@ -209,19 +234,20 @@ func TestUpdateSuccess(t *testing.T) {
 		Address:      "Bulnes 10",
 		Telephone:    "123456",
 		LocalitiesId: 1,
+		Version:      3,
 	}
 
 	db, mock, sqlMockErr := sqlmock.New()
 	assert.Nil(t, sqlMockErr)
 	defer db.Close()
 	mock.
-		ExpectPrepare("UPDATE sellers SET cid=\\?, company_name=\\?, address=\\?, telephone=\\? WHERE id=\\?").
+		ExpectPrepare("UPDATE sellers SET cid=\\?, company_name=\\?, address=\\?, telephone=\\?, localities_id=\\?, version=version\\+1, updated_at=UTC_TIMESTAMP\\(\\) WHERE id=\\? AND version=\\? AND deleted_at IS NULL").
 		ExpectExec().
-		WithArgs(sellerToUpdate.CID, sellerToUpdate.CompanyName, sellerToUpdate.Address, sellerToUpdate.Telephone, sellerToUpdate.ID).
+		WithArgs(sellerToUpdate.CID, sellerToUpdate.CompanyName, sellerToUpdate.Address, sellerToUpdate.Telephone, sellerToUpdate.LocalitiesId, sellerToUpdate.ID, sellerToUpdate.Version).
 		WillReturnResult(sqlmock.NewResult(1, 1)).
 		WillReturnError(nil)
 
-	sellerRepository := NewRepository(db)
+	sellerRepository := NewRepository(db, logging.Discard)
 
 	err := sellerRepository.Update(context.Background(), sellerToUpdate)
 	assert.NoError(t, err)
@@ -242,16 +268,310 @@ func TestDeleteSuccess(t *testing.T) {
 	assert.Nil(t, sqlMockErr)
 	defer db.Close()
 	mock.
-		ExpectPrepare("DELETE FROM sellers WHERE id=\\?").
-		ExpectExec().
-		WithArgs(sellerToDelete.ID).
+		ExpectExec("UPDATE sellers SET deleted_at=UTC_TIMESTAMP\\(\\), version=version\\+1, updated_at=UTC_TIMESTAMP\\(\\) WHERE id=\\? AND version=\\? AND deleted_at IS NULL").
+		WithArgs(sellerToDelete.ID, 1).
 		WillReturnResult(sqlmock.NewResult(1, 1)).
 		WillReturnError(nil)
 
-	sellerRepository := NewRepository(db)
+	sellerRepository := NewRepository(db, logging.Discard)
+
+	err := sellerRepository.Delete(context.Background(), 1, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+
+func TestListSellersFiltersAndSorts(t *testing.T) {
+	db, mock, sqlMockErr := sqlmock.New()
+	assert.Nil(t, sqlMockErr)
+	defer db.Close()
+
+	mock.
+		ExpectQuery("SELECT COUNT\\(\\*\\) FROM sellers s LEFT JOIN localities l ON l.id = s.localities_id WHERE s.deleted_at IS NULL AND s.company_name LIKE \\? AND l.province_name = \\?").
+		WithArgs("%Me\\_li%", "Tucuman").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
 
-	err := sellerRepository.Delete(context.Background(), 1)
+	columns := []string{"id", "cid", "company_name", "address", "telephone", "localities_id", "deleted_at", "version", "updated_at"}
+	rows := sqlmock.NewRows(columns)
+	rows.AddRow(3, 3, "Me_li C", "Bulnes 30", "3", 1, nil, 1, updatedAt)
+	rows.AddRow(2, 2, "Me_li B", "Bulnes 20", "2", 1, nil, 1, updatedAt)
+	rows.AddRow(1, 1, "Me_li A", "Bulnes 10", "1", 1, nil, 1, updatedAt)
+	mock.
+		ExpectQuery("SELECT s.id, s.cid, s.company_name, s.address, s.telephone, s.localities_id, s.deleted_at, s.version, s.updated_at FROM sellers s .* ORDER BY s.company_name DESC, s.id DESC LIMIT \\?").
+		WithArgs("%Me\\_li%", "Tucuman", 3).
+		WillReturnRows(rows)
+
+	sellerRepository := NewRepository(db, logging.Discard)
+
+	page, err := sellerRepository.List(context.Background(), Query{
+		Filter: Filter{CompanyName: "Me_li", Province: "Tucuman"},
+		Sort:   "-company_name",
+		Limit:  2,
+	})
 	assert.NoError(t, err)
+	assert.Equal(t, 3, page.Total)
+	assert.Len(t, page.Sellers, 2)
+	assert.NotEmpty(t, page.NextCursor)
 	assert.NoError(t, mock.ExpectationsWereMet())
+
+	c, err := decodeCursor(page.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, cursor{Sort: "-company_name", Value: "Me_li B", ID: 2}, c)
 }
 
+func TestListSellersWithCursor(t *testing.T) {
+	db, mock, sqlMockErr := sqlmock.New()
+	assert.Nil(t, sqlMockErr)
+	defer db.Close()
+
+	mock.
+		ExpectQuery("SELECT COUNT\\(\\*\\) FROM sellers s").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.
+		ExpectQuery("WHERE s.deleted_at IS NULL AND \\(s.cid > \\? OR \\(s.cid = \\? AND s.id > \\?\\)\\) ORDER BY s.cid ASC, s.id ASC LIMIT \\?").
+		WithArgs(20, 20, 2, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "cid", "company_name", "address", "telephone", "localities_id", "deleted_at", "version", "updated_at"}).
+			AddRow(3, 30, "Meli", "Bulnes 10", "1", 1, nil, 1, updatedAt))
+
+	sellerRepository := NewRepository(db, logging.Discard)
+
+	page, err := sellerRepository.List(context.Background(), Query{
+		Sort:   "cid",
+		Limit:  2,
+		Cursor: encodeCursor(cursor{Sort: "cid", Value: "20", ID: 2}),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, page.Sellers, 1)
+	assert.Empty(t, page.NextCursor)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListSellersInvalidQuery(t *testing.T) {
+	db, _, sqlMockErr := sqlmock.New()
+	assert.Nil(t, sqlMockErr)
+	defer db.Close()
+
+	sellerRepository := NewRepository(db, logging.Discard)
+
+	_, err := sellerRepository.List(context.Background(), Query{Sort: "password"})
+	assert.True(t, errors.Is(err, ErrInvalidQuery))
+
+	_, err = sellerRepository.List(context.Background(), Query{Cursor: "%%%"})
+	assert.True(t, errors.Is(err, ErrInvalidQuery))
+}
+
+func TestWithTxCommits(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT cid FROM sellers").WithArgs(1).WillReturnError(sql.ErrNoRows)
+	mock.ExpectPrepare("INSERT INTO sellers").ExpectExec().WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectCommit()
+
+	repo := NewRepository(db, logging.Discard)
+	var id int
+	err = repo.WithTx(context.Background(), func(tx Repository) error {
+		exists, err := tx.Exists(context.Background(), 1)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrCIDExists
+		}
+		id, err = tx.Save(context.Background(), domain.Seller{CID: 1})
+		return err
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 7, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithTxRollsBack(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("INSERT INTO sellers").ExpectExec().WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectRollback()
+
+	failed := errors.New("stop")
+	err = NewRepository(db, logging.Discard).WithTx(context.Background(), func(tx Repository) error {
+		if _, err := tx.Save(context.Background(), domain.Seller{CID: 1}); err != nil {
+			return err
+		}
+		return failed
+	})
+
+	assert.Equal(t, failed, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStreamSellersFiltersAndSorts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	columns := []string{"id", "cid", "company_name", "address", "telephone", "localities_id", "deleted_at", "version", "updated_at"}
+	mock.ExpectQuery(`WHERE s.deleted_at IS NULL AND l.country_name = \? ORDER BY s.company_name DESC, s.id DESC$`).
+		WithArgs("Argentina").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(2, 2, "Meli", "Bulnes 10", "123456", 1, nil, 1, updatedAt).
+			AddRow(1, 1, "Globant", "Libertador 1", "654321", 1, nil, 1, updatedAt))
+
+	var ids []int
+	err = NewRepository(db, logging.Discard).Stream(context.Background(), Query{Sort: "-company_name", Filter: Filter{Country: "Argentina"}}, func(s domain.Seller) error {
+		ids = append(ids, s.ID)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 1}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreNotDeleted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE sellers SET deleted_at=NULL, version=version\\+1, updated_at=UTC_TIMESTAMP\\(\\) WHERE id=\\? AND deleted_at IS NOT NULL").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = NewRepository(db, logging.Discard).Restore(context.Background(), 1)
+
+	assert.Equal(t, ErrNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurge(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec("DELETE FROM sellers WHERE deleted_at IS NOT NULL AND deleted_at < UTC_TIMESTAMP\\(\\) - INTERVAL \\? SECOND").
+		WithArgs(int64(86400)).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	n, err := NewRepository(db, logging.Discard).Purge(context.Background(), 24*time.Hour)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecordChange(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec("INSERT INTO seller_history").
+		WithArgs(1, ActionDelete, `{"id":1,"cid":123,"company_name":"Meli","address":"Bulnes 10","telephone":"123456","localities_id":1}`, nil, "ana", "req-1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	before := domain.Seller{ID: 1, CID: 123, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "123456", LocalitiesId: 1}
+	err = NewRepository(db, logging.Discard).Record(context.Background(), Change{SellerID: 1, Action: ActionDelete, Before: &before, Actor: "ana", RequestID: "req-1"})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHistoryNewestFirst(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	at := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\), EXISTS\\(SELECT 1 FROM sellers WHERE id=\\?\\) FROM seller_history WHERE seller_id=\\?").
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "exists"}).AddRow(2, true))
+	mock.ExpectQuery("SELECT .* FROM seller_history WHERE seller_id=\\? ORDER BY id DESC LIMIT \\? OFFSET \\?").
+		WithArgs(1, 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "seller_id", "action", "before_data", "after_data", "actor", "request_id", "created_at"}).
+			AddRow(2, 1, "update", []byte(`{"id":1,"cid":123}`), []byte(`{"id":1,"cid":124}`), "ana", "req-2", at).
+			AddRow(1, 1, "create", nil, []byte(`{"id":1,"cid":123}`), "anonymous", "", at))
+
+	page, err := NewRepository(db, logging.Discard).History(context.Background(), HistoryQuery{SellerID: 1, Limit: 10})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, page.Total)
+	assert.Len(t, page.Changes, 2)
+	assert.Equal(t, ActionUpdate, page.Changes[0].Action)
+	assert.Equal(t, 123, page.Changes[0].Before.CID)
+	assert.Equal(t, 124, page.Changes[0].After.CID)
+	assert.Nil(t, page.Changes[1].Before)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHistoryOfSellerWithoutChanges(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\), EXISTS").
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "exists"}).AddRow(0, true))
+	mock.ExpectQuery("SELECT .* FROM seller_history").
+		WithArgs(1, 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "seller_id", "action", "before_data", "after_data", "actor", "request_id", "created_at"}))
+
+	page, err := NewRepository(db, logging.Discard).History(context.Background(), HistoryQuery{SellerID: 1, Limit: 10})
+
+	assert.NoError(t, err)
+	assert.Equal(t, HistoryPage{Changes: []Change{}, Limit: 10}, page)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHistoryOfUnknownSeller(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\), EXISTS").
+		WithArgs(9, 9).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "exists"}).AddRow(3, false))
+
+	_, err = NewRepository(db, logging.Discard).History(context.Background(), HistoryQuery{SellerID: 9, Limit: 10})
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateVersionMismatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectPrepare("UPDATE sellers SET").ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version FROM sellers WHERE id=\\? AND deleted_at IS NULL").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(4))
+
+	err = NewRepository(db, logging.Discard).Update(context.Background(), domain.Seller{ID: 1, CID: 1, Version: 3})
+
+	assert.Equal(t, ErrVersionMismatch, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteMissingSeller(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE sellers SET deleted_at").
+		WithArgs(1, 3).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version FROM sellers WHERE id=\\? AND deleted_at IS NULL").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}))
+
+	err = NewRepository(db, logging.Discard).Delete(context.Background(), 1, 3)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
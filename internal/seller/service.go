@@ -2,35 +2,97 @@ package seller
 
 import (
 	"Sellers/internal/domain"
+	"Sellers/internal/locality"
+	"Sellers/internal/logging"
+	"Sellers/internal/reqctx"
+	"Sellers/internal/validation"
 	"context"
 	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
 )
 
 // Errors
 var (
-	ErrNotFound = errors.New("seller not found")
+	ErrNotFound  = domain.NewError(domain.ErrNotFound, "seller_not_found", "seller not found")
+	ErrCIDExists = domain.NewError(domain.ErrConflict, "seller_cid_exists", "a seller with that cid already exists")
+
+	// ErrVersionMismatch is returned when a write is based on a version of the
+	// seller that another write has replaced.
+	ErrVersionMismatch = domain.NewError(domain.ErrPrecondition, "seller_version_mismatch", "the seller was modified by another request")
+
+	ErrInvalidRetention = domain.NewError(domain.ErrValidation, "seller_invalid_retention", "the purge retention must be positive")
 )
 
+// InvalidLocalityError is returned when a seller references a locality that
+// does not exist.
+type InvalidLocalityError struct {
+	LocalitiesId int
+}
+
+func (e *InvalidLocalityError) Error() string {
+	return fmt.Sprintf("locality %d does not exist", e.LocalitiesId)
+}
+
+// Is classifies the error as a validation failure.
+func (e *InvalidLocalityError) Is(target error) bool {
+	return target == domain.ErrValidation
+}
+
+// Code returns the stable code of the error.
+func (e *InvalidLocalityError) Code() string { return "seller_invalid_locality" }
+
+// Args returns the arguments of the localized message.
+func (e *InvalidLocalityError) Args() []interface{} { return []interface{}{e.LocalitiesId} }
+
+// LocalityRepository is the part of locality.Repository the seller service
+// needs to check Seller.LocalitiesId.
+type LocalityRepository interface {
+	Get(ctx context.Context, id int) (domain.Locality, error)
+}
+
 type Service interface{
 	GetAll(ctx context.Context) ([]domain.Seller, error)
+	List(ctx context.Context, q Query) (Page, error)
+	Export(ctx context.Context, q Query, fn func(domain.Seller) error) error
 	Get(ctx context.Context ,id int ) (domain.Seller, error)
 	Save(ctx context.Context,se domain.Seller) (domain.Seller, error)
-	Update(ctx context.Context,  se domain.Seller) error
-	Delete(ctx context.Context, id int) error
-	Exists(ctx context.Context, cid int) bool
-	
+	// Update and Delete fail with ErrVersionMismatch unless the seller is
+	// still at the given version; version 0 accepts any.
+	Update(ctx context.Context,  se domain.Seller) (domain.Seller, error)
+	Delete(ctx context.Context, id int, version int) error
+	Restore(ctx context.Context, id int) (domain.Seller, error)
+	History(ctx context.Context, id, limit, offset int) (HistoryPage, error)
+	Purge(ctx context.Context, olderThan time.Duration) (int64, error)
+	Exists(ctx context.Context, cid int) (bool, error)
+	SaveBatch(ctx context.Context, sellers []domain.Seller) ([]domain.Seller, error)
+	Import(ctx context.Context, records []Record, mode ImportMode) (ImportReport, error)
 }
 
 type service struct {
-	repo Repository
+	repo       Repository
+	localities LocalityRepository
+	log        *logging.Logger
 }
 
 
-func NewService(s Repository) Service {
+func NewService(s Repository, l LocalityRepository, log *logging.Logger) Service {
 	return &service {repo: s,
+		localities: l,
+		log: log,
 	}
 }
 
+// checkLocality verifies that the seller's locality exists.
+func (s *service) checkLocality(ctx context.Context, se domain.Seller) error {
+	_, err := s.localities.Get(ctx, se.LocalitiesId)
+	if errors.Is(err, locality.ErrNotFound) {
+		return &InvalidLocalityError{LocalitiesId: se.LocalitiesId}
+	}
+	return err
+}
+
 func (s *service) GetAll(ctx context.Context) ([]domain.Seller, error) {
 	ps, err := s.repo.GetAll(ctx)
 	if err != nil {
@@ -39,6 +101,26 @@ func (s *service) GetAll(ctx context.Context) ([]domain.Seller, error) {
 	 return ps,nil
 }
 
+// List clamps the page size to [1, MaxLimit] and delegates to the repository.
+func (s *service) List(ctx context.Context, q Query) (Page, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+	if q.Offset < 0 {
+		return Page{}, fmt.Errorf("%w: offset cannot be negative", ErrInvalidQuery)
+	}
+	return s.repo.List(ctx, q)
+}
+
+// Export streams every seller matching q's filter and sort to fn. Pagination
+// fields of q are ignored.
+func (s *service) Export(ctx context.Context, q Query, fn func(domain.Seller) error) error {
+	return s.repo.Stream(ctx, q, fn)
+}
+
 func (s *service) Get(ctx context.Context, id int) (domain.Seller, error) {
 	p, err := s.repo.Get(ctx, id)
 	if err != nil {
@@ -49,11 +131,44 @@ func (s *service) Get(ctx context.Context, id int) (domain.Seller, error) {
 }
 
 func (s *service) Save(ctx context.Context, se domain.Seller) (domain.Seller, error) {
-	exist := s.repo.Exists(ctx, se.CID)
+	if err := s.check(ctx, se); err != nil {
+		return domain.Seller{}, err
+	}
+	var saved domain.Seller
+	err := s.repo.WithTx(ctx, func(repo Repository) error {
+		var err error
+		saved, err = s.save(ctx, repo, se)
+		return err
+	})
+	if err != nil {
+		return domain.Seller{}, err
+	}
+	return saved, nil
+}
+
+// check validates se and checks its locality exists. It must run before the
+// transaction save runs in: the locality lookup takes a connection of its own
+// from the pool, and waiting for one while holding the transaction's can
+// exhaust the pool under concurrent creates.
+func (s *service) check(ctx context.Context, se domain.Seller) error {
+	if err := validation.Struct(se); err != nil {
+		return err
+	}
+	return s.checkLocality(ctx, se)
+}
+
+// save checks the CID of se, which check already accepted, is free and
+// stores it through repo, recording the creation in the audit trail. repo
+// should be bound to a transaction so both writes succeed or fail together.
+func (s *service) save(ctx context.Context, repo Repository, se domain.Seller) (domain.Seller, error) {
+	exist, err := repo.Exists(ctx, se.CID)
+	if err != nil {
+		return domain.Seller{}, err
+	}
 	if exist {
-			return domain.Seller{}, errors.New("el seller ya existe")
+			return domain.Seller{}, ErrCIDExists
 	}
-	p, err := s.repo.Save(ctx,se)
+	p, err := repo.Save(ctx,se)
 
 	if err != nil {
 		return domain.Seller{}, err
@@ -61,21 +176,152 @@ func (s *service) Save(ctx context.Context, se domain.Seller) (domain.Seller, er
 
 	se.ID = p
 
+	if err := record(ctx, repo, ActionCreate, se.ID, nil, &se); err != nil {
+		return domain.Seller{}, err
+	}
 	return se, nil
 }
 
-func (s *service) Update(ctx context.Context, se domain.Seller)  error {
+// Update writes se over the seller with its id, as long as it is still at
+// se.Version, and returns it with its new version. Like Save, it returns
+// ErrCIDExists when se takes the CID of another seller.
+func (s *service) Update(ctx context.Context, se domain.Seller) (domain.Seller, error) {
+	if err := validation.Struct(se); err != nil {
+		return domain.Seller{}, err
+	}
+	if err := s.checkLocality(ctx, se); err != nil {
+		return domain.Seller{}, err
+	}
+
+	err := s.repo.WithTx(ctx, func(repo Repository) error {
+		before, err := repo.Get(ctx, se.ID)
+		if err != nil {
+			return err
+		}
+		if se.Version, err = expectVersion(before, se.Version); err != nil {
+			return err
+		}
+		if se.CID != before.CID {
+			exist, err := repo.Exists(ctx, se.CID)
+			if err != nil {
+				return err
+			}
+			if exist {
+				return ErrCIDExists
+			}
+		}
+		if err := repo.Update(ctx, se); err != nil {
+			return err
+		}
+		se.Version++
+		return record(ctx, repo, ActionUpdate, se.ID, &before, &se)
+	})
+	if err != nil {
+		return domain.Seller{}, err
+	}
+	return se, nil
+}
 
-	return s.repo.Update(ctx, se)
+func (s *service) Delete(ctx context.Context, id int, version int) error {
+	return s.repo.WithTx(ctx, func(repo Repository) error {
+		before, err := repo.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if version, err = expectVersion(before, version); err != nil {
+			return err
+		}
+		if err := repo.Delete(ctx, id, version); err != nil {
+			return err
+		}
+		return record(ctx, repo, ActionDelete, id, &before, nil)
+	})
+}
 
- }
+// expectVersion returns the version a write over current must be conditional
+// on: version itself, or the current one when version is 0.
+func expectVersion(current domain.Seller, version int) (int, error) {
+	if version == 0 {
+		return current.Version, nil
+	}
+	if version != current.Version {
+		return 0, ErrVersionMismatch
+	}
+	return version, nil
+}
 
-func (s *service) Delete(ctx context.Context, id int) error {
-	
-	return s.repo.Delete(ctx, id)
-	
+// Restore undeletes a soft deleted seller and returns it.
+func (s *service) Restore(ctx context.Context, id int) (domain.Seller, error) {
+	var restored domain.Seller
+	err := s.repo.WithTx(ctx, func(repo Repository) error {
+		if err := repo.Restore(ctx, id); err != nil {
+			return err
+		}
+		var err error
+		if restored, err = repo.Get(ctx, id); err != nil {
+			return err
+		}
+		return record(ctx, repo, ActionRestore, id, nil, &restored)
+	})
+	if err != nil {
+		return domain.Seller{}, err
+	}
+	return restored, nil
+}
+
+// History returns one page of the changes of seller id, newest first. The
+// page is empty for sellers created before the audit trail existed.
+func (s *service) History(ctx context.Context, id, limit, offset int) (HistoryPage, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	if offset < 0 {
+		return HistoryPage{}, fmt.Errorf("%w: offset cannot be negative", ErrInvalidQuery)
+	}
+
+	return s.repo.History(ctx, HistoryQuery{SellerID: id, Limit: limit, Offset: offset})
+}
+
+// record appends a change made by the request in ctx to the audit trail.
+func record(ctx context.Context, repo Repository, action Action, id int, before, after *domain.Seller) error {
+	return repo.Record(ctx, Change{
+		SellerID:  id,
+		Action:    action,
+		Before:    before,
+		After:     after,
+		Actor:     reqctx.Actor(ctx),
+		RequestID: truncate(reqctx.RequestID(ctx), maxRequestIDLength),
+	})
+}
+
+// maxRequestIDLength is the size of seller_history.request_id.
+const maxRequestIDLength = 128
+
+// truncate cuts s to its first n characters.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// Purge hard deletes the sellers soft deleted more than olderThan ago and
+// returns how many were removed.
+func (s *service) Purge(ctx context.Context, olderThan time.Duration) (int64, error) {
+	if olderThan <= 0 {
+		return 0, ErrInvalidRetention
+	}
+	n, err := s.repo.Purge(ctx, olderThan)
+	if err != nil {
+		return 0, err
+	}
+	s.log.Info(ctx, "purged sellers", "count", n, "older_than", olderThan.String())
+	return n, nil
 }
 
-func (s *service) Exists(ctx context.Context, cid int) bool {
+func (s *service) Exists(ctx context.Context, cid int) (bool, error) {
 	return s.repo.Exists(ctx, cid)
 }
\ No newline at end of file
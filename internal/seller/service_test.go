@@ -5,7 +5,12 @@ import (
 	"errors"
 	//"errors"
 	"Sellers/internal/domain"
+	"Sellers/internal/locality"
+	"Sellers/internal/logging"
+	"Sellers/internal/reqctx"
+	"strings"
 	"testing"
+	"time"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -13,6 +18,8 @@ import (
 
 type repoM struct{
 	mock.Mock
+	changes []Change
+	inTx    bool
 }
 
 func (r *repoM) GetAll(ctx context.Context) ([]domain.Seller, error) {
@@ -20,14 +27,29 @@ func (r *repoM) GetAll(ctx context.Context) ([]domain.Seller, error) {
 	return args.Get(0).([]domain.Seller), args.Error(1)
 }
 
+func (r *repoM) List(ctx context.Context, q Query) (Page, error) {
+	args := r.Called(ctx, q)
+	return args.Get(0).(Page), args.Error(1)
+}
+
+func (r *repoM) Stream(ctx context.Context, q Query, fn func(domain.Seller) error) error {
+	args := r.Called(ctx, q)
+	for _, s := range args.Get(0).([]domain.Seller) {
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
 func (r *repoM) Get(ctx context.Context, id int) (domain.Seller, error) {
 	args := r.Called(ctx, id)
 	return args.Get(0).(domain.Seller), args.Error(1)
 }
 
-func (r *repoM) Exists(ctx context.Context, cid int) bool {
+func (r *repoM) Exists(ctx context.Context, cid int) (bool, error) {
 	args := r.Called(ctx, cid)
-	return args.Bool(0)
+	return args.Bool(0), args.Error(1)
 }
 
 func (r *repoM) Save(ctx context.Context, se domain.Seller) (int, error) {
@@ -40,20 +62,85 @@ func (r *repoM) Update(ctx context.Context, se domain.Seller) error {
 	return args.Error(0)
 }
 
-func (r *repoM) Delete(ctx context.Context, id int) error {
+func (r *repoM) Delete(ctx context.Context, id int, version int) error {
+	args := r.Called(ctx, id, version)
+	return args.Error(0)
+}
+
+func (r *repoM) Restore(ctx context.Context, id int) error {
 	args := r.Called(ctx, id)
 	return args.Error(0)
 }
 
+func (r *repoM) Purge(ctx context.Context, olderThan time.Duration) (int64, error) {
+	args := r.Called(ctx, olderThan)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (r *repoM) WithTx(ctx context.Context, fn func(Repository) error) error {
+	r.inTx = true
+	defer func() { r.inTx = false }()
+	return fn(r)
+}
+
+// Record keeps the change so tests can inspect the audit trail.
+func (r *repoM) Record(ctx context.Context, c Change) error {
+	r.changes = append(r.changes, c)
+	return nil
+}
+
+func (r *repoM) History(ctx context.Context, q HistoryQuery) (HistoryPage, error) {
+	args := r.Called(ctx, q)
+	return args.Get(0).(HistoryPage), args.Error(1)
+}
+
+type localityRepoM struct {
+	mock.Mock
+}
+
+func (r *localityRepoM) Get(ctx context.Context, id int) (domain.Locality, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).(domain.Locality), args.Error(1)
+}
+
+func localityFound() *localityRepoM {
+	l := new(localityRepoM)
+	l.On("Get", mock.Anything, mock.Anything).Return(domain.Locality{ID: 1}, nil)
+	return l
+}
+
+// localityOutsideTx finds every locality, failing the test if one is looked
+// up inside a transaction of repo.
+func localityOutsideTx(t *testing.T, repo *repoM) *localityRepoM {
+	l := new(localityRepoM)
+	l.On("Get", mock.Anything, mock.Anything).Return(domain.Locality{ID: 1}, nil).Run(func(mock.Arguments) {
+		assert.False(t, repo.inTx, "locality looked up inside the transaction")
+	})
+	return l
+}
+
+func TestCreateChecksLocalityBeforeTransaction(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Exists", mock.Anything, 123).Return(false, nil)
+	repo.On("Save", mock.Anything, mock.Anything).Return(1, nil)
+	localities := localityOutsideTx(t, repo)
+	s := NewService(repo, localities, logging.Discard)
+
+	_, err := s.Save(context.Background(), domain.Seller{CID: 123, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "123456", LocalitiesId: 1})
+
+	assert.NoError(t, err)
+	localities.AssertNumberOfCalls(t, "Get", 1)
+}
+
 func TestCreateOk(t *testing.T) {
 	
 	repo := new(repoM) 
 	
 	repo.On("Save", mock.Anything, mock.Anything).Return(1, nil) 
 	
-	repo.On("Exists", mock.Anything, mock.Anything).Return(false)
+	repo.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
 
-	s := NewService(repo) 
+	s := NewService(repo, localityFound(), logging.Discard) 
 
 	
 	objetoAPersistir := domain.Seller{
@@ -61,6 +148,7 @@ func TestCreateOk(t *testing.T) {
 		CompanyName: "Meli",
 		Address: "Bulnes 10",
 		Telephone: "123456",
+		LocalitiesId: 1,
 	}
 
 	
@@ -94,7 +182,7 @@ func TestFindAll(t *testing.T) {
 		},
 	}, nil)
 
-	s := NewService(repo)
+	s := NewService(repo, localityFound(), logging.Discard)
 
 	objetosRecuperados, err := s.GetAll(context.Background())
 
@@ -121,7 +209,7 @@ func TestFindOne( t *testing.T){
 		Address: "Bulnes 10",
 		Telephone: "123456",
 	}
-	s := NewService(repo)
+	s := NewService(repo, localityFound(), logging.Discard)
 
 	objetoRecuperado, err := s.Get(context.Background(),1)
 
@@ -134,7 +222,7 @@ func TestFindOneNoExist(t *testing.T){
 	repo := new(repoM)
 	repo.On("Get", mock.Anything, mock.Anything).Return(domain.Seller{}, errors.New("No existe un objeto con ese id"))
 
-	s := NewService(repo)
+	s := NewService(repo, localityFound(), logging.Discard)
 	
 	objetoRecuperado, err := s.Get(context.Background(), 3)
 
@@ -153,11 +241,13 @@ func TestUpdateOk(t *testing.T){
 		CompanyName: "Meli",
 		Address: "Bulnes 10",
 		Telephone: "123456",
+		LocalitiesId: 1,
 	}
+	repo.On("Get", mock.Anything, 1).Return(objetoAc, nil)
 	repo.On("Update", mock.Anything, mock.Anything).Return(nil)
-	s := NewService(repo)
+	s := NewService(repo, localityFound(), logging.Discard)
 	ctx := context.Background()
-	err := s.Update(ctx, objetoAc)
+	_, err := s.Update(ctx, objetoAc)
 	assert.Nil(t, err)
 
 }
@@ -166,9 +256,9 @@ func TestUpdateFail(t *testing.T){
 	repo := new(repoM)
 
 	repo.On("Update", mock.Anything, mock.Anything).Return(errors.New("No se puede modificar el seller"))
-	s := NewService(repo)
+	s := NewService(repo, localityFound(), logging.Discard)
 	ctx := context.Background()
-	err := s.Update(ctx, domain.Seller{})
+	_, err := s.Update(ctx, domain.Seller{})
 	assert.NotNil(t, err)
 
 }
@@ -177,10 +267,11 @@ func TestUpdateFail(t *testing.T){
 func TestDeleteOk(t *testing.T){
 	
 	repo := new(repoM)
-	repo.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	repo.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1}, nil)
+	repo.On("Delete", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-	s := NewService(repo)
-	err := s.Delete(context.Background(), 1)
+	s := NewService(repo, localityFound(), logging.Discard)
+	err := s.Delete(context.Background(), 1, 0)
 	assert.NoError(t, err)
 
 }
@@ -189,19 +280,273 @@ func TestDeleteOk(t *testing.T){
 
 func TestDeleteFail(t *testing.T){
 	repo := new(repoM)
-	repo.On("Delete", mock.Anything, mock.Anything).Return(ErrNotFound)
+	repo.On("Get", mock.Anything, 1).Return(domain.Seller{}, ErrNotFound)
 
-	s := NewService(repo)
-	err := s.Delete(context.Background(), 1)
+	s := NewService(repo, localityFound(), logging.Discard)
+	err := s.Delete(context.Background(), 1, 0)
 	assert.Error(t, err)
 
 }
 
 func TestExist(t *testing.T){
 	repo := new(repoM)
-	repo.On("Exists", mock.Anything, mock.Anything).Return(false)
-	s :=NewService(repo)
+	repo.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
+	s :=NewService(repo, localityFound(), logging.Discard)
 	ctx := context.Background()
-	err := s.Exists(ctx, 1)
-	assert.Equal(t,false, err)
-}
\ No newline at end of file
+	exists, err := s.Exists(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t,false, exists)
+}
+
+func TestListClampsLimit(t *testing.T) {
+	repo := new(repoM)
+	repo.On("List", mock.Anything, Query{Limit: MaxLimit, Sort: "cid"}).Return(Page{Total: 0, Limit: MaxLimit}, nil)
+	s := NewService(repo, localityFound(), logging.Discard)
+
+	page, err := s.List(context.Background(), Query{Limit: 1000, Sort: "cid"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, MaxLimit, page.Limit)
+	repo.AssertExpectations(t)
+}
+
+func TestListDefaultLimit(t *testing.T) {
+	repo := new(repoM)
+	repo.On("List", mock.Anything, Query{Limit: DefaultLimit}).Return(Page{Limit: DefaultLimit}, nil)
+	s := NewService(repo, localityFound(), logging.Discard)
+
+	_, err := s.List(context.Background(), Query{})
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestCreateInvalidLocality(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Exists", mock.Anything, mock.Anything).Return(false, nil)
+	localities := new(localityRepoM)
+	localities.On("Get", mock.Anything, 9).Return(domain.Locality{}, locality.ErrNotFound)
+	s := NewService(repo, localities, logging.Discard)
+
+	_, err := s.Save(context.Background(), domain.Seller{CID: 1, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "123456", LocalitiesId: 9})
+
+	var invalid *InvalidLocalityError
+	assert.True(t, errors.As(err, &invalid))
+	assert.Equal(t, 9, invalid.LocalitiesId)
+	repo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestCreateFailsWhenCIDLookupFails(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Exists", mock.Anything, 1).Return(false, domain.ErrUnavailable)
+	s := NewService(repo, localityFound(), logging.Discard)
+
+	_, err := s.Save(context.Background(), domain.Seller{CID: 1, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "123456", LocalitiesId: 1})
+
+	assert.ErrorIs(t, err, domain.ErrUnavailable)
+	repo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestUpdateToTakenCID(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, CID: 123, LocalitiesId: 1, Version: 2}, nil)
+	repo.On("Exists", mock.Anything, 456).Return(true, nil)
+	s := NewService(repo, localityFound(), logging.Discard)
+
+	_, err := s.Update(context.Background(), domain.Seller{ID: 1, CID: 456, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "123456", LocalitiesId: 1})
+
+	assert.Equal(t, ErrCIDExists, err)
+	repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestUpdateMovesSellerToLocality(t *testing.T) {
+	moved := domain.Seller{ID: 1, CID: 123, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "123456", LocalitiesId: 2}
+	repo := new(repoM)
+	repo.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, CID: 123, LocalitiesId: 1}, nil)
+	repo.On("Update", mock.Anything, moved).Return(nil)
+	localities := new(localityRepoM)
+	localities.On("Get", mock.Anything, 2).Return(domain.Locality{ID: 2}, nil)
+	s := NewService(repo, localities, logging.Discard)
+
+	_, err := s.Update(context.Background(), moved)
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+	localities.AssertExpectations(t)
+}
+
+func TestUpdateInvalidLocality(t *testing.T) {
+	repo := new(repoM)
+	localities := new(localityRepoM)
+	localities.On("Get", mock.Anything, 9).Return(domain.Locality{}, locality.ErrNotFound)
+	s := NewService(repo, localities, logging.Discard)
+
+	_, err := s.Update(context.Background(), domain.Seller{ID: 1, CID: 1, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "123456", LocalitiesId: 9})
+
+	var invalid *InvalidLocalityError
+	assert.True(t, errors.As(err, &invalid))
+	repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestRestoreReturnsSeller(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Restore", mock.Anything, 1).Return(nil)
+	repo.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, CID: 123}, nil)
+	s := NewService(repo, localityFound(), logging.Discard)
+
+	restored, err := s.Restore(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 123, restored.CID)
+}
+
+func TestPurgeRejectsNonPositiveRetention(t *testing.T) {
+	repo := new(repoM)
+	s := NewService(repo, localityFound(), logging.Discard)
+
+	_, err := s.Purge(context.Background(), 0)
+
+	assert.Equal(t, ErrInvalidRetention, err)
+	repo.AssertNotCalled(t, "Purge", mock.Anything, mock.Anything)
+}
+
+func TestSaveRecordsCreate(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Exists", mock.Anything, 123).Return(false, nil)
+	repo.On("Save", mock.Anything, mock.Anything).Return(7, nil)
+	s := NewService(repo, localityFound(), logging.Discard)
+	ctx := reqctx.WithRequestID(reqctx.WithActor(context.Background(), "ana"), "req-1")
+
+	_, err := s.Save(ctx, domain.Seller{CID: 123, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "123456", LocalitiesId: 1})
+
+	assert.NoError(t, err)
+	assert.Len(t, repo.changes, 1)
+	c := repo.changes[0]
+	assert.Equal(t, ActionCreate, c.Action)
+	assert.Equal(t, 7, c.SellerID)
+	assert.Nil(t, c.Before)
+	assert.Equal(t, 7, c.After.ID)
+	assert.Equal(t, "ana", c.Actor)
+	assert.Equal(t, "req-1", c.RequestID)
+}
+
+func TestSaveRecordsRequestIDThatFitsTheColumn(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Exists", mock.Anything, 123).Return(false, nil)
+	repo.On("Save", mock.Anything, mock.Anything).Return(7, nil)
+	s := NewService(repo, localityFound(), logging.Discard)
+	ctx := reqctx.WithRequestID(context.Background(), strings.Repeat("ñ", 200))
+
+	_, err := s.Save(ctx, domain.Seller{CID: 123, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "123456", LocalitiesId: 1})
+
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Repeat("ñ", 128), repo.changes[0].RequestID)
+}
+
+func TestUpdateRecordsBeforeAndAfter(t *testing.T) {
+	before := domain.Seller{ID: 1, CID: 123, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "123456", LocalitiesId: 1}
+	after := before
+	after.Address = "Corrientes 500"
+	repo := new(repoM)
+	repo.On("Get", mock.Anything, 1).Return(before, nil)
+	repo.On("Update", mock.Anything, after).Return(nil)
+	s := NewService(repo, localityFound(), logging.Discard)
+
+	_, err := s.Update(context.Background(), after)
+
+	assert.NoError(t, err)
+	assert.Len(t, repo.changes, 1)
+	assert.Equal(t, ActionUpdate, repo.changes[0].Action)
+	assert.Equal(t, "Bulnes 10", repo.changes[0].Before.Address)
+	assert.Equal(t, "Corrientes 500", repo.changes[0].After.Address)
+	assert.Equal(t, reqctx.Anonymous, repo.changes[0].Actor)
+}
+
+func TestDeleteRecordsSnapshot(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, CID: 123}, nil)
+	repo.On("Delete", mock.Anything, 1, 0).Return(nil)
+	s := NewService(repo, localityFound(), logging.Discard)
+
+	err := s.Delete(context.Background(), 1, 0)
+
+	assert.NoError(t, err)
+	assert.Len(t, repo.changes, 1)
+	assert.Equal(t, ActionDelete, repo.changes[0].Action)
+	assert.Equal(t, 123, repo.changes[0].Before.CID)
+	assert.Nil(t, repo.changes[0].After)
+}
+
+func TestUpdateFailureRecordsNothing(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, CID: 1}, nil)
+	repo.On("Update", mock.Anything, mock.Anything).Return(ErrNotFound)
+	s := NewService(repo, localityFound(), logging.Discard)
+
+	_, err := s.Update(context.Background(), domain.Seller{ID: 1, CID: 1, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "123456", LocalitiesId: 1})
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Empty(t, repo.changes)
+}
+
+func TestHistoryClampsLimit(t *testing.T) {
+	repo := new(repoM)
+	repo.On("History", mock.Anything, HistoryQuery{SellerID: 1, Limit: MaxLimit}).Return(HistoryPage{Changes: []Change{{SellerID: 1}}, Total: 1, Limit: MaxLimit}, nil)
+	s := NewService(repo, localityFound(), logging.Discard)
+
+	page, err := s.History(context.Background(), 1, 1000, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, page.Total)
+	repo.AssertExpectations(t)
+}
+
+func TestHistoryUnknownSeller(t *testing.T) {
+	repo := new(repoM)
+	repo.On("History", mock.Anything, HistoryQuery{SellerID: 9, Limit: DefaultLimit}).Return(HistoryPage{}, ErrNotFound)
+	s := NewService(repo, localityFound(), logging.Discard)
+
+	_, err := s.History(context.Background(), 9, 0, 0)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestUpdateReturnsNewVersion(t *testing.T) {
+	current := domain.Seller{ID: 1, CID: 123, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "123456", LocalitiesId: 1, Version: 3}
+	repo := new(repoM)
+	repo.On("Get", mock.Anything, 1).Return(current, nil)
+	repo.On("Update", mock.Anything, current).Return(nil)
+	s := NewService(repo, localityFound(), logging.Discard)
+
+	updated, err := s.Update(context.Background(), current)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, updated.Version)
+	assert.Equal(t, 4, repo.changes[0].After.Version)
+}
+
+func TestUpdateStaleVersion(t *testing.T) {
+	current := domain.Seller{ID: 1, CID: 123, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "123456", LocalitiesId: 1, Version: 4}
+	stale := current
+	stale.Version = 3
+	repo := new(repoM)
+	repo.On("Get", mock.Anything, 1).Return(current, nil)
+	s := NewService(repo, localityFound(), logging.Discard)
+
+	_, err := s.Update(context.Background(), stale)
+
+	assert.Equal(t, ErrVersionMismatch, err)
+	repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	assert.Empty(t, repo.changes)
+}
+
+func TestDeleteStaleVersion(t *testing.T) {
+	repo := new(repoM)
+	repo.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, Version: 4}, nil)
+	s := NewService(repo, localityFound(), logging.Discard)
+
+	err := s.Delete(context.Background(), 1, 3)
+
+	assert.Equal(t, ErrVersionMismatch, err)
+	repo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+
+	"Sellers/internal/domain"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL server error numbers the repositories care about.
+const (
+	errDuplicateEntry    = 1062
+	errRowIsReferenced   = 1451
+	errNoReferencedRow   = 1452
+	errLockWaitTimeout   = 1205
+	errTooManyConnection = 1040
+)
+
+// Translate classifies a database/sql or MySQL driver error under the domain
+// error kinds. sql.ErrNoRows becomes notFound; errors it does not recognise
+// are returned unchanged.
+func Translate(err error, notFound error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound
+	}
+
+	var me *mysql.MySQLError
+	if errors.As(err, &me) {
+		switch me.Number {
+		case errDuplicateEntry, errRowIsReferenced:
+			return domain.WithKind(domain.ErrConflict, err)
+		case errNoReferencedRow:
+			return domain.WithKind(domain.ErrValidation, err)
+		case errLockWaitTimeout, errTooManyConnection:
+			return domain.WithKind(domain.ErrUnavailable, err)
+		}
+		return err
+	}
+
+	var ne net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, context.DeadlineExceeded) || errors.As(err, &ne) {
+		return domain.WithKind(domain.ErrUnavailable, err)
+	}
+	return err
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"Sellers/internal/domain"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+var errThingNotFound = domain.NewError(domain.ErrNotFound, "thing_not_found", "thing not found")
+
+func TestTranslateNoRows(t *testing.T) {
+	assert.Equal(t, errThingNotFound, Translate(sql.ErrNoRows, errThingNotFound))
+}
+
+func TestTranslateKinds(t *testing.T) {
+	cases := []struct {
+		err  error
+		kind error
+	}{
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}, domain.ErrConflict},
+		{&mysql.MySQLError{Number: 1451, Message: "Cannot delete or update a parent row"}, domain.ErrConflict},
+		{&mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row"}, domain.ErrValidation},
+		{&mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}, domain.ErrUnavailable},
+		{driver.ErrBadConn, domain.ErrUnavailable},
+		{mysql.ErrInvalidConn, domain.ErrUnavailable},
+	}
+
+	for _, c := range cases {
+		err := Translate(c.err, errThingNotFound)
+		assert.True(t, errors.Is(err, c.kind), c.err.Error())
+		assert.True(t, errors.Is(err, c.err), c.err.Error())
+		assert.Equal(t, c.err.Error(), err.Error())
+	}
+}
+
+func TestTranslateUnknown(t *testing.T) {
+	err := errors.New("boom")
+
+	assert.Nil(t, Translate(nil, errThingNotFound))
+	assert.Equal(t, err, Translate(err, errThingNotFound))
+}
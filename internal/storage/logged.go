@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"Sellers/internal/logging"
+)
+
+// Logged returns q with every statement it runs or prepares logged at debug
+// level with its duration, its error and the request ID of its context, so
+// the SQL of a request can be found from its access log line. Arguments are
+// left out since they may hold personal data or tokens.
+func Logged(q Querier, log *logging.Logger) Querier {
+	return &loggedQuerier{q: q, log: log}
+}
+
+type loggedQuerier struct {
+	q   Querier
+	log *logging.Logger
+}
+
+func (l *loggedQuerier) logged(ctx context.Context, op, query string, start time.Time, err error) {
+	if !l.log.Enabled(logging.LevelDebug) {
+		return
+	}
+	args := []interface{}{"op", op, "query", query, "duration_ms", time.Since(start)}
+	if err != nil {
+		args = append(args, "err", err)
+	}
+	l.log.Debug(ctx, "sql", args...)
+}
+
+func (l *loggedQuerier) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return l.ExecContext(context.Background(), query, args...)
+}
+
+func (l *loggedQuerier) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	res, err := l.q.ExecContext(ctx, query, args...)
+	l.logged(ctx, "exec", query, start, err)
+	return res, err
+}
+
+func (l *loggedQuerier) Prepare(query string) (*sql.Stmt, error) {
+	return l.PrepareContext(context.Background(), query)
+}
+
+func (l *loggedQuerier) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	start := time.Now()
+	stmt, err := l.q.PrepareContext(ctx, query)
+	l.logged(ctx, "prepare", query, start, err)
+	return stmt, err
+}
+
+func (l *loggedQuerier) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return l.QueryContext(context.Background(), query, args...)
+}
+
+func (l *loggedQuerier) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := l.q.QueryContext(ctx, query, args...)
+	l.logged(ctx, "query", query, start, err)
+	return rows, err
+}
+
+func (l *loggedQuerier) QueryRow(query string, args ...interface{}) *sql.Row {
+	return l.QueryRowContext(context.Background(), query, args...)
+}
+
+// QueryRowContext logs no error: sql.Row defers it to Scan.
+func (l *loggedQuerier) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := l.q.QueryRowContext(ctx, query, args...)
+	l.logged(ctx, "query", query, start, nil)
+	return row
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"Sellers/internal/logging"
+	"Sellers/internal/reqctx"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggedCorrelatesSQLWithRequest(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	mock.ExpectExec("DELETE FROM sellers WHERE id=\\?").WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	var buf bytes.Buffer
+	q := Logged(db, logging.New(&buf, logging.LevelDebug))
+	ctx := reqctx.WithRequestID(context.Background(), "req-1")
+	_, err = q.ExecContext(ctx, "DELETE FROM sellers WHERE id=?", 7)
+
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), `"msg":"sql","request_id":"req-1","op":"exec","query":"DELETE FROM sellers WHERE id=?"`)
+	assert.NotContains(t, buf.String(), `"err"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoggedSkipsWhenDebugDisabled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+
+	var buf bytes.Buffer
+	rows, err := Logged(db, logging.New(&buf, logging.LevelInfo)).QueryContext(context.Background(), "SELECT 1")
+
+	assert.NoError(t, err)
+	rows.Close()
+	assert.Empty(t, buf.String())
+}
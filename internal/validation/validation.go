@@ -0,0 +1,103 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"Sellers/internal/domain"
+	"Sellers/internal/i18n"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError is one rule a field failed. Field is the JSON name, Rule the
+// validate tag (e.g. "required", "max") and Param its argument, if any. Key is
+// the i18n message key, formatted with Field and Param; Message is that
+// message in the default language.
+type FieldError struct {
+	Field   string
+	Rule    string
+	Param   string
+	Key     string
+	Message string
+}
+
+// Errors lists every invalid field of a struct. It is classified as
+// domain.ErrValidation.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+func (e Errors) Is(target error) bool {
+	return target == domain.ErrValidation
+}
+
+var telephone = regexp.MustCompile(`^\+?[0-9][0-9 ()-]{4,30}$`)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	_ = v.RegisterValidation("telephone", func(fl validator.FieldLevel) bool {
+		return telephone.MatchString(fl.Field().String())
+	})
+	return v
+}
+
+// Struct checks v against its validate tags and returns Errors with every
+// failing field, or nil.
+func Struct(v interface{}) error {
+	err := validate.Struct(v)
+	if err == nil {
+		return nil
+	}
+
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+
+	errs := make(Errors, 0, len(verrs))
+	for _, fe := range verrs {
+		key := messageKey(fe)
+		errs = append(errs, FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Key:     key,
+			Message: i18n.T(i18n.Default, key, fe.Field(), fe.Param()),
+		})
+	}
+	return errs
+}
+
+// messageKey picks the i18n key describing the rule fe failed. Length rules
+// on strings get their own wording.
+func messageKey(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required", "alphanum", "telephone":
+		return "validation." + fe.Tag()
+	case "max", "min":
+		if fe.Kind() == reflect.String {
+			return "validation." + fe.Tag() + "_len"
+		}
+		return "validation." + fe.Tag()
+	default:
+		return "validation.invalid"
+	}
+}
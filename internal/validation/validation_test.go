@@ -0,0 +1,54 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"Sellers/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStructValid(t *testing.T) {
+	s := domain.Seller{CID: 1, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "+54 (11) 4444-5555", LocalitiesId: 1}
+
+	assert.NoError(t, Struct(s))
+}
+
+func TestStructReportsAllFields(t *testing.T) {
+	err := Struct(domain.Seller{CompanyName: "Meli"})
+
+	var errs Errors
+	assert.True(t, errors.As(err, &errs))
+	fields := make([]string, len(errs))
+	for i, fe := range errs {
+		fields[i] = fe.Field
+	}
+	assert.Equal(t, []string{"cid", "address", "telephone", "localities_id"}, fields)
+	assert.Equal(t, "required", errs[0].Rule)
+	assert.Equal(t, "cid es requerido", errs[0].Message)
+	assert.True(t, errors.Is(err, domain.ErrValidation))
+}
+
+func TestStructTelephoneFormat(t *testing.T) {
+	s := domain.Seller{CID: 1, CompanyName: "Meli", Address: "Bulnes 10", Telephone: "llamar", LocalitiesId: 1}
+
+	err := Struct(s)
+
+	var errs Errors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 1)
+	assert.Equal(t, FieldError{Field: "telephone", Rule: "telephone", Key: "validation.telephone", Message: "telephone no es un telefono valido"}, errs[0])
+}
+
+func TestStructLocalityLengths(t *testing.T) {
+	l := domain.Locality{ZipCode: "67-00", LocalityName: "Lujan", ProvinceName: "Buenos Aires", CountryName: "Argentina"}
+
+	err := Struct(l)
+
+	var errs Errors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "zip_code", errs[0].Field)
+	assert.Equal(t, "alphanum", errs[0].Rule)
+}
@@ -0,0 +1,87 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"Sellers/internal/auth"
+	"Sellers/internal/domain"
+	"Sellers/internal/reqctx"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenHeader is the header clients may send their API token in instead of
+// "Authorization: Bearer".
+const TokenHeader = "token"
+
+const identityKey = "web.identity"
+
+// Authenticate rejects requests without a valid API token with a 401. The
+// token is read from "Authorization: Bearer <token>" or the token header. The
+// identity it belongs to is kept in the gin context, see Identity, and becomes
+// the actor of the request context.
+func Authenticate(a auth.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := requestToken(c)
+		if token == "" {
+			c.Header("WWW-Authenticate", `Bearer realm="sellers"`)
+			Error(c, http.StatusUnauthorized, "unauthenticated")
+			return
+		}
+
+		id, err := a.Authenticate(c.Request.Context(), token)
+		if err != nil {
+			if errors.Is(err, domain.ErrUnauthenticated) {
+				c.Header("WWW-Authenticate", `Bearer realm="sellers", error="invalid_token"`)
+			}
+			c.Error(err)
+			c.Abort()
+			return
+		}
+
+		c.Set(identityKey, id)
+		c.Request = c.Request.WithContext(reqctx.WithActor(c.Request.Context(), id.Name))
+		c.Next()
+	}
+}
+
+// Require rejects with a 403 requests whose identity's role does not allow
+// role. It must run after Authenticate; requests without an identity are
+// rejected with a 401.
+func Require(role auth.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := Identity(c)
+		if !ok {
+			Error(c, http.StatusUnauthorized, "unauthenticated")
+			return
+		}
+		if !id.Role.Allows(role) {
+			c.Error(auth.ErrForbidden)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// Identity returns the identity Authenticate stored for the request.
+func Identity(c *gin.Context) (auth.Identity, bool) {
+	id, ok := c.Get(identityKey)
+	if !ok {
+		return auth.Identity{}, false
+	}
+	return id.(auth.Identity), true
+}
+
+func requestToken(c *gin.Context) string {
+	if h := c.GetHeader("Authorization"); h != "" {
+		const prefix = "bearer "
+		if len(h) > len(prefix) && strings.EqualFold(h[:len(prefix)], prefix) {
+			return strings.TrimSpace(h[len(prefix):])
+		}
+		return ""
+	}
+	return strings.TrimSpace(c.GetHeader(TokenHeader))
+}
@@ -0,0 +1,36 @@
+package web
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrBodyTooLarge is returned by a LimitBody reader once the body goes over
+// its limit.
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// LimitBody returns the request body limited to n bytes. Like
+// http.MaxBytesReader, which it wraps, reading past n fails and the
+// connection is closed after the response; the error is ErrBodyTooLarge, so
+// it can be told apart from other read errors.
+func LimitBody(c *gin.Context, n int64) io.Reader {
+	return &limitedBody{r: http.MaxBytesReader(c.Writer, c.Request.Body, n), n: n}
+}
+
+type limitedBody struct {
+	r    io.Reader
+	n    int64
+	read int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.read += int64(n)
+	if err != nil && err != io.EOF && b.read >= b.n {
+		err = ErrBodyTooLarge
+	}
+	return n, err
+}
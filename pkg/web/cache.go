@@ -0,0 +1,142 @@
+package web
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetLastModified writes the Last-Modified header for t, unless t is zero.
+func SetLastModified(c *gin.Context, t time.Time) {
+	if !t.IsZero() {
+		c.Header("Last-Modified", t.UTC().Format(http.TimeFormat))
+	}
+}
+
+// Cacheable makes a GET route conditional. The response is buffered; when it
+// is a 200 it gets cacheControl as its Cache-Control header (if not empty) and,
+// unless the handler set one, an ETag hashed from the body. A request whose
+// If-None-Match or If-Modified-Since shows it already has that representation
+// gets a 304 without body instead. Other responses are written unchanged.
+//
+// Buffering defeats streaming, so it is not meant for the export routes.
+func Cacheable(cacheControl string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		w := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if !w.wrote {
+			return
+		}
+		if w.status != http.StatusOK {
+			c.Writer.WriteHeader(w.status)
+			c.Writer.Write(w.body.Bytes())
+			return
+		}
+
+		h := c.Writer.Header()
+		if cacheControl != "" {
+			h.Set("Cache-Control", cacheControl)
+		}
+		if h.Get("ETag") == "" {
+			sum := sha256.Sum256(w.body.Bytes())
+			h.Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+		}
+
+		if notModified(c.Request, h.Get("ETag"), h.Get("Last-Modified")) {
+			h.Del("Content-Type")
+			h.Del("Content-Length")
+			c.Writer.WriteHeader(http.StatusNotModified)
+			c.Writer.WriteHeaderNow()
+			return
+		}
+		c.Writer.WriteHeader(w.status)
+		c.Writer.Write(w.body.Bytes())
+	}
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when there is no
+// If-None-Match, against the validators of the response (RFC 7232 section 6).
+func notModified(r *http.Request, etag, lastModified string) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return matchWeak(inm, etag)
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified == "" {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+	return !modified.After(since)
+}
+
+// matchWeak reports whether the If-None-Match list matches etag using the weak
+// comparison, which ignores the W/ prefix.
+func matchWeak(list, etag string) bool {
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(list, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedWriter holds the status and body of a response so Cacheable can
+// decide what to send once the handler is done. Headers go straight to the
+// underlying writer.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status int
+	wrote  bool
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 {
+		w.status = code
+		w.wrote = true
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	w.wrote = true
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	w.wrote = true
+	return w.body.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	w.wrote = true
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int { return w.status }
+
+func (w *bufferedWriter) Size() int { return w.body.Len() }
+
+func (w *bufferedWriter) Written() bool { return w.wrote }
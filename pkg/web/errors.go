@@ -0,0 +1,87 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+
+	"Sellers/internal/domain"
+	"Sellers/internal/i18n"
+	"Sellers/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatusFor returns the HTTP status for an error according to its domain kind.
+func StatusFor(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, domain.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, domain.ErrPrecondition):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, domain.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, domain.ErrForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ErrorHandler renders the last error a handler attached with c.Error, as long
+// as the handler has not written a response itself. validation.Errors become a
+// 422 listing every invalid field; unclassified errors become a 500 without
+// exposing their message. Details are localized from the error code.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+
+		var verrs validation.Errors
+		if errors.As(err, &verrs) {
+			ValidationError(c, FieldErrors(c, verrs)...)
+			return
+		}
+
+		status := StatusFor(err)
+		if status == http.StatusInternalServerError {
+			Error(c, status, "internal_error")
+			return
+		}
+
+		code := domain.Code(err)
+		if !i18n.Has(code) {
+			WriteProblem(c, Problem{Status: status, Code: code, Detail: err.Error()})
+			return
+		}
+		Error(c, status, code, args(err)...)
+	}
+}
+
+// FieldErrors localizes verrs into the response language.
+func FieldErrors(c *gin.Context, verrs validation.Errors) []FieldError {
+	fields := make([]FieldError, len(verrs))
+	for i, fe := range verrs {
+		fields[i] = FieldError{Field: fe.Field, Code: fe.Rule, Message: T(c, fe.Key, fe.Field, fe.Param)}
+	}
+	return fields
+}
+
+// args returns the message arguments err carries, if any.
+func args(err error) []interface{} {
+	var a interface{ Args() []interface{} }
+	if errors.As(err, &a) {
+		return a.Args()
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package web
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IfMatchHeader is the header clients send to make a write conditional on the
+// ETag they last read.
+const IfMatchHeader = "If-Match"
+
+// ETag formats a resource version as a strong entity tag.
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// SetETag writes the ETag header for version.
+func SetETag(c *gin.Context, version int) {
+	c.Header("ETag", ETag(version))
+}
+
+// HasIfMatch reports whether the request carries an If-Match header.
+func HasIfMatch(c *gin.Context) bool {
+	return c.GetHeader(IfMatchHeader) != ""
+}
+
+// IfMatch reports whether the If-Match header of the request matches etag,
+// using the strong comparison of RFC 7232: weak tags never match and "*"
+// matches any current representation. Requests without If-Match match.
+func IfMatch(c *gin.Context, etag string) bool {
+	h := strings.TrimSpace(c.GetHeader(IfMatchHeader))
+	if h == "" || h == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(h, ",") {
+		if strings.TrimSpace(tag) == etag {
+			return true
+		}
+	}
+	return false
+}
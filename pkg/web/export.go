@@ -0,0 +1,154 @@
+package web
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"Sellers/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Export formats, chosen with ?format=.
+const (
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson"
+	ExportJSON   = "json"
+)
+
+// exportFlushEvery is how many rows are buffered before flushing to the client.
+const exportFlushEvery = 100
+
+var errExportFormat = domain.NewError(domain.ErrValidation, "export_format", "unsupported export format")
+
+var exportContentTypes = map[string]string{
+	ExportCSV:    "text/csv; charset=utf-8",
+	ExportNDJSON: "application/x-ndjson",
+	ExportJSON:   "application/json; charset=utf-8",
+}
+
+// ExportFormat reads ?format=, JSON by default.
+func ExportFormat(c *gin.Context) (string, error) {
+	format := c.DefaultQuery("format", ExportJSON)
+	if _, ok := exportContentTypes[format]; !ok {
+		return "", errExportFormat
+	}
+	return format, nil
+}
+
+// Exporter streams rows to the client as they are written. Nothing is sent
+// until the first row (or Close), so an error raised before that can still
+// be answered with a problem response; see Abort. The whole export must be
+// written within server.write_timeout.
+type Exporter struct {
+	c       *gin.Context
+	format  string
+	name    string
+	header  []string
+	started bool
+	rows    int
+	csv     *csv.Writer
+	json    *json.Encoder
+}
+
+// NewExporter returns an Exporter writing format as a download named
+// name.<format>. header is the CSV header row.
+func NewExporter(c *gin.Context, format, name string, header []string) *Exporter {
+	return &Exporter{c: c, format: format, name: name, header: header}
+}
+
+func (e *Exporter) start() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+
+	e.c.Header("Content-Type", exportContentTypes[e.format])
+	e.c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, e.name, e.format))
+	e.c.Status(http.StatusOK)
+
+	switch e.format {
+	case ExportCSV:
+		e.csv = csv.NewWriter(e.c.Writer)
+		return e.csv.Write(e.header)
+	case ExportJSON:
+		e.json = json.NewEncoder(e.c.Writer)
+		_, err := e.c.Writer.WriteString("[")
+		return err
+	default:
+		e.json = json.NewEncoder(e.c.Writer)
+		return nil
+	}
+}
+
+// Write sends one row: v encoded as JSON, or record for CSV.
+func (e *Exporter) Write(v interface{}, record []string) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+
+	var err error
+	switch e.format {
+	case ExportCSV:
+		err = e.csv.Write(record)
+	case ExportJSON:
+		if e.rows > 0 {
+			if _, err = e.c.Writer.WriteString(","); err != nil {
+				return err
+			}
+		}
+		err = e.json.Encode(v)
+	default:
+		err = e.json.Encode(v)
+	}
+	if err != nil {
+		return err
+	}
+
+	e.rows++
+	if e.rows%exportFlushEvery == 0 {
+		return e.flush()
+	}
+	return nil
+}
+
+// Close terminates the document and flushes what is left.
+func (e *Exporter) Close() error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	if e.format == ExportJSON {
+		if _, err := e.c.Writer.WriteString("]"); err != nil {
+			return err
+		}
+	}
+	return e.flush()
+}
+
+// Abort handles an error that stopped the export. If nothing reached the
+// client yet, the download headers are removed so the error can be answered
+// with a problem response instead. Otherwise the status cannot change
+// anymore and Abort panics with http.ErrAbortHandler, which makes the server
+// break the connection: the client sees a failed transfer rather than a 200
+// with a truncated document.
+func (e *Exporter) Abort() {
+	if e.c.Writer.Written() {
+		panic(http.ErrAbortHandler)
+	}
+	h := e.c.Writer.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Disposition")
+}
+
+func (e *Exporter) flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+	e.c.Writer.Flush()
+	return nil
+}
@@ -0,0 +1,30 @@
+package web
+
+import (
+	"net/http"
+
+	"Sellers/internal/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Liveness answers as long as the process can serve requests.
+func Liveness() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+	}
+}
+
+// Readiness runs the checks of h and answers with their report, 503 Service
+// Unavailable when a dependency failed or the service is draining.
+func Readiness(h *health.Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+		r := h.Check(c.Request.Context())
+		status := http.StatusOK
+		if !r.Ready() {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, r)
+	}
+}
@@ -0,0 +1,55 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"Sellers/internal/health"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func createHealthServer(h *health.Checker) *gin.Engine {
+	r := gin.New()
+	r.Use(ErrorHandler())
+	r.GET("/healthz", Liveness())
+	r.GET("/readyz", Readiness(h))
+	return r
+}
+
+func TestReadinessReportsEachDependency(t *testing.T) {
+	h := health.New(time.Second)
+	h.Add("database", func(context.Context) error { return nil })
+	pending := errors.New("1 pending migrations, the first is 0009_add_index")
+	h.Add("migrations", func(context.Context) error { return pending })
+	r := createHealthServer(h)
+
+	res := serve(t, r, http.MethodGet, "/readyz")
+
+	assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+	assert.Equal(t, "no-store", res.Header().Get("Cache-Control"))
+	assert.JSONEq(t, `{"status":"fail","checks":{"database":{"status":"ok"},"migrations":{"status":"fail","error":"1 pending migrations, the first is 0009_add_index"}}}`, res.Body.String())
+
+	res = serve(t, r, http.MethodGet, "/healthz")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, res.Body.String())
+}
+
+func TestReadinessFailsWhileDraining(t *testing.T) {
+	h := health.New(time.Second)
+	h.Add("database", func(context.Context) error { return nil })
+	r := createHealthServer(h)
+
+	res := serve(t, r, http.MethodGet, "/readyz")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `{"status":"ok","checks":{"database":{"status":"ok"}}}`, res.Body.String())
+
+	h.Drain()
+	res = serve(t, r, http.MethodGet, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+	assert.JSONEq(t, `{"status":"draining","checks":{"database":{"status":"ok"}}}`, res.Body.String())
+}
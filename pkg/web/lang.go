@@ -0,0 +1,35 @@
+package web
+
+import (
+	"Sellers/internal/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+const langKey = "lang"
+
+// Localize negotiates the response language from Accept-Language once per
+// request and announces it in Content-Language.
+func Localize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := i18n.Negotiate(c.GetHeader("Accept-Language"))
+		c.Set(langKey, lang)
+		c.Header("Content-Language", string(lang))
+		c.Header("Vary", "Accept-Language")
+		c.Next()
+	}
+}
+
+// Lang returns the language of the response, negotiating it if Localize did
+// not run.
+func Lang(c *gin.Context) i18n.Lang {
+	if v, ok := c.Get(langKey); ok {
+		return v.(i18n.Lang)
+	}
+	return i18n.Negotiate(c.GetHeader("Accept-Language"))
+}
+
+// T returns the message for key in the language of the response.
+func T(c *gin.Context, key string, args ...interface{}) string {
+	return i18n.T(Lang(c), key, args...)
+}
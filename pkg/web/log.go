@@ -0,0 +1,66 @@
+package web
+
+import (
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"Sellers/internal/logging"
+	"Sellers/internal/reqctx"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Logger writes an access log line per request once it is answered, with
+// the request ID so it can be matched with the lines logged while serving
+// it. Server errors are logged at error level with the error behind them,
+// which the response does not expose.
+func Logger(log *logging.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		ctx := c.Request.Context()
+		args := []interface{}{
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", status,
+			"bytes", c.Writer.Size(),
+			"duration_ms", time.Since(start),
+			"client_ip", c.ClientIP(),
+			"actor", reqctx.Actor(ctx),
+		}
+		if status >= http.StatusInternalServerError {
+			if len(c.Errors) > 0 {
+				args = append(args, "err", c.Errors.Last().Err)
+			}
+			log.Error(ctx, "request", args...)
+			return
+		}
+		log.Info(ctx, "request", args...)
+	}
+}
+
+// Recovery turns a panic into a 500, logging it with its stack trace.
+// http.ErrAbortHandler is panicked again for the server to break the
+// connection, as handlers that use it logged why already.
+func Recovery(log *logging.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if p := recover(); p != nil {
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				log.Error(c.Request.Context(), "panic", "panic", p, "stack", string(debug.Stack()))
+				if c.Writer.Written() {
+					c.Abort()
+					return
+				}
+				Error(c, http.StatusInternalServerError, "internal_error")
+			}
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,49 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"testing"
+
+	"Sellers/internal/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// createLoggedServer mounts a failing and a panicking route behind the access
+// log and panic recovery, logging to buf.
+func createLoggedServer(buf *bytes.Buffer) *gin.Engine {
+	log := logging.New(buf, logging.LevelInfo)
+	r := gin.New()
+	r.Use(RequestID(), Logger(log), Recovery(log), ErrorHandler())
+	r.GET("/sellers/:id", func(c *gin.Context) { c.Error(errors.New("connection reset")) })
+	r.GET("/panic", func(c *gin.Context) { panic("boom") })
+	return r
+}
+
+func TestAccessLogCarriesRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	r := createLoggedServer(&buf)
+
+	res := serve(t, r, http.MethodGet, "/sellers/1", RequestIDHeader, "req-42")
+
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+	assert.NotContains(t, res.Body.String(), "connection reset")
+	assert.Contains(t, buf.String(), `"level":"ERROR","msg":"request","request_id":"req-42","method":"GET","path":"/sellers/1","route":"/sellers/:id","status":500`)
+	assert.Contains(t, buf.String(), `"err":"connection reset"`)
+}
+
+func TestRecoveryLogsPanic(t *testing.T) {
+	var buf bytes.Buffer
+	r := createLoggedServer(&buf)
+
+	res := serve(t, r, http.MethodGet, "/panic")
+
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+	assert.Contains(t, res.Body.String(), `"code":"internal_error"`)
+	assert.Contains(t, buf.String(), `"msg":"panic"`)
+	assert.Contains(t, buf.String(), `"panic":"boom"`)
+	assert.Contains(t, buf.String(), `"status":500`)
+}
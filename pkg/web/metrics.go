@@ -0,0 +1,54 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"Sellers/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// methods are the request methods Metrics labels by name; any other is
+// "other", so clients cannot create series by inventing methods.
+var methods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodConnect: true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// Metrics records every request in h under the route template it matched, or
+// "unmatched", so paths with IDs do not each get their own series.
+func Metrics(h *metrics.HTTP) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+		if !methods[method] {
+			method = "other"
+		}
+		h.Observe(method, route, c.Writer.Status(), time.Since(start))
+	}
+}
+
+// MetricsHandler answers a Prometheus scrape with the metrics of reg.
+func MetricsHandler(reg *metrics.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		c.Header("Content-Type", metrics.ContentType)
+		if err := reg.Write(c.Request.Context(), c.Writer); err != nil {
+			c.Error(err)
+		}
+	}
+}
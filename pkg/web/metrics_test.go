@@ -0,0 +1,51 @@
+package web
+
+import (
+	"net/http"
+	"testing"
+
+	"Sellers/internal/domain"
+	"Sellers/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsCountRequestsByRouteTemplate(t *testing.T) {
+	reg := metrics.NewRegistry()
+	r := gin.New()
+	r.Use(Metrics(metrics.NewHTTP(reg)), ErrorHandler())
+	r.GET("/sellers/:id", func(c *gin.Context) {
+		if c.Param("id") != "1" {
+			c.Error(domain.NewError(domain.ErrNotFound, "seller_not_found", "seller not found"))
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	r.GET("/metrics", MetricsHandler(reg))
+
+	for _, url := range []string{"/sellers/1", "/sellers/1", "/sellers/2", "/nowhere"} {
+		serve(t, r, http.MethodGet, url)
+	}
+	res := serve(t, r, http.MethodGet, "/metrics")
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, metrics.ContentType, res.Header().Get("Content-Type"))
+	assert.Contains(t, res.Body.String(), `http_requests_total{method="GET",route="/sellers/:id",status="200"} 2`)
+	assert.Contains(t, res.Body.String(), `http_requests_total{method="GET",route="/sellers/:id",status="404"} 1`)
+	assert.Contains(t, res.Body.String(), `http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, res.Body.String(), `http_request_duration_seconds_count{method="GET",route="/sellers/:id",status="200"} 2`)
+}
+
+func TestMetricsLabelUnknownMethodsAsOther(t *testing.T) {
+	reg := metrics.NewRegistry()
+	r := gin.New()
+	r.Use(Metrics(metrics.NewHTTP(reg)))
+	r.GET("/metrics", MetricsHandler(reg))
+
+	serve(t, r, "PROPFIND", "/sellers")
+	res := serve(t, r, http.MethodGet, "/metrics")
+
+	assert.Contains(t, res.Body.String(), `http_requests_total{method="other",route="unmatched",status="404"} 1`)
+	assert.NotContains(t, res.Body.String(), "PROPFIND")
+}
@@ -0,0 +1,101 @@
+package web
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"Sellers/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Pagination is the meta block returned with a paginated listing.
+type Pagination struct {
+	Total      int    `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+var (
+	errInvalidLimit     = domain.NewError(domain.ErrValidation, "invalid_limit", "limit must be a positive integer")
+	errInvalidOffset    = domain.NewError(domain.ErrValidation, "invalid_offset", "offset must be a non-negative integer")
+	errCursorWithOffset = domain.NewError(domain.ErrValidation, "cursor_with_offset", "cursor and offset cannot be combined")
+)
+
+// ParsePagination reads the limit, offset and cursor query parameters.
+// A missing limit is returned as 0 so the service can apply its default.
+func ParsePagination(c *gin.Context) (limit, offset int, cursor string, err error) {
+	if v := c.Query("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return 0, 0, "", errInvalidLimit
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return 0, 0, "", errInvalidOffset
+		}
+	}
+	cursor = c.Query("cursor")
+	if cursor != "" && offset != 0 {
+		return 0, 0, "", errCursorWithOffset
+	}
+	return limit, offset, cursor, nil
+}
+
+// SetPaginationHeaders writes X-Total-Count and an RFC 8288 Link header with
+// first/prev/next/last relations. Requests made with a cursor only get first
+// and next, since cursors cannot jump backwards.
+func SetPaginationHeaders(c *gin.Context, p Pagination) {
+	c.Header("X-Total-Count", strconv.Itoa(p.Total))
+
+	var links []string
+	add := func(rel string, set map[string]string) {
+		u := *c.Request.URL
+		q := u.Query()
+		q.Del("offset")
+		q.Del("cursor")
+		q.Set("limit", strconv.Itoa(p.Limit))
+		for k, v := range set {
+			q.Set(k, v)
+		}
+		u.RawQuery = q.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, requestURL(c, &u), rel))
+	}
+
+	add("first", nil)
+	if c.Query("cursor") != "" {
+		if p.NextCursor != "" {
+			add("next", map[string]string{"cursor": p.NextCursor})
+		}
+	} else {
+		if p.Offset > 0 {
+			prev := p.Offset - p.Limit
+			if prev < 0 {
+				prev = 0
+			}
+			add("prev", map[string]string{"offset": strconv.Itoa(prev)})
+		}
+		if p.Offset+p.Limit < p.Total {
+			add("next", map[string]string{"offset": strconv.Itoa(p.Offset + p.Limit)})
+		}
+		if p.Total > 0 {
+			last := (p.Total - 1) / p.Limit * p.Limit
+			add("last", map[string]string{"offset": strconv.Itoa(last)})
+		}
+	}
+
+	c.Header("Link", strings.Join(links, ", "))
+}
+
+func requestURL(c *gin.Context, u *url.URL) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + u.RequestURI()
+}
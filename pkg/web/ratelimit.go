@@ -0,0 +1,60 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"Sellers/internal/auth"
+	"Sellers/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Rate limit headers, as in the IETF RateLimit header fields draft.
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+)
+
+// RateLimit lets each client make l requests of group, group being the name
+// of the bucket shared by the routes it is mounted on. Clients are told apart
+// by their API token once Authenticate accepted it, by IP otherwise. Every
+// response carries the RateLimit-* headers; rejected requests get a 429 with
+// Retry-After. If store fails the request is let through, so the API keeps
+// working while a shared store is down.
+func RateLimit(store ratelimit.Store, group string, l ratelimit.Limit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res, err := store.Take(c.Request.Context(), group+":"+clientKey(c), l)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		c.Header(RateLimitLimitHeader, strconv.Itoa(res.Limit))
+		c.Header(RateLimitRemainingHeader, strconv.Itoa(res.Remaining))
+		c.Header(RateLimitResetHeader, strconv.Itoa(seconds(res.Reset)))
+		if !res.Allowed {
+			retry := seconds(res.RetryAfter)
+			c.Header("Retry-After", strconv.Itoa(retry))
+			Error(c, http.StatusTooManyRequests, "rate_limited", retry)
+			return
+		}
+		c.Next()
+	}
+}
+
+// clientKey identifies the client of a request. Tokens are hashed so stores
+// never hold them.
+func clientKey(c *gin.Context) string {
+	if _, ok := Identity(c); ok {
+		return "token:" + auth.HashToken(requestToken(c))
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// seconds rounds d up to whole seconds, at least 1 when d is positive.
+func seconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
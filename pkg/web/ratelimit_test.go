@@ -0,0 +1,56 @@
+package web
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"Sellers/internal/auth"
+	"Sellers/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// createRateLimitedServer mounts a route with a limit of one request a minute
+// per client, behind authenticate when it is not nil.
+func createRateLimitedServer(authenticate gin.HandlerFunc) *gin.Engine {
+	r := gin.New()
+	r.Use(ErrorHandler())
+	if authenticate != nil {
+		r.Use(authenticate)
+	}
+	limit := RateLimit(ratelimit.NewMemory(), "read", ratelimit.Limit{Requests: 1, Per: time.Minute})
+	r.GET("/sellers/:id", limit, func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func TestRateLimit(t *testing.T) {
+	r := createRateLimitedServer(nil)
+
+	res := serve(t, r, http.MethodGet, "/sellers/1")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "1", res.Header().Get(RateLimitLimitHeader))
+	assert.Equal(t, "0", res.Header().Get(RateLimitRemainingHeader))
+	assert.Equal(t, "60", res.Header().Get(RateLimitResetHeader))
+
+	res = serve(t, r, http.MethodGet, "/sellers/1")
+	assert.Equal(t, http.StatusTooManyRequests, res.Code)
+	assert.Equal(t, "60", res.Header().Get("Retry-After"))
+	assert.Contains(t, res.Body.String(), `"code":"rate_limited"`)
+}
+
+func TestRateLimitPerToken(t *testing.T) {
+	r := createRateLimitedServer(Authenticate(auth.NewStatic(map[string]auth.Identity{
+		"editor-token-0001": {Name: "eli", Role: auth.Editor},
+		"reader-token-0001": {Name: "rui", Role: auth.Reader},
+	})))
+
+	for _, token := range []string{"reader-token-0001", "editor-token-0001"} {
+		res := serve(t, r, http.MethodGet, "/sellers/1", TokenHeader, token)
+		assert.Equal(t, http.StatusOK, res.Code, token)
+	}
+
+	res := serve(t, r, http.MethodGet, "/sellers/1", "Authorization", "Bearer reader-token-0001")
+	assert.Equal(t, http.StatusTooManyRequests, res.Code)
+}
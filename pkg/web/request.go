@@ -0,0 +1,53 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"Sellers/internal/reqctx"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader is the header clients use to correlate their requests.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the request IDs accepted from clients.
+const maxRequestIDLength = 128
+
+// RequestID makes sure every request has an ID: the X-Request-ID header when
+// the client sent a usable one, a random one otherwise. The ID is put in the
+// request context, for services and log lines, and echoed in the response.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Request = c.Request.WithContext(reqctx.WithRequestID(c.Request.Context(), id))
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// validRequestID accepts non-empty IDs of printable ASCII without spaces, so
+// they can be echoed and logged as they are.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,24 @@
+package web
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDGeneratedAndEchoed(t *testing.T) {
+	r := gin.New()
+	r.Use(RequestID())
+	r.GET("/sellers/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	res := serve(t, r, http.MethodGet, "/sellers/1")
+	assert.Regexp(t, "^[0-9a-f]{32}$", res.Header().Get(RequestIDHeader))
+
+	res = serve(t, r, http.MethodGet, "/sellers/1", RequestIDHeader, "req-42")
+	assert.Equal(t, "req-42", res.Header().Get(RequestIDHeader))
+
+	res = serve(t, r, http.MethodGet, "/sellers/1", RequestIDHeader, "bad id")
+	assert.NotEqual(t, "bad id", res.Header().Get(RequestIDHeader))
+}
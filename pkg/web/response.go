@@ -1,58 +1,102 @@
 package web
 
 import (
-	"fmt"
 	"bytes"
 	"net/http"
 	"net/http/httptest"
-	"strings"
-	"strconv"
+
+	"Sellers/internal/domain"
+	"Sellers/internal/i18n"
+
 	"github.com/gin-gonic/gin"
 )
 
-type response struct {
+// ProblemContentType is the media type of error responses (RFC 7807).
+const ProblemContentType = "application/problem+json"
+
+// envelope wraps every successful response body.
+type envelope struct {
 	Data interface{} `json:"data"`
+	Meta interface{} `json:"meta,omitempty"`
 }
 
-type errorResponse struct {
-	Status  int    `json:"-"`
-	Code    string `json:"code"`
-	Message string `json:"message"`
+// Problem is an RFC 7807 problem details object. Code is a stable,
+// machine-readable identifier of the problem while Detail is localized.
+// Errors carries one entry per invalid field on validation failures.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Code     string       `json:"code,omitempty"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
 }
 
-type r struct {
-	Code  string      `json:"code"`
-	Data  interface{} `json:"data,omitempty"`
-	Error string      `json:"error,omitempty"`
+// FieldError describes why one field of the request was rejected. Code is the
+// rule the field failed (e.g. "required").
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
 }
 
 func Response(c *gin.Context, status int, data interface{}) {
 	c.JSON(status, data)
 }
 
+// Success writes data inside the {"data": ...} envelope.
 func Success(c *gin.Context, status int, data interface{}) {
-	Response(c, status, response{Data: data})
+	Response(c, status, envelope{Data: data})
 }
 
-// NewErrorf creates a new error with the given status code and the message
-// formatted according to args and format.
-func Error(c *gin.Context, status int, format string, args ...interface{}) {
-	err := errorResponse{
-		Code:    strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_"),
-		Message: fmt.Sprintf(format, args...),
-		Status:  status,
-	}
+// SuccessWithMeta writes data and meta (e.g. Pagination) inside the envelope.
+func SuccessWithMeta(c *gin.Context, status int, data interface{}, meta interface{}) {
+	Response(c, status, envelope{Data: data, Meta: meta})
+}
 
-	Response(c, status, err)
+// Error writes a problem+json response with the given status and code. The
+// detail is the message of code in the response language, formatted with args.
+func Error(c *gin.Context, status int, code string, args ...interface{}) {
+	WriteProblem(c, Problem{Status: status, Code: code, Detail: T(c, code, args...)})
 }
 
-func NewResponse(code int, data interface{}, error string) r {
+// ErrorFrom writes err as a problem+json response with the given status. The
+// code comes from err when it has a localized one; otherwise err is reported
+// as a bad_request.
+func ErrorFrom(c *gin.Context, status int, err error) {
+	code := domain.Code(err)
+	if code == "" || !i18n.Has(code) {
+		Error(c, status, "bad_request", err.Error())
+		return
+	}
+	Error(c, status, code, args(err)...)
+}
+
+// ValidationError writes a 422 problem+json response listing every invalid field.
+func ValidationError(c *gin.Context, errs ...FieldError) {
+	WriteProblem(c, Problem{
+		Status: http.StatusUnprocessableEntity,
+		Code:   "invalid_fields",
+		Detail: T(c, "invalid_fields"),
+		Errors: errs,
+	})
+}
 
-	if code < 300 {
-		return r{strconv.FormatInt(int64(code), 10), data, ""}
+// WriteProblem fills in the defaults of p and writes it, aborting the chain.
+func WriteProblem(c *gin.Context, p Problem) {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Instance == "" {
+		p.Instance = c.Request.URL.Path
 	}
 
-	return r{strconv.FormatInt(int64(code), 10), nil, error}
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(p.Status, p)
 }
 
 func CreateTestRequest(method string, url string, body string) (*http.Request, *httptest.ResponseRecorder) {
@@ -61,4 +105,3 @@ func CreateTestRequest(method string, url string, body string) (*http.Request, *
 	req.Header.Add("token", "1234")
 	return req, httptest.NewRecorder()
 }
-